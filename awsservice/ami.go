@@ -0,0 +1,155 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const ssmAMIPrefix = "resolve:ssm:"
+
+// AMIQuery selects images. All fields are optional, but at least one of Owners, NamePattern or Tags is required.
+type AMIQuery struct {
+	Owners       []string          // Account IDs or aliases ("self", "amazon")
+	NamePattern  string            // Glob (eg "api-*")
+	Architecture string            // eg "x86_64" or "arm64"
+	Tags         map[string]string // eg Packer build tags: {"role": "api"}
+}
+
+type AMIInfo struct {
+	ID                 string
+	Name               string
+	Description        string
+	Architecture       string
+	Owner              string
+	State              string
+	RootDeviceType     string
+	VirtualizationType string
+	CreationDate       time.Time
+	Tags               map[string]string
+}
+
+func amiInfo(img *ec2.Image) AMIInfo {
+	ai := AMIInfo{
+		ID:                 drefStringPtr(img.ImageId),
+		Name:               drefStringPtr(img.Name),
+		Description:        drefStringPtr(img.Description),
+		Architecture:       drefStringPtr(img.Architecture),
+		Owner:              drefStringPtr(img.OwnerId),
+		State:              drefStringPtr(img.State),
+		RootDeviceType:     drefStringPtr(img.RootDeviceType),
+		VirtualizationType: drefStringPtr(img.VirtualizationType),
+		Tags:               ec2TagMap(img.Tags),
+	}
+	if t, err := time.Parse(time.RFC3339, drefStringPtr(img.CreationDate)); err == nil {
+		ai.CreationDate = t
+	}
+	return ai
+}
+
+// sortAMIsNewestFirst orders images by creation date, newest first (ties broken by name for stable output)
+func sortAMIsNewestFirst(amis []AMIInfo) {
+	sort.SliceStable(amis, func(i, j int) bool {
+		if amis[i].CreationDate.Equal(amis[j].CreationDate) {
+			return amis[i].Name > amis[j].Name
+		}
+		return amis[i].CreationDate.After(amis[j].CreationDate)
+	})
+}
+
+// FindAMIs returns the available images matching q, newest first
+func (aws *RealAWSService) FindAMIs(q *AMIQuery) ([]AMIInfo, error) {
+	result := []AMIInfo{}
+	if len(q.Owners) == 0 && q.NamePattern == "" && len(q.Tags) == 0 {
+		return result, invalidDefinition("AMI query requires owners, a name pattern or tags")
+	}
+	dii := &ec2.DescribeImagesInput{
+		Filters: ec2Filters(map[string]string{
+			"name":         q.NamePattern,
+			"architecture": q.Architecture,
+			"state":        ec2.ImageStateAvailable,
+		}, q.Tags),
+	}
+	if len(q.Owners) > 0 {
+		dii.Owners = stringSlicetoStringPointerSlice(q.Owners)
+	}
+	res, err := aws.ec2.DescribeImages(dii)
+	if err != nil {
+		return result, wrapError("FindAMIs", err)
+	}
+	for _, img := range res.Images {
+		result = append(result, amiInfo(img))
+	}
+	sortAMIsNewestFirst(result)
+	return result, nil
+}
+
+// FindLatestAMI returns the newest available image matching q
+func (aws *RealAWSService) FindLatestAMI(q *AMIQuery) (*AMIInfo, error) {
+	result := &AMIInfo{}
+	amis, err := aws.FindAMIs(q)
+	if err != nil {
+		return result, err
+	}
+	if len(amis) == 0 {
+		return result, notFoundError("FindLatestAMI", "no AMI found matching query: %+v", *q)
+	}
+	*result = amis[0]
+	return result, nil
+}
+
+// ResolveAMI returns the image ID referred to by ref, which may be a literal image ID, an SSM parameter
+// name (eg "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64") or the same prefixed
+// with "resolve:ssm:". Literal IDs are returned unchanged.
+func (aws *RealAWSService) ResolveAMI(ref string) (string, error) {
+	if !strings.HasPrefix(ref, ssmAMIPrefix) && !strings.HasPrefix(ref, "/") {
+		return ref, nil
+	}
+	name := strings.TrimPrefix(ref, ssmAMIPrefix)
+	res, err := aws.ssmc.GetParameter(&ssm.GetParameterInput{
+		Name: &name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error resolving AMI parameter %v: %w", name, wrapError("ResolveAMI", err))
+	}
+	if res.Parameter == nil || res.Parameter.Value == nil {
+		return "", fmt.Errorf("AMI parameter has no value: %v", name)
+	}
+	return *res.Parameter.Value, nil
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) FindAMIs(q *AMIQuery) ([]AMIInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindAMIs",
+		NotableParams: map[string]string{
+			"query": fmt.Sprintf("%+v", *q),
+		},
+	})
+	return []AMIInfo{}, nil
+}
+
+func (aws *TestingAWSService) FindLatestAMI(q *AMIQuery) (*AMIInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindLatestAMI",
+		NotableParams: map[string]string{
+			"query": fmt.Sprintf("%+v", *q),
+		},
+	})
+	return &AMIInfo{}, nil
+}
+
+func (aws *TestingAWSService) ResolveAMI(ref string) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ResolveAMI",
+		NotableParams: map[string]string{
+			"ref": ref,
+		},
+	})
+	return ref, nil
+}
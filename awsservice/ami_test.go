@@ -0,0 +1,127 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ec2FilterValues returns the values of the EC2 filters in a query request by filter name
+func ec2FilterValues(form url.Values) map[string]string {
+	filters := map[string]string{}
+	for i := 1; form.Get(fmt.Sprintf("Filter.%v.Name", i)) != ""; i++ {
+		filters[form.Get(fmt.Sprintf("Filter.%v.Name", i))] = form.Get(fmt.Sprintf("Filter.%v.Value.1", i))
+	}
+	return filters
+}
+func TestSortAMIsNewestFirst(t *testing.T) {
+	dates := []string{"2019-03-01T10:00:00.000Z", "2021-07-15T08:30:00.000Z", "2020-11-20T00:00:00.000Z"}
+	amis := []AMIInfo{}
+	for i, d := range dates {
+		id := []string{"ami-1", "ami-2", "ami-3"}[i]
+		cd := d
+		amis = append(amis, amiInfo(&ec2.Image{ImageId: &id, CreationDate: &cd}))
+	}
+	sortAMIsNewestFirst(amis)
+	if amis[0].ID != "ami-2" || amis[1].ID != "ami-3" || amis[2].ID != "ami-1" {
+		t.Fatalf("incorrect order: %v, %v, %v", amis[0].ID, amis[1].ID, amis[2].ID)
+	}
+}
+
+func TestFindAMIs(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"DescribeImages": `<DescribeImagesResponse><imagesSet>` +
+			`<item><imageId>ami-old</imageId><name>api-1</name><architecture>arm64</architecture><imageState>available</imageState>` +
+			`<creationDate>2026-01-01T00:00:00.000Z</creationDate></item>` +
+			`<item><imageId>ami-new</imageId><name>api-2</name><architecture>arm64</architecture><imageState>available</imageState>` +
+			`<imageOwnerId>123456789012</imageOwnerId><creationDate>2026-09-01T00:00:00.000Z</creationDate>` +
+			`<tagSet><item><key>role</key><value>api</value></item></tagSet></item>` +
+			`</imagesSet></DescribeImagesResponse>`,
+	})
+	defer done()
+	ami, err := svc.FindLatestAMI(&AMIQuery{
+		Owners:       []string{"self"},
+		NamePattern:  "api-*",
+		Architecture: "arm64",
+		Tags:         map[string]string{"role": "api"},
+	})
+	if err != nil {
+		t.Fatalf("error finding AMI: %v", err)
+	}
+	if ami.ID != "ami-new" || ami.Owner != "123456789012" || ami.Tags["role"] != "api" {
+		t.Fatalf("bad AMI: %+v", ami)
+	}
+	form := f.requests[0]
+	expected := map[string]string{"name": "api-*", "architecture": "arm64", "state": "available", "tag:role": "api"}
+	filters := ec2FilterValues(form)
+	if len(filters) != len(expected) {
+		t.Fatalf("bad filters: %v", filters)
+	}
+	for k, v := range expected {
+		if filters[k] != v {
+			t.Fatalf("bad %v filter: %q (expected: %q)", k, filters[k], v)
+		}
+	}
+	if form.Get("Owner.1") != "self" {
+		t.Fatalf("bad owners: %v", form)
+	}
+	svc.FindAMIs(&AMIQuery{Tags: map[string]string{"role": "api"}})
+	if form = f.requests[1]; form.Get("Owner.1") != "" || ec2FilterValues(form)["architecture"] != "" {
+		t.Fatalf("unset query fields should not be sent: %v", form)
+	}
+	if _, err := svc.FindAMIs(&AMIQuery{Architecture: "arm64"}); !errors.Is(err, ErrInvalidParameter) || len(f.requests) != 2 {
+		t.Fatalf("query without owners, name or tags should be rejected locally: %v", err)
+	}
+}
+
+func TestFindLatestAMINotFound(t *testing.T) {
+	svc, _, done := newEC2FakeService(map[string]string{
+		"DescribeImages": `<DescribeImagesResponse><imagesSet></imagesSet></DescribeImagesResponse>`,
+	})
+	defer done()
+	if _, err := svc.FindLatestAMI(&AMIQuery{Owners: []string{"self"}}); !IsNotFound(err) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+}
+
+func TestResolveAMI(t *testing.T) {
+	svc, f, done := newSSMFakeService(map[string][]string{
+		"GetParameter": {
+			`{"Parameter":{"Name":"/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64","Type":"String","Value":"ami-0abc"}}`,
+			`{"Parameter":{"Name":"/app/ami","Type":"String"}}`,
+		},
+	})
+	defer done()
+	if id, err := svc.ResolveAMI("ami-literal"); err != nil || id != "ami-literal" || len(f.requests) != 0 {
+		t.Fatalf("literal IDs should be returned unchanged: %v: %v", id, err)
+	}
+	id, err := svc.ResolveAMI("resolve:ssm:/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64")
+	if err != nil {
+		t.Fatalf("error resolving AMI: %v", err)
+	}
+	if id != "ami-0abc" || f.requests[0]["Name"] != "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64" {
+		t.Fatalf("bad resolution: %v: %v", id, f.requests[0])
+	}
+	if _, err := svc.ResolveAMI("/app/ami"); err == nil || !strings.Contains(err.Error(), "no value") {
+		t.Fatalf("parameter without a value should be an error: %v", err)
+	}
+}
+
+func TestResolveAMINotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"ParameterNotFound","message":"parameter not found"}`))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if _, err := svc.ResolveAMI("/app/missing"); !IsNotFound(err) || !strings.Contains(err.Error(), "/app/missing") {
+		t.Fatalf("should have been not found: %v", err)
+	}
+}
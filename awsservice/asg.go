@@ -0,0 +1,559 @@
+package awsservice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const defaultLaunchTemplateVersion = "$Latest"
+
+type AutoScalingGroupDefinition struct {
+	Name                   string
+	LaunchTemplateID       string // One of LaunchTemplateID or LaunchTemplateName is required
+	LaunchTemplateName     string
+	LaunchTemplateVersion  string // Optional (default: $Latest)
+	MinSize                int64
+	MaxSize                int64
+	DesiredCapacity        int64
+	Subnets                []string
+	LoadBalancers          []string          // Classic ELB names
+	HealthCheckType        string            // Optional: "EC2" (default) or "ELB"
+	HealthCheckGracePeriod int64             // Optional (seconds)
+	Tags                   map[string]string // Propagated to launched instances
+}
+
+type ASGInstanceInfo struct {
+	ID                    string
+	AvailabilityZone      string
+	InstanceType          string
+	LifecycleState        string
+	HealthStatus          string
+	LaunchTemplateVersion string
+	ProtectedFromScaleIn  bool
+}
+
+type AutoScalingGroupInfo struct {
+	Name                   string
+	ARN                    string
+	LaunchTemplateID       string
+	LaunchTemplateName     string
+	LaunchTemplateVersion  string
+	MinSize                int64
+	MaxSize                int64
+	DesiredCapacity        int64
+	Subnets                []string
+	LoadBalancers          []string
+	HealthCheckType        string
+	HealthCheckGracePeriod int64
+	Status                 string // Set while the group is being deleted
+	SuspendedProcesses     []string
+	Instances              []ASGInstanceInfo
+	Tags                   map[string]string
+}
+
+type InstanceRefreshPreferences struct {
+	MinHealthyPercentage int64 // Optional (default: 90)
+	InstanceWarmup       int64 // Optional (seconds, default: health check grace period)
+	SkipMatching         bool  // Skip instances already on the desired launch template
+}
+
+type InstanceRefreshInfo struct {
+	ID                 string
+	Status             string
+	StatusReason       string
+	PercentageComplete int64
+	InstancesToUpdate  int64
+	StartTime          time.Time
+	EndTime            time.Time
+}
+
+// Done returns whether the refresh has reached a terminal status
+func (iri *InstanceRefreshInfo) Done() bool {
+	switch iri.Status {
+	case autoscaling.InstanceRefreshStatusSuccessful, autoscaling.InstanceRefreshStatusFailed,
+		autoscaling.InstanceRefreshStatusCancelled, autoscaling.InstanceRefreshStatusRollbackSuccessful,
+		autoscaling.InstanceRefreshStatusRollbackFailed:
+		return true
+	}
+	return false
+}
+
+func asgTags(name string, tags map[string]string) []*autoscaling.Tag {
+	rt := "auto-scaling-group"
+	out := []*autoscaling.Tag{}
+	for k, v := range tags {
+		ck := k
+		cv := v
+		out = append(out, &autoscaling.Tag{
+			Key:               &ck,
+			Value:             &cv,
+			PropagateAtLaunch: &True,
+			ResourceId:        &name,
+			ResourceType:      &rt,
+		})
+	}
+	return out
+}
+
+func (asgd *AutoScalingGroupDefinition) launchTemplate() (*autoscaling.LaunchTemplateSpecification, error) {
+	if (asgd.LaunchTemplateID == "") == (asgd.LaunchTemplateName == "") {
+		return nil, invalidDefinition("exactly one of launch template ID or name is required")
+	}
+	ver := asgd.LaunchTemplateVersion
+	if ver == "" {
+		ver = defaultLaunchTemplateVersion
+	}
+	lts := &autoscaling.LaunchTemplateSpecification{
+		Version: &ver,
+	}
+	if asgd.LaunchTemplateID != "" {
+		lts.LaunchTemplateId = &asgd.LaunchTemplateID
+	} else {
+		lts.LaunchTemplateName = &asgd.LaunchTemplateName
+	}
+	return lts, nil
+}
+
+func (aws *RealAWSService) CreateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	lts, err := asgd.launchTemplate()
+	if err != nil {
+		return err
+	}
+	vpczi := strings.Join(asgd.Subnets, ",")
+	casgi := &autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: &asgd.Name,
+		LaunchTemplate:       lts,
+		MinSize:              &asgd.MinSize,
+		MaxSize:              &asgd.MaxSize,
+		DesiredCapacity:      &asgd.DesiredCapacity,
+		VPCZoneIdentifier:    &vpczi,
+		Tags:                 asgTags(asgd.Name, asgd.Tags),
+	}
+	if len(asgd.LoadBalancers) > 0 {
+		casgi.LoadBalancerNames = stringSlicetoStringPointerSlice(asgd.LoadBalancers)
+	}
+	if asgd.HealthCheckType != "" {
+		casgi.HealthCheckType = &asgd.HealthCheckType
+	}
+	if asgd.HealthCheckGracePeriod != 0 {
+		casgi.HealthCheckGracePeriod = &asgd.HealthCheckGracePeriod
+	}
+	_, err = aws.asgc.CreateAutoScalingGroup(casgi)
+	return wrapError("CreateAutoScalingGroup", err)
+}
+
+// UpdateAutoScalingGroup applies the launch template, sizes, subnets, health check and tags in asgd to an
+// existing group. Load balancers are not changed (see AttachLoadBalancers/DetachLoadBalancers) and tags
+// absent from asgd are left in place.
+func (aws *RealAWSService) UpdateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	lts, err := asgd.launchTemplate()
+	if err != nil {
+		return err
+	}
+	vpczi := strings.Join(asgd.Subnets, ",")
+	uasgi := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: &asgd.Name,
+		LaunchTemplate:       lts,
+		MinSize:              &asgd.MinSize,
+		MaxSize:              &asgd.MaxSize,
+		DesiredCapacity:      &asgd.DesiredCapacity,
+		VPCZoneIdentifier:    &vpczi,
+	}
+	if asgd.HealthCheckType != "" {
+		uasgi.HealthCheckType = &asgd.HealthCheckType
+	}
+	if asgd.HealthCheckGracePeriod != 0 {
+		uasgi.HealthCheckGracePeriod = &asgd.HealthCheckGracePeriod
+	}
+	if _, err := aws.asgc.UpdateAutoScalingGroup(uasgi); err != nil {
+		return wrapError("UpdateAutoScalingGroup", err)
+	}
+	if len(asgd.Tags) == 0 {
+		return nil
+	}
+	_, err = aws.asgc.CreateOrUpdateTags(&autoscaling.CreateOrUpdateTagsInput{
+		Tags: asgTags(asgd.Name, asgd.Tags),
+	})
+	return wrapError("UpdateAutoScalingGroup", err)
+}
+
+// DeleteAutoScalingGroup deletes a group. If force is true its instances are terminated as well;
+// otherwise the group must have no instances.
+func (aws *RealAWSService) DeleteAutoScalingGroup(name string, force bool) error {
+	_, err := aws.asgc.DeleteAutoScalingGroup(&autoscaling.DeleteAutoScalingGroupInput{
+		AutoScalingGroupName: &name,
+		ForceDelete:          &force,
+	})
+	return wrapError("DeleteAutoScalingGroup", err)
+}
+
+func asgInstanceInfo(i *autoscaling.Instance) ASGInstanceInfo {
+	ai := ASGInstanceInfo{
+		ID:               drefStringPtr(i.InstanceId),
+		AvailabilityZone: drefStringPtr(i.AvailabilityZone),
+		InstanceType:     drefStringPtr(i.InstanceType),
+		LifecycleState:   drefStringPtr(i.LifecycleState),
+		HealthStatus:     drefStringPtr(i.HealthStatus),
+	}
+	if i.LaunchTemplate != nil {
+		ai.LaunchTemplateVersion = drefStringPtr(i.LaunchTemplate.Version)
+	}
+	if i.ProtectedFromScaleIn != nil {
+		ai.ProtectedFromScaleIn = *i.ProtectedFromScaleIn
+	}
+	return ai
+}
+
+func (aws *RealAWSService) GetAutoScalingGroupInfo(name string) (*AutoScalingGroupInfo, error) {
+	result := &AutoScalingGroupInfo{}
+	res, err := aws.asgc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: stringSlicetoStringPointerSlice([]string{name}),
+	})
+	if err != nil {
+		return result, wrapError("GetAutoScalingGroupInfo", err)
+	}
+	if len(res.AutoScalingGroups) == 0 {
+		return result, notFoundError("GetAutoScalingGroupInfo", "auto scaling group not found: %v", name)
+	}
+	g := res.AutoScalingGroups[0]
+	result.Name = drefStringPtr(g.AutoScalingGroupName)
+	result.ARN = drefStringPtr(g.AutoScalingGroupARN)
+	if g.LaunchTemplate != nil {
+		result.LaunchTemplateID = drefStringPtr(g.LaunchTemplate.LaunchTemplateId)
+		result.LaunchTemplateName = drefStringPtr(g.LaunchTemplate.LaunchTemplateName)
+		result.LaunchTemplateVersion = drefStringPtr(g.LaunchTemplate.Version)
+	}
+	result.MinSize = drefInt64Ptr(g.MinSize)
+	result.MaxSize = drefInt64Ptr(g.MaxSize)
+	result.DesiredCapacity = drefInt64Ptr(g.DesiredCapacity)
+	result.Subnets = []string{}
+	if vpczi := drefStringPtr(g.VPCZoneIdentifier); vpczi != "" {
+		result.Subnets = strings.Split(vpczi, ",")
+	}
+	result.LoadBalancers = stringPointerSlicetoStringSlice(g.LoadBalancerNames)
+	result.HealthCheckType = drefStringPtr(g.HealthCheckType)
+	result.HealthCheckGracePeriod = drefInt64Ptr(g.HealthCheckGracePeriod)
+	result.Status = drefStringPtr(g.Status)
+	sp := []string{}
+	for _, p := range g.SuspendedProcesses {
+		sp = append(sp, drefStringPtr(p.ProcessName))
+	}
+	result.SuspendedProcesses = sp
+	insts := []ASGInstanceInfo{}
+	for _, i := range g.Instances {
+		insts = append(insts, asgInstanceInfo(i))
+	}
+	result.Instances = insts
+	tags := map[string]string{}
+	for _, t := range g.Tags {
+		tags[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
+	}
+	result.Tags = tags
+	return result, nil
+}
+
+// GetAutoScalingGroupInstances returns the group's instances with their lifecycle and health state
+func (aws *RealAWSService) GetAutoScalingGroupInstances(name string) ([]ASGInstanceInfo, error) {
+	asg, err := aws.GetAutoScalingGroupInfo(name)
+	if err != nil {
+		return []ASGInstanceInfo{}, err
+	}
+	return asg.Instances, nil
+}
+
+func (aws *RealAWSService) SetDesiredCapacity(name string, capacity int64, honorCooldown bool) error {
+	_, err := aws.asgc.SetDesiredCapacity(&autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: &name,
+		DesiredCapacity:      &capacity,
+		HonorCooldown:        &honorCooldown,
+	})
+	return wrapError("SetDesiredCapacity", err)
+}
+
+// SuspendProcesses suspends the named scaling processes (eg "Launch", "HealthCheck"), or all processes if none are given
+func (aws *RealAWSService) SuspendProcesses(name string, processes []string) error {
+	spq := &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: &name,
+	}
+	if len(processes) > 0 {
+		spq.ScalingProcesses = stringSlicetoStringPointerSlice(processes)
+	}
+	_, err := aws.asgc.SuspendProcesses(spq)
+	return wrapError("SuspendProcesses", err)
+}
+
+// ResumeProcesses resumes the named scaling processes, or all processes if none are given
+func (aws *RealAWSService) ResumeProcesses(name string, processes []string) error {
+	spq := &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: &name,
+	}
+	if len(processes) > 0 {
+		spq.ScalingProcesses = stringSlicetoStringPointerSlice(processes)
+	}
+	_, err := aws.asgc.ResumeProcesses(spq)
+	return wrapError("ResumeProcesses", err)
+}
+
+func (aws *RealAWSService) AttachLoadBalancers(name string, lbs []string) error {
+	_, err := aws.asgc.AttachLoadBalancers(&autoscaling.AttachLoadBalancersInput{
+		AutoScalingGroupName: &name,
+		LoadBalancerNames:    stringSlicetoStringPointerSlice(lbs),
+	})
+	return wrapError("AttachLoadBalancers", err)
+}
+
+func (aws *RealAWSService) DetachLoadBalancers(name string, lbs []string) error {
+	_, err := aws.asgc.DetachLoadBalancers(&autoscaling.DetachLoadBalancersInput{
+		AutoScalingGroupName: &name,
+		LoadBalancerNames:    stringSlicetoStringPointerSlice(lbs),
+	})
+	return wrapError("DetachLoadBalancers", err)
+}
+
+// StartInstanceRefresh starts a rolling replacement of the group's instances, returning the refresh ID.
+// prefs may be nil to use the AWS defaults.
+func (aws *RealAWSService) StartInstanceRefresh(name string, prefs *InstanceRefreshPreferences) (string, error) {
+	siri := &autoscaling.StartInstanceRefreshInput{
+		AutoScalingGroupName: &name,
+	}
+	if prefs != nil {
+		rp := &autoscaling.RefreshPreferences{
+			SkipMatching: &prefs.SkipMatching,
+		}
+		if prefs.MinHealthyPercentage != 0 {
+			rp.MinHealthyPercentage = &prefs.MinHealthyPercentage
+		}
+		if prefs.InstanceWarmup != 0 {
+			rp.InstanceWarmup = &prefs.InstanceWarmup
+		}
+		siri.Preferences = rp
+	}
+	o, err := aws.asgc.StartInstanceRefresh(siri)
+	if err != nil {
+		return "", wrapError("StartInstanceRefresh", err)
+	}
+	return drefStringPtr(o.InstanceRefreshId), nil
+}
+
+func (aws *RealAWSService) CancelInstanceRefresh(name string) error {
+	_, err := aws.asgc.CancelInstanceRefresh(&autoscaling.CancelInstanceRefreshInput{
+		AutoScalingGroupName: &name,
+	})
+	return wrapError("CancelInstanceRefresh", err)
+}
+
+// GetInstanceRefreshes returns the given refreshes of a group (the most recent ones if ids is empty), newest first
+func (aws *RealAWSService) GetInstanceRefreshes(name string, ids []string) ([]InstanceRefreshInfo, error) {
+	result := []InstanceRefreshInfo{}
+	diri := &autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: &name,
+	}
+	if len(ids) > 0 {
+		diri.InstanceRefreshIds = stringSlicetoStringPointerSlice(ids)
+	}
+	res, err := aws.asgc.DescribeInstanceRefreshes(diri)
+	if err != nil {
+		return result, wrapError("GetInstanceRefreshes", err)
+	}
+	for _, ir := range res.InstanceRefreshes {
+		result = append(result, InstanceRefreshInfo{
+			ID:                 drefStringPtr(ir.InstanceRefreshId),
+			Status:             drefStringPtr(ir.Status),
+			StatusReason:       drefStringPtr(ir.StatusReason),
+			PercentageComplete: drefInt64Ptr(ir.PercentageComplete),
+			InstancesToUpdate:  drefInt64Ptr(ir.InstancesToUpdate),
+			StartTime:          drefTimePtr(ir.StartTime),
+			EndTime:            drefTimePtr(ir.EndTime),
+		})
+	}
+	return result, nil
+}
+
+// WaitForInstanceRefresh polls a refresh every interval until it reaches a terminal status or timeout elapses.
+// It returns an error if the refresh did not succeed.
+func (aws *RealAWSService) WaitForInstanceRefresh(name string, id string, interval time.Duration, timeout time.Duration) (*InstanceRefreshInfo, error) {
+	result := &InstanceRefreshInfo{ID: id}
+	if interval <= 0 {
+		return result, invalidDefinition("poll interval must be positive: %v", interval)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		irs, err := aws.GetInstanceRefreshes(name, []string{id})
+		if err != nil {
+			return result, err
+		}
+		if len(irs) == 0 {
+			return result, notFoundError("WaitForInstanceRefresh", "instance refresh not found: %v", id)
+		}
+		*result = irs[0]
+		if result.Done() {
+			if result.Status != autoscaling.InstanceRefreshStatusSuccessful {
+				return result, fmt.Errorf("instance refresh %v: %v: %v", id, result.Status, result.StatusReason)
+			}
+			return result, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return result, fmt.Errorf("timed out waiting for instance refresh %v (%v, %v%% complete)", id, result.Status, result.PercentageComplete)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) CreateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateAutoScalingGroup",
+		NotableParams: map[string]string{
+			"name":             asgd.Name,
+			"launch_template":  asgd.LaunchTemplateID + asgd.LaunchTemplateName,
+			"desired_capacity": fmt.Sprintf("%v", asgd.DesiredCapacity),
+			"subnets":          fmt.Sprintf("%v", asgd.Subnets),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) UpdateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "UpdateAutoScalingGroup",
+		NotableParams: map[string]string{
+			"name":             asgd.Name,
+			"launch_template":  asgd.LaunchTemplateID + asgd.LaunchTemplateName,
+			"desired_capacity": fmt.Sprintf("%v", asgd.DesiredCapacity),
+			"subnets":          fmt.Sprintf("%v", asgd.Subnets),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) DeleteAutoScalingGroup(name string, force bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteAutoScalingGroup",
+		NotableParams: map[string]string{
+			"name":  name,
+			"force": fmt.Sprintf("%v", force),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetAutoScalingGroupInfo(name string) (*AutoScalingGroupInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetAutoScalingGroupInfo",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return &AutoScalingGroupInfo{Name: name}, nil
+}
+
+func (aws *TestingAWSService) GetAutoScalingGroupInstances(name string) ([]ASGInstanceInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetAutoScalingGroupInstances",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return []ASGInstanceInfo{}, nil
+}
+
+func (aws *TestingAWSService) SetDesiredCapacity(name string, capacity int64, honorCooldown bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SetDesiredCapacity",
+		NotableParams: map[string]string{
+			"name":     name,
+			"capacity": fmt.Sprintf("%v", capacity),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) SuspendProcesses(name string, processes []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SuspendProcesses",
+		NotableParams: map[string]string{
+			"name":      name,
+			"processes": fmt.Sprintf("%v", processes),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) ResumeProcesses(name string, processes []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ResumeProcesses",
+		NotableParams: map[string]string{
+			"name":      name,
+			"processes": fmt.Sprintf("%v", processes),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) AttachLoadBalancers(name string, lbs []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AttachLoadBalancers",
+		NotableParams: map[string]string{
+			"name":           name,
+			"load_balancers": fmt.Sprintf("%v", lbs),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) DetachLoadBalancers(name string, lbs []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DetachLoadBalancers",
+		NotableParams: map[string]string{
+			"name":           name,
+			"load_balancers": fmt.Sprintf("%v", lbs),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) StartInstanceRefresh(name string, prefs *InstanceRefreshPreferences) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "StartInstanceRefresh",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) CancelInstanceRefresh(name string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CancelInstanceRefresh",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetInstanceRefreshes(name string, ids []string) ([]InstanceRefreshInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInstanceRefreshes",
+		NotableParams: map[string]string{
+			"name": name,
+			"ids":  fmt.Sprintf("%v", ids),
+		},
+	})
+	return []InstanceRefreshInfo{}, nil
+}
+
+func (aws *TestingAWSService) WaitForInstanceRefresh(name string, id string, interval time.Duration, timeout time.Duration) (*InstanceRefreshInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "WaitForInstanceRefresh",
+		NotableParams: map[string]string{
+			"name": name,
+			"id":   id,
+		},
+	})
+	return &InstanceRefreshInfo{ID: id, Status: autoscaling.InstanceRefreshStatusSuccessful}, nil
+}
@@ -0,0 +1,193 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const asgDescribeResponse = `<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups><member>` +
+	`<AutoScalingGroupName>web</AutoScalingGroupName><MinSize>1</MinSize><MaxSize>4</MaxSize><DesiredCapacity>2</DesiredCapacity>` +
+	`<VPCZoneIdentifier>subnet-1,subnet-2</VPCZoneIdentifier><HealthCheckType>ELB</HealthCheckType>` +
+	`<LaunchTemplate><LaunchTemplateId>lt-1</LaunchTemplateId><Version>3</Version></LaunchTemplate>` +
+	`<SuspendedProcesses><member><ProcessName>Launch</ProcessName></member></SuspendedProcesses>` +
+	`<Instances>` +
+	`<member><InstanceId>i-1</InstanceId><AvailabilityZone>us-west-2a</AvailabilityZone><LifecycleState>InService</LifecycleState>` +
+	`<HealthStatus>Healthy</HealthStatus><LaunchTemplate><LaunchTemplateId>lt-1</LaunchTemplateId><Version>3</Version></LaunchTemplate>` +
+	`<ProtectedFromScaleIn>true</ProtectedFromScaleIn></member>` +
+	`<member><InstanceId>i-2</InstanceId><LifecycleState>Terminating:Wait</LifecycleState><HealthStatus>Unhealthy</HealthStatus></member>` +
+	`</Instances>` +
+	`<Tags><member><Key>Name</Key><Value>web</Value></member></Tags>` +
+	`</member></AutoScalingGroups></DescribeAutoScalingGroupsResult></DescribeAutoScalingGroupsResponse>`
+
+func TestCreateAutoScalingGroup(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"CreateAutoScalingGroup": `<CreateAutoScalingGroupResponse></CreateAutoScalingGroupResponse>`,
+	})
+	defer done()
+	err := svc.CreateAutoScalingGroup(&AutoScalingGroupDefinition{
+		Name:               "web",
+		LaunchTemplateName: "web-lt",
+		MinSize:            1,
+		MaxSize:            4,
+		DesiredCapacity:    2,
+		Subnets:            []string{"subnet-1", "subnet-2"},
+		LoadBalancers:      []string{"web-lb"},
+		HealthCheckType:    "ELB",
+		Tags:               map[string]string{"Name": "web"},
+	})
+	if err != nil {
+		t.Fatalf("error creating group: %v", err)
+	}
+	form := f.requests[0]
+	expected := map[string]string{
+		"AutoScalingGroupName":              "web",
+		"LaunchTemplate.LaunchTemplateName": "web-lt",
+		"LaunchTemplate.Version":            "$Latest",
+		"DesiredCapacity":                   "2",
+		"VPCZoneIdentifier":                 "subnet-1,subnet-2",
+		"LoadBalancerNames.member.1":        "web-lb",
+		"HealthCheckType":                   "ELB",
+		"Tags.member.1.Key":                 "Name",
+		"Tags.member.1.PropagateAtLaunch":   "true",
+		"Tags.member.1.ResourceId":          "web",
+		"Tags.member.1.ResourceType":        "auto-scaling-group",
+		"LaunchTemplate.LaunchTemplateId":   "",
+		"HealthCheckGracePeriod":            "",
+		"Tags.member.1.Value":               "web",
+		"LoadBalancerNames.member.2":        "",
+	}
+	for k, v := range expected {
+		if form.Get(k) != v {
+			t.Fatalf("bad %v: %q (expected: %q)", k, form.Get(k), v)
+		}
+	}
+	err = svc.CreateAutoScalingGroup(&AutoScalingGroupDefinition{Name: "web", LaunchTemplateID: "lt-1", LaunchTemplateName: "web-lt"})
+	if !errors.Is(err, ErrInvalidParameter) || len(f.requests) != 1 {
+		t.Fatalf("both launch template ID and name should be rejected locally: %v", err)
+	}
+}
+
+func TestUpdateAutoScalingGroup(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"UpdateAutoScalingGroup": `<UpdateAutoScalingGroupResponse></UpdateAutoScalingGroupResponse>`,
+		"CreateOrUpdateTags":     `<CreateOrUpdateTagsResponse></CreateOrUpdateTagsResponse>`,
+	})
+	defer done()
+	asgd := &AutoScalingGroupDefinition{
+		Name:                  "web",
+		LaunchTemplateID:      "lt-1",
+		LaunchTemplateVersion: "3",
+		MaxSize:               4,
+		Subnets:               []string{"subnet-1"},
+	}
+	if err := svc.UpdateAutoScalingGroup(asgd); err != nil {
+		t.Fatalf("error updating group: %v", err)
+	}
+	if len(f.requests) != 1 {
+		t.Fatalf("tags should not be updated when none are given: %v", f.requests)
+	}
+	form := f.requests[0]
+	if form.Get("LaunchTemplate.LaunchTemplateId") != "lt-1" || form.Get("LaunchTemplate.Version") != "3" || form.Get("MaxSize") != "4" ||
+		form.Get("VPCZoneIdentifier") != "subnet-1" || form.Get("LoadBalancerNames.member.1") != "" {
+		t.Fatalf("bad update request: %v", form)
+	}
+	asgd.Tags = map[string]string{"team": "web"}
+	if err := svc.UpdateAutoScalingGroup(asgd); err != nil {
+		t.Fatalf("error updating group: %v", err)
+	}
+	if len(f.requests) != 3 || f.requests[2].Get("Action") != "CreateOrUpdateTags" || f.requests[2].Get("Tags.member.1.Value") != "web" {
+		t.Fatalf("bad tag update: %v", f.requests)
+	}
+}
+
+func TestGetAutoScalingGroupInstances(t *testing.T) {
+	svc, _, done := newEC2FakeService(map[string]string{
+		"DescribeAutoScalingGroups": asgDescribeResponse,
+	})
+	defer done()
+	asg, err := svc.GetAutoScalingGroupInfo("web")
+	if err != nil {
+		t.Fatalf("error getting group: %v", err)
+	}
+	if asg.LaunchTemplateID != "lt-1" || asg.DesiredCapacity != 2 || strings.Join(asg.Subnets, ",") != "subnet-1,subnet-2" ||
+		strings.Join(asg.SuspendedProcesses, ",") != "Launch" || asg.Tags["Name"] != "web" {
+		t.Fatalf("bad group: %+v", asg)
+	}
+	insts, err := svc.GetAutoScalingGroupInstances("web")
+	if err != nil {
+		t.Fatalf("error getting instances: %v", err)
+	}
+	expected := []ASGInstanceInfo{
+		{ID: "i-1", AvailabilityZone: "us-west-2a", LifecycleState: "InService", HealthStatus: "Healthy", LaunchTemplateVersion: "3", ProtectedFromScaleIn: true},
+		{ID: "i-2", LifecycleState: "Terminating:Wait", HealthStatus: "Unhealthy"},
+	}
+	if len(insts) != len(expected) {
+		t.Fatalf("bad instances: %+v", insts)
+	}
+	for i := range expected {
+		if insts[i] != expected[i] {
+			t.Fatalf("bad instance %v: %+v (expected: %+v)", i, insts[i], expected[i])
+		}
+	}
+}
+
+func TestGetAutoScalingGroupNotFound(t *testing.T) {
+	svc, _, done := newEC2FakeService(map[string]string{
+		"DescribeAutoScalingGroups": `<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups></AutoScalingGroups></DescribeAutoScalingGroupsResult></DescribeAutoScalingGroupsResponse>`,
+	})
+	defer done()
+	if _, err := svc.GetAutoScalingGroupInstances("web"); !IsNotFound(err) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+}
+
+// instanceRefreshServer answers DescribeInstanceRefreshes with each status in turn, repeating the last
+func instanceRefreshServer(statuses ...string) *httptest.Server {
+	n := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[n]
+		if n < len(statuses)-1 {
+			n++
+		}
+		fmt.Fprintf(w, `<DescribeInstanceRefreshesResponse><DescribeInstanceRefreshesResult><InstanceRefreshes><member>`+
+			`<InstanceRefreshId>r-1</InstanceRefreshId><Status>%v</Status><StatusReason>reason</StatusReason><PercentageComplete>%v</PercentageComplete>`+
+			`</member></InstanceRefreshes></DescribeInstanceRefreshesResult></DescribeInstanceRefreshesResponse>`, status, n*50)
+	}))
+}
+
+func TestWaitForInstanceRefresh(t *testing.T) {
+	ts := instanceRefreshServer("Pending", "InProgress", "Successful")
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	iri, err := svc.WaitForInstanceRefresh("web", "r-1", time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("error waiting: %v", err)
+	}
+	if iri.Status != "Successful" || !iri.Done() {
+		t.Fatalf("bad refresh: %+v", iri)
+	}
+
+	ts = instanceRefreshServer("InProgress", "Failed")
+	defer ts.Close()
+	svc = newEndpointAWSService(ts.URL)
+	iri, err = svc.WaitForInstanceRefresh("web", "r-1", time.Millisecond, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "Failed: reason") || iri.Status != "Failed" {
+		t.Fatalf("failed refresh should be an error: %v: %+v", err, iri)
+	}
+
+	ts = instanceRefreshServer("InProgress")
+	defer ts.Close()
+	svc = newEndpointAWSService(ts.URL)
+	iri, err = svc.WaitForInstanceRefresh("web", "r-1", 5*time.Millisecond, 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || iri.Status != "InProgress" {
+		t.Fatalf("should have timed out: %v: %+v", err, iri)
+	}
+	if _, err := svc.WaitForInstanceRefresh("web", "r-1", 0, time.Second); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("zero interval should be rejected: %v", err)
+	}
+}
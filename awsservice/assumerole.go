@@ -0,0 +1,164 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	defaultRoleSessionName = "awsservice"
+	// assumed credentials are refreshed this long before they expire
+	assumeRoleExpiryWindow = time.Minute
+	minAssumeRoleDuration  = 15 * time.Minute
+	maxAssumeRoleDuration  = 12 * time.Hour
+)
+
+// AssumeRoleConfig describes an IAM role to assume (typically in another account) via STS
+type AssumeRoleConfig struct {
+	RoleARN           string
+	ExternalID        string                   // Optional, required if the role's trust policy demands it
+	SessionName       string                   // Optional (default: "awsservice"), visible in CloudTrail
+	Duration          time.Duration            // Optional (default: 15m), between 15m and 12h and within the role's maximum
+	MFASerial         string                   // Optional MFA device serial number or ARN
+	MFATokenProvider  func() (string, error)   // Required with MFASerial, eg stscreds.StdinTokenProvider
+	Region            string                   // Optional (default: us-west-2)
+	SourceCredentials *credentials.Credentials // Optional credentials used to call STS (default: the default credential chain)
+}
+
+// Validate checks the config without calling AWS
+func (arc *AssumeRoleConfig) Validate() error {
+	ve := &ValidationError{}
+	if arc.RoleARN == "" {
+		ve.add("role ARN is required")
+	}
+	if arc.Duration != 0 && (arc.Duration < minAssumeRoleDuration || arc.Duration > maxAssumeRoleDuration) {
+		ve.add("duration must be between %v and %v: %v", minAssumeRoleDuration, maxAssumeRoleDuration, arc.Duration)
+	}
+	if (arc.MFASerial == "") != (arc.MFATokenProvider == nil) {
+		ve.add("MFA serial and MFA token provider must be given together")
+	}
+	return ve.errOrNil()
+}
+
+func (arc *AssumeRoleConfig) region() string {
+	if arc.Region == "" {
+		return awsRegion
+	}
+	return arc.Region
+}
+
+// credentials returns credentials that assume the role on first use and again shortly before each expiry
+func (arc *AssumeRoleConfig) credentials() *credentials.Credentials {
+	region := arc.region()
+	base := session.New(&aws.Config{Credentials: arc.SourceCredentials, Region: &region})
+	return stscreds.NewCredentials(base, arc.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = defaultRoleSessionName
+		if arc.SessionName != "" {
+			p.RoleSessionName = arc.SessionName
+		}
+		if arc.Duration != 0 {
+			p.Duration = arc.Duration
+		}
+		if arc.ExternalID != "" {
+			p.ExternalID = aws.String(arc.ExternalID)
+		}
+		if arc.MFASerial != "" {
+			p.SerialNumber = aws.String(arc.MFASerial)
+			p.TokenProvider = arc.MFATokenProvider
+		}
+		p.ExpiryWindow = assumeRoleExpiryWindow
+	})
+}
+
+// NewAssumeRoleAWSService uses credentials obtained by assuming an IAM role. The role is not assumed until the
+// first call, and credentials are refreshed automatically before they expire.
+func NewAssumeRoleAWSService(arc *AssumeRoleConfig) (AWSService, error) {
+	if err := arc.Validate(); err != nil {
+		return nil, err
+	}
+	region := arc.region()
+	s := session.New(&aws.Config{Credentials: arc.credentials(), Region: &region})
+	return newRealAWSService(s), nil
+}
+
+// AWSServiceKey identifies a service in an AWSServiceRegistry
+type AWSServiceKey struct {
+	Account string // Account ID or a name such as "staging"
+	Region  string
+}
+
+func (k AWSServiceKey) String() string {
+	return fmt.Sprintf("%v/%v", k.Account, k.Region)
+}
+
+// AWSServiceRegistry holds services for several accounts and regions side by side.
+// It is safe for concurrent use.
+type AWSServiceRegistry struct {
+	mu       sync.Mutex
+	services map[AWSServiceKey]AWSService
+}
+
+func NewAWSServiceRegistry() *AWSServiceRegistry {
+	return &AWSServiceRegistry{
+		services: map[AWSServiceKey]AWSService{},
+	}
+}
+
+// Register adds svc, replacing any service already registered for the account and region
+func (r *AWSServiceRegistry) Register(account string, region string, svc AWSService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services[AWSServiceKey{Account: account, Region: region}] = svc
+}
+
+// RegisterAssumeRole creates a service with NewAssumeRoleAWSService and registers it under account and the config region
+func (r *AWSServiceRegistry) RegisterAssumeRole(account string, arc *AssumeRoleConfig) (AWSService, error) {
+	svc, err := NewAssumeRoleAWSService(arc)
+	if err != nil {
+		return nil, err
+	}
+	r.Register(account, arc.region(), svc)
+	return svc, nil
+}
+
+// Get returns the service registered for the account and region, or an ErrNotFound error
+func (r *AWSServiceRegistry) Get(account string, region string) (AWSService, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := AWSServiceKey{Account: account, Region: region}
+	svc, ok := r.services[k]
+	if !ok {
+		return nil, notFoundError("AWSServiceRegistry.Get", "no service registered for %v", k)
+	}
+	return svc, nil
+}
+
+func (r *AWSServiceRegistry) Remove(account string, region string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.services, AWSServiceKey{Account: account, Region: region})
+}
+
+// Keys returns the registered accounts and regions, sorted
+func (r *AWSServiceRegistry) Keys() []AWSServiceKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := []AWSServiceKey{}
+	for k := range r.services {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Account != keys[j].Account {
+			return keys[i].Account < keys[j].Account
+		}
+		return keys[i].Region < keys[j].Region
+	})
+	return keys
+}
@@ -0,0 +1,59 @@
+package awsservice
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAssumeRoleConfigValidate(t *testing.T) {
+	arc := &AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/deploy"}
+	if err := arc.Validate(); err != nil {
+		t.Fatalf("should have been valid: %v", err)
+	}
+	arc = &AssumeRoleConfig{
+		Duration:  time.Minute,
+		MFASerial: "arn:aws:iam::123456789012:mfa/user",
+	}
+	err := arc.Validate()
+	ve := &ValidationError{}
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected validation error: %v", err)
+	}
+	if len(ve.Problems) != 3 {
+		t.Fatalf("expected 3 problems: %v", ve.Problems)
+	}
+	if _, err := NewAssumeRoleAWSService(arc); err == nil {
+		t.Fatalf("constructor should have rejected invalid config")
+	}
+}
+
+func TestAWSServiceRegistry(t *testing.T) {
+	r := NewAWSServiceRegistry()
+	staging := &RealAWSService{}
+	prod := &RealAWSService{}
+	r.Register("staging", "us-west-2", staging)
+	r.Register("production", "us-west-2", prod)
+	r.Register("production", "us-east-1", prod)
+	svc, err := r.Get("staging", "us-west-2")
+	if err != nil || svc != staging {
+		t.Fatalf("bad service: %v: %v", svc, err)
+	}
+	if _, err := r.Get("staging", "us-east-1"); !IsNotFound(err) {
+		t.Fatalf("expected not found: %v", err)
+	}
+	keys := r.Keys()
+	if len(keys) != 3 || keys[0].String() != "production/us-east-1" || keys[2].String() != "staging/us-west-2" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	r.Remove("staging", "us-west-2")
+	if _, err := r.Get("staging", "us-west-2"); !IsNotFound(err) {
+		t.Fatalf("expected not found after remove: %v", err)
+	}
+	if _, err := r.RegisterAssumeRole("dev", &AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/deploy"}); err != nil {
+		t.Fatalf("should have registered: %v", err)
+	}
+	if _, err := r.Get("dev", awsRegion); err != nil {
+		t.Fatalf("assume role service should use the default region: %v", err)
+	}
+}
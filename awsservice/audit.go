@@ -0,0 +1,216 @@
+package awsservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+// AuditRecord is the audit trail entry for one AWSService call
+type AuditRecord struct {
+	Time       time.Time              `json:"time"`
+	Action     string                 `json:"action"`
+	Params     map[string]interface{} `json:"params"`
+	Caller     string                 `json:"caller"`
+	CallSite   string                 `json:"call_site"`
+	DurationMS int64                  `json:"duration_ms"`
+	Result     interface{}            `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	ErrorCode  string                 `json:"error_code,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"` // Last AWS request made, when known
+}
+
+// AuditSink receives audit records. Sinks must be safe for concurrent use.
+type AuditSink interface {
+	WriteAuditRecord(*AuditRecord) error
+}
+
+// JSONAuditSink writes each record as a line of JSON
+type JSONAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// OpenJSONAuditFile appends JSON lines to the file at path, creating it if needed. Close the returned file when done.
+func OpenJSONAuditFile(path string) (*JSONAuditSink, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening audit file: %v", err)
+	}
+	return NewJSONAuditSink(f), f, nil
+}
+
+func (s *JSONAuditSink) WriteAuditRecord(ar *AuditRecord) error {
+	b, err := json.Marshal(ar)
+	if err != nil {
+		return fmt.Errorf("error marshaling audit record: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// LoggerAuditSink writes a one line summary of each record to a logger
+type LoggerAuditSink struct {
+	Logger *log.Logger
+}
+
+func (s *LoggerAuditSink) WriteAuditRecord(ar *AuditRecord) error {
+	params, err := json.Marshal(ar.Params)
+	if err != nil {
+		return fmt.Errorf("error marshaling audit params: %v", err)
+	}
+	result := "ok"
+	if ar.Error != "" {
+		result = fmt.Sprintf("error: %v", ar.Error)
+	}
+	s.Logger.Printf("aws audit: %v %s by %v at %v (%vms): %v", ar.Action, params, ar.Caller, ar.CallSite, ar.DurationMS, result)
+	return nil
+}
+
+// ChannelAuditSink sends each record on a channel, blocking until it is received
+type ChannelAuditSink chan<- *AuditRecord
+
+func (s ChannelAuditSink) WriteAuditRecord(ar *AuditRecord) error {
+	s <- ar
+	return nil
+}
+
+// AWSAuditor is an AWSCallHook writing an audit record for every call
+type AWSAuditor struct {
+	Sink    AuditSink
+	Caller  string      // Identity recorded with each call (default: DefaultAuditCaller())
+	OnError func(error) // Optional, called if the sink fails (the AWS call is unaffected)
+}
+
+// DefaultAuditCaller identifies the current process as "user@host"
+func DefaultAuditCaller() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%v@%v", name, host)
+}
+
+// NewAuditingAWSService decorates svc so every call is recorded to sink
+func NewAuditingAWSService(svc AWSService, sink AuditSink) *WrappedAWSService {
+	return WrapAWSService(svc, &AWSAuditor{Sink: sink, Caller: DefaultAuditCaller()})
+}
+
+func (a *AWSAuditor) BeforeCall(c *AWSCall) {}
+
+func (a *AWSAuditor) AfterCall(c *AWSCall) {
+	ar := &AuditRecord{
+		Time:       c.Start,
+		Action:     c.Method,
+		Params:     map[string]interface{}{},
+		Caller:     a.Caller,
+		CallSite:   c.CallSite,
+		DurationMS: int64(c.Duration / time.Millisecond),
+		Result:     auditValue(c.Result),
+	}
+	if ar.Caller == "" {
+		ar.Caller = DefaultAuditCaller()
+	}
+	for k, v := range c.Params {
+		ar.Params[k] = auditValue(v)
+	}
+	if len(c.RequestIDs) > 0 {
+		ar.RequestID = c.RequestIDs[len(c.RequestIDs)-1]
+	}
+	if c.Err != nil {
+		ar.Error = c.Err.Error()
+		var ae *AWSError
+		if errors.As(c.Err, &ae) {
+			ar.ErrorCode = ae.Code
+			if ae.RequestID != "" {
+				ar.RequestID = ae.RequestID
+			}
+		}
+	}
+	if err := a.Sink.WriteAuditRecord(ar); err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// auditedInstancesDefinition hides user data, which may contain secrets
+type auditedInstancesDefinition struct {
+	InstancesDefinition
+	UserData string
+}
+
+// auditedKeyPair hides private keys
+type auditedKeyPair struct {
+	KeyPair
+	PrivateKey string
+}
+
+// auditedParameterDefinition hides parameter values
+type auditedParameterDefinition struct {
+	ParameterDefinition
+	Value string
+}
+
+func redactParameters(pis []ParameterInfo) []ParameterInfo {
+	out := []ParameterInfo{}
+	for _, pi := range pis {
+		if pi.Type == ParameterSecureString {
+			pi.Value = redacted
+		}
+		out = append(out, pi)
+	}
+	return out
+}
+
+// auditValue returns v in a form safe to record: secrets are redacted and bodies summarized
+func auditValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return fmt.Sprintf("<%v bytes>", len(t))
+	case io.Reader:
+		return "<stream>"
+	case *InstancesDefinition:
+		if t == nil {
+			return t
+		}
+		return auditedInstancesDefinition{
+			InstancesDefinition: *t,
+			UserData:            fmt.Sprintf("<%v bytes>", len(t.UserData)),
+		}
+	case *KeyPair:
+		if t == nil {
+			return t
+		}
+		return auditedKeyPair{KeyPair: *t, PrivateKey: redacted}
+	case *ParameterDefinition:
+		if t == nil {
+			return t
+		}
+		return auditedParameterDefinition{ParameterDefinition: *t, Value: redacted}
+	case *ParameterInfo:
+		if t == nil {
+			return t
+		}
+		return redactParameters([]ParameterInfo{*t})[0]
+	case []ParameterInfo:
+		return redactParameters(t)
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package awsservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+var _ AWSService = &WrappedAWSService{}
+
+// stubAWSService implements the methods the tests call; anything else panics
+type stubAWSService struct {
+	AWSService
+}
+
+func (s *stubAWSService) RunInstances(idef *InstancesDefinition) ([]string, error) {
+	return []string{"i-1"}, nil
+}
+
+func (s *stubAWSService) TerminateInstances(ids []string) error {
+	return wrapError("TerminateInstances", awserr.NewRequestFailure(awserr.New("UnauthorizedOperation", "denied", nil), 403, "req-1"))
+}
+
+func (s *stubAWSService) PutParameter(pd *ParameterDefinition) (int64, error) {
+	return 3, nil
+}
+
+func TestAuditingAWSService(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svc := WrapAWSService(&stubAWSService{}, &AWSAuditor{Sink: NewJSONAuditSink(buf), Caller: "deployer@host"})
+	idef := testInstancesDefinition()
+	idef.UserData = []byte("#!/bin/sh\nexport SECRET=hunter2\n")
+	if _, err := svc.RunInstances(idef); err != nil {
+		t.Fatalf("error running instances: %v", err)
+	}
+	if err := svc.TerminateInstances([]string{"i-1"}); err == nil {
+		t.Fatalf("should have failed")
+	}
+	if _, err := svc.PutParameter(&ParameterDefinition{Name: "/app/password", Value: "hunter2", Type: ParameterSecureString}); err != nil {
+		t.Fatalf("error putting parameter: %v", err)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Fatalf("secret leaked into audit log: %v", buf.String())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records: %v", lines)
+	}
+	recs := []AuditRecord{}
+	for _, l := range lines {
+		ar := AuditRecord{}
+		if err := json.Unmarshal([]byte(l), &ar); err != nil {
+			t.Fatalf("bad record: %v: %v", l, err)
+		}
+		recs = append(recs, ar)
+	}
+	if recs[0].Action != "RunInstances" || recs[0].Caller != "deployer@host" || recs[0].Error != "" {
+		t.Fatalf("bad run record: %+v", recs[0])
+	}
+	if !strings.Contains(recs[0].CallSite, "audit_test.go") {
+		t.Fatalf("call site should be the test: %v", recs[0].CallSite)
+	}
+	if ids, ok := recs[0].Result.([]interface{}); !ok || len(ids) != 1 || ids[0] != "i-1" {
+		t.Fatalf("bad run result: %v", recs[0].Result)
+	}
+	if recs[1].ErrorCode != "UnauthorizedOperation" || recs[1].RequestID != "req-1" {
+		t.Fatalf("bad terminate record: %+v", recs[1])
+	}
+}
+
+func TestAuditRequestID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("X-Amzn-RequestId", "req-"+r.Form.Get("InstanceId.1"))
+		w.Write([]byte(`<TerminateInstancesResponse><instancesSet></instancesSet></TerminateInstancesResponse>`))
+	}))
+	defer ts.Close()
+	ch := make(chan *AuditRecord, 10)
+	svc := WrapAWSService(newEndpointAWSService(ts.URL), &AWSAuditor{Sink: ChannelAuditSink(ch)})
+	var wg sync.WaitGroup
+	for i := 0; i < cap(ch); i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := svc.TerminateInstances([]string{id}); err != nil {
+				t.Errorf("error terminating: %v", err)
+			}
+		}(fmt.Sprintf("i-%v", i))
+	}
+	wg.Wait()
+	close(ch)
+	for ar := range ch {
+		ids, _ := ar.Params["ids"].([]string)
+		if len(ids) != 1 || ar.RequestID != "req-"+ids[0] {
+			t.Fatalf("successful call should have its own request ID: %+v", ar)
+		}
+	}
+}
+
+func TestChannelAuditSink(t *testing.T) {
+	ch := make(chan *AuditRecord, 1)
+	svc := WrapAWSService(&stubAWSService{}, &AWSAuditor{Sink: ChannelAuditSink(ch)})
+	svc.RunInstances(testInstancesDefinition())
+	ar := <-ch
+	if ar.Action != "RunInstances" || ar.Caller == "" {
+		t.Fatalf("bad record: %+v", ar)
+	}
+}
@@ -1,12 +1,22 @@
 package awsservice
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
 )
 
 var awsRegion = "us-west-2"
@@ -19,11 +29,27 @@ type AWSLoadBalancerService interface {
 	GetLoadBalancerInfo(string) (*LoadBalancerInfo, error)
 	GetInstanceHealth(string) (*LBInstanceHealthInfo, error)
 	SetHealthCheck(string, *LBHealthCheck) error
+	GetLoadBalancerTags(string) (map[string]string, error)
+	FindLoadBalancersByTag(string, string) ([]string, error)
 }
 
 type AWSRoute53Service interface {
 	CreateDNSRecord(*Route53RecordDefinition) error
 	DeleteDNSRecord(*Route53RecordDefinition) error
+	UpsertDNSRecord(*Route53RecordDefinition) error
+	GetDNSRecords(string, string) ([]Route53RecordDefinition, error)
+}
+
+type AWSHostedZoneService interface {
+	CreateHostedZone(*HostedZoneDefinition) (*HostedZoneInfo, error)
+	DeleteHostedZone(string) error
+	GetHostedZoneInfo(string) (*HostedZoneInfo, error)
+	FindHostedZonesByName(string) ([]HostedZoneInfo, error)
+	AssociateVPCWithHostedZone(string, HostedZoneVPC) error
+	DisassociateVPCFromHostedZone(string, HostedZoneVPC) error
+	CreateDelegationSet(string) (*DelegationSet, error)
+	GetDelegationSets() ([]DelegationSet, error)
+	DeleteDelegationSet(string) error
 }
 
 type AWSEC2Service interface {
@@ -34,14 +60,142 @@ type AWSEC2Service interface {
 	TagInstances([]string, string, string) error
 	DeleteTag([]string, string) error
 	GetSubnetInfo(string) (*SubnetInfo, error)
+	FindSubnets(string, map[string]string) ([]SubnetInfo, error)
+	RunInstancesAcrossSubnets(*InstancesDefinition, []string) ([]SubnetPlacement, error)
 	GetInstancesInfo([]string) ([]InstanceInfo, error)
+	GetInstanceUserData(string) ([]byte, error)
 	TerminateInstances([]string) error
+	RebootInstances([]string) error
+	HibernateInstances([]string) error
+	ModifyInstanceType(string, string) error
+	ResizeInstance(string, string, time.Duration, time.Duration) error
+	SetTerminationProtection(string, bool) error
+	SetStopProtection(string, bool) error
+	SetSourceDestCheck(string, bool) error
+}
+
+type AWSInstanceDiagnosticsService interface {
+	GetConsoleOutput(string) (*ConsoleOutput, error)
+	GetConsoleScreenshot(string) ([]byte, error)
+	GetInstanceStatus([]string) ([]InstanceStatusInfo, error)
+}
+
+type AWSKeyPairService interface {
+	CreateKeyPair(*KeyPairDefinition) (*KeyPair, error)
+	ImportKeyPair(*KeyPairDefinition) (*KeyPairInfo, error)
+	GetKeyPairsInfo([]string) ([]KeyPairInfo, error)
+	DeleteKeyPair(string) error
+}
+
+type AWSSecurityGroupService interface {
+	CreateSecurityGroup(*SecurityGroupDefinition) (string, error)
+	DeleteSecurityGroup(string) error
+	AuthorizeIngress(string, []SecurityGroupRule) error
+	RevokeIngress(string, []SecurityGroupRule) error
+	AuthorizeEgress(string, []SecurityGroupRule) error
+	RevokeEgress(string, []SecurityGroupRule) error
+	GetSecurityGroupsInfo([]string) ([]SecurityGroupInfo, error)
+	FindSecurityGroups(string, map[string]string) ([]SecurityGroupInfo, error)
+	ReconcileSecurityGroup(string, []SecurityGroupRule, []SecurityGroupRule) (*SecurityGroupRuleChanges, error)
+}
+
+type AWSNetworkService interface {
+	GetVPCsInfo([]string) ([]VPCInfo, error)
+	FindVPCs(map[string]string) ([]VPCInfo, error)
+	GetRouteTables(string) ([]RouteTableInfo, error)
+	GetSubnetRouteTable(string) (*RouteTableInfo, error)
+	GetInternetGateways(string) ([]InternetGatewayInfo, error)
+	GetNATGateways(string) ([]NATGatewayInfo, error)
+	GetVPCEndpoints(string) ([]VPCEndpointInfo, error)
+}
+
+type AWSImageService interface {
+	FindAMIs(*AMIQuery) ([]AMIInfo, error)
+	FindLatestAMI(*AMIQuery) (*AMIInfo, error)
+	ResolveAMI(string) (string, error)
+}
+
+type AWSElasticIPService interface {
+	AllocateElasticIP(map[string]string) (*ElasticIPInfo, error)
+	AssociateElasticIP(string, string) (string, error)
+	AssociateElasticIPWithInterface(string, string, string) (string, error)
+	DisassociateElasticIP(string) error
+	ReleaseElasticIP(string) error
+	GetElasticIPsInfo([]string) ([]ElasticIPInfo, error)
+}
+
+type AWSNetworkInterfaceService interface {
+	CreateNetworkInterface(*NetworkInterfaceDefinition) (string, error)
+	DeleteNetworkInterface(string) error
+	AttachNetworkInterface(string, string, int64) (string, error)
+	DetachNetworkInterface(string, bool) error
+	AssignPrivateIPs(string, []string, int64) ([]string, error)
+	UnassignPrivateIPs(string, []string) error
+	GetNetworkInterfacesInfo([]string) ([]NetworkInterfaceInfo, error)
+}
+
+type AWSAutoScalingService interface {
+	CreateAutoScalingGroup(*AutoScalingGroupDefinition) error
+	UpdateAutoScalingGroup(*AutoScalingGroupDefinition) error
+	DeleteAutoScalingGroup(string, bool) error
+	GetAutoScalingGroupInfo(string) (*AutoScalingGroupInfo, error)
+	GetAutoScalingGroupInstances(string) ([]ASGInstanceInfo, error)
+	SetDesiredCapacity(string, int64, bool) error
+	SuspendProcesses(string, []string) error
+	ResumeProcesses(string, []string) error
+	AttachLoadBalancers(string, []string) error
+	DetachLoadBalancers(string, []string) error
+	StartInstanceRefresh(string, *InstanceRefreshPreferences) (string, error)
+	CancelInstanceRefresh(string) error
+	GetInstanceRefreshes(string, []string) ([]InstanceRefreshInfo, error)
+	WaitForInstanceRefresh(string, string, time.Duration, time.Duration) (*InstanceRefreshInfo, error)
+}
+
+type AWSS3Service interface {
+	PutObject(string, string, []byte, *S3PutOptions) error
+	UploadStream(string, string, io.Reader, *S3PutOptions) error
+	UploadFile(string, string, string, *S3PutOptions) error
+	GetObject(string, string) ([]byte, error)
+	GetObjectStream(string, string) (io.ReadCloser, *S3ObjectInfo, error)
+	DeleteObject(string, string) error
+	ListObjects(string, string) ([]S3ObjectInfo, error)
+	CopyObject(string, string, string, string, *S3PutOptions) error
+	PresignGetURL(string, string, time.Duration) (string, error)
+	PresignPutURL(string, string, time.Duration) (string, error)
+}
+
+type AWSCloudWatchService interface {
+	PutMetricData(string, []MetricDatum) error
+	GetMetricStatistics(*MetricStatisticsQuery) ([]MetricDatapoint, error)
+	CreateAlarm(*AlarmDefinition) error
+	DeleteAlarms([]string) error
+	GetAlarmsInfo([]string) ([]AlarmInfo, error)
+}
+
+type AWSSSMService interface {
+	GetParameter(string, bool) (*ParameterInfo, error)
+	GetParametersByPath(string, bool, bool) ([]ParameterInfo, error)
+	PutParameter(*ParameterDefinition) (int64, error)
+	DeleteParameter(string) error
+	GetParameterHistory(string, bool) ([]ParameterInfo, error)
 }
 
 type AWSService interface {
 	AWSLoadBalancerService
 	AWSRoute53Service
+	AWSHostedZoneService
 	AWSEC2Service
+	AWSInstanceDiagnosticsService
+	AWSKeyPairService
+	AWSSecurityGroupService
+	AWSNetworkService
+	AWSImageService
+	AWSElasticIPService
+	AWSNetworkInterfaceService
+	AWSAutoScalingService
+	AWSS3Service
+	AWSCloudWatchService
+	AWSSSMService
 }
 
 type LimitedRoute53API interface {
@@ -59,6 +213,17 @@ type RealAWSService struct {
 	elbc *elb.ELB
 	r53c *route53.Route53
 	ec2  *ec2.EC2
+	ssmc *ssm.SSM
+	asgc *autoscaling.AutoScaling
+	s3c  *s3.S3
+	cwc  *cloudwatch.CloudWatch
+
+	dryRun  *MutationPlan // see SetDryRun
+	metrics *AWSMetrics   // see SetMetrics
+	limiter *rateLimiter  // see SetRateLimits
+
+	recording  *Recording // see SetRecording
+	recordMode RecordMode
 }
 
 // Testing types
@@ -71,26 +236,72 @@ type TestingAWSService struct {
 	Log []AWSActionLog
 }
 
-// NewStaticAWSService uses the static credential provider (pass in access key ID and secret key)
-func NewStaticAWSService(id string, secret string) AWSService {
-	s := session.New(&aws.Config{Credentials: credentials.NewStaticCredentials(id, secret, ""), Region: &awsRegion})
-
-	return &RealAWSService{
+func newRealAWSService(s *session.Session) *RealAWSService {
+	aws := &RealAWSService{
 		elbc: elb.New(s),
 		r53c: route53.New(s),
 		ec2:  ec2.New(s),
+		ssmc: ssm.New(s),
+		asgc: autoscaling.New(s),
+		s3c:  s3.New(s),
+		cwc:  cloudwatch.New(s),
 	}
+	for _, h := range []*request.Handlers{
+		&aws.elbc.Handlers,
+		&aws.r53c.Handlers,
+		&aws.ec2.Handlers,
+		&aws.ssmc.Handlers,
+		&aws.asgc.Handlers,
+		&aws.s3c.Handlers,
+		&aws.cwc.Handlers,
+	} {
+		aws.addDryRunHandlers(h)
+		aws.addMetricsHandlers(h)
+		aws.addRateLimitHandlers(h)
+		aws.addRecordingHandlers(h)
+	}
+	return aws
+}
+
+// withRequestClient copies c, adding h to the end of its Complete handlers
+func withRequestClient(c *client.Client, h request.NamedHandler) *client.Client {
+	cc := *c
+	cc.Handlers = c.Handlers.Copy()
+	cc.Handlers.Complete.PushBackNamed(h)
+	return &cc
+}
+
+// withRequestIDCollector returns a copy of aws, sharing its settings, that also passes every completed
+// request to rc
+func (aws *RealAWSService) withRequestIDCollector(rc *requestIDCollector) *RealAWSService {
+	h := request.NamedHandler{Name: "awsservice.RequestID", Fn: rc.completeHandler}
+	cp := *aws
+	cp.elbc = &elb.ELB{Client: withRequestClient(aws.elbc.Client, h)}
+	cp.r53c = &route53.Route53{Client: withRequestClient(aws.r53c.Client, h)}
+	cp.ec2 = &ec2.EC2{Client: withRequestClient(aws.ec2.Client, h)}
+	cp.ssmc = &ssm.SSM{Client: withRequestClient(aws.ssmc.Client, h)}
+	cp.asgc = &autoscaling.AutoScaling{Client: withRequestClient(aws.asgc.Client, h)}
+	cp.s3c = &s3.S3{Client: withRequestClient(aws.s3c.Client, h)}
+	cp.cwc = &cloudwatch.CloudWatch{Client: withRequestClient(aws.cwc.Client, h)}
+	return &cp
+}
+
+// NewStaticAWSService uses the static credential provider (pass in access key ID and secret key)
+func NewStaticAWSService(id string, secret string) AWSService {
+	s := session.New(&aws.Config{Credentials: credentials.NewStaticCredentials(id, secret, ""), Region: &awsRegion})
+	return newRealAWSService(s)
 }
 
 // NewAWSService uses the default Environment credential store
 func NewAWSService() AWSService {
 	s := session.New(&aws.Config{Region: &awsRegion})
+	return newRealAWSService(s)
+}
 
-	return &RealAWSService{
-		elbc: elb.New(s),
-		r53c: route53.New(s),
-		ec2:  ec2.New(s),
-	}
+// NewRegionAWSService uses the default Environment credential store in the given region
+func NewRegionAWSService(region string) AWSService {
+	s := session.New(&aws.Config{Region: &region})
+	return newRealAWSService(s)
 }
 
 // Stupid AWS SDK...
@@ -125,6 +336,65 @@ func drefInt64Ptr(ptr *int64) int64 {
 	return *ptr
 }
 
+func drefFloat64Ptr(ptr *float64) float64 {
+	if ptr == nil {
+		return 0
+	}
+	return *ptr
+}
+
+func drefTimePtr(ptr *time.Time) time.Time {
+	if ptr == nil {
+		return time.Time{}
+	}
+	return *ptr
+}
+
+func ec2TagSpecification(rt string, tags map[string]string) []*ec2.TagSpecification {
+	if len(tags) == 0 {
+		return nil
+	}
+	ts := &ec2.TagSpecification{
+		ResourceType: &rt,
+	}
+	for k, v := range tags {
+		ck := k
+		cv := v
+		ts.Tags = append(ts.Tags, &ec2.Tag{Key: &ck, Value: &cv})
+	}
+	return []*ec2.TagSpecification{ts}
+}
+
+func ec2TagMap(tags []*ec2.Tag) map[string]string {
+	m := map[string]string{}
+	for _, t := range tags {
+		m[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
+	}
+	return m
+}
+
+// ec2Filters builds filters from exact-match attributes (skipping empty values) and tags
+func ec2Filters(attrs map[string]string, tags map[string]string) []*ec2.Filter {
+	filters := []*ec2.Filter{}
+	for n, v := range attrs {
+		if v == "" {
+			continue
+		}
+		cn := n
+		cv := v
+		filters = append(filters, &ec2.Filter{Name: &cn, Values: []*string{&cv}})
+	}
+	for k, v := range tags {
+		fn := fmt.Sprintf("tag:%v", k)
+		cv := v
+		filters = append(filters, &ec2.Filter{Name: &fn, Values: []*string{&cv}})
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
+}
+
 func instanceIDSlice(ids []string) []*elb.Instance {
 	instances := []*elb.Instance{}
 	for _, id := range ids {
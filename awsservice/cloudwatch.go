@@ -0,0 +1,352 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// maxMetricDataPerRequest is the CloudWatch limit on data points per PutMetricData call
+const maxMetricDataPerRequest = 1000
+
+// maxAlarmNamesPerRequest is the CloudWatch limit on alarm names per DeleteAlarms or DescribeAlarms call
+const maxAlarmNamesPerRequest = 100
+
+type MetricDatum struct {
+	Name       string
+	Value      float64
+	Unit       string // Optional, eg cloudwatch.StandardUnitCount
+	Dimensions map[string]string
+	Timestamp  time.Time // Optional (default: now)
+}
+
+type MetricStatisticsQuery struct {
+	Namespace  string
+	MetricName string
+	Dimensions map[string]string
+	Start      time.Time
+	End        time.Time
+	Period     time.Duration // Multiple of 60s
+	Statistics []string      // eg "Average", "Sum", "Maximum"
+	Unit       string        // Optional
+}
+
+type MetricDatapoint struct {
+	Timestamp   time.Time
+	Average     float64
+	Sum         float64
+	Minimum     float64
+	Maximum     float64
+	SampleCount float64
+	Unit        string
+}
+
+type AlarmDefinition struct {
+	Name                    string
+	Description             string
+	Namespace               string
+	MetricName              string
+	Dimensions              map[string]string
+	Statistic               string
+	Period                  time.Duration
+	EvaluationPeriods       int64
+	DatapointsToAlarm       int64 // Optional (default: EvaluationPeriods)
+	Threshold               float64
+	ComparisonOperator      string // eg cloudwatch.ComparisonOperatorGreaterThanThreshold
+	TreatMissingData        string // Optional: "breaching", "notBreaching", "ignore" or "missing"
+	Unit                    string // Optional
+	AlarmActions            []string
+	OKActions               []string
+	InsufficientDataActions []string
+}
+
+type AlarmInfo struct {
+	Name               string
+	ARN                string
+	Description        string
+	State              string
+	StateReason        string
+	StateUpdated       time.Time
+	Namespace          string
+	MetricName         string
+	Dimensions         map[string]string
+	Statistic          string
+	Period             time.Duration
+	EvaluationPeriods  int64
+	Threshold          float64
+	ComparisonOperator string
+	AlarmActions       []string
+}
+
+// ELBUnhealthyHostAlarm returns an alarm that fires when a classic load balancer (see CreateLoadBalancer) reports
+// more than threshold unhealthy instances for three consecutive minutes
+func ELBUnhealthyHostAlarm(lbName string, threshold float64, actions []string) *AlarmDefinition {
+	return &AlarmDefinition{
+		Name:               fmt.Sprintf("%v-unhealthy-hosts", lbName),
+		Description:        fmt.Sprintf("Unhealthy hosts behind ELB %v", lbName),
+		Namespace:          "AWS/ELB",
+		MetricName:         "UnHealthyHostCount",
+		Dimensions:         map[string]string{"LoadBalancerName": lbName},
+		Statistic:          cloudwatch.StatisticMaximum,
+		Period:             time.Minute,
+		EvaluationPeriods:  3,
+		Threshold:          threshold,
+		ComparisonOperator: cloudwatch.ComparisonOperatorGreaterThanThreshold,
+		TreatMissingData:   "notBreaching",
+		AlarmActions:       actions,
+		OKActions:          actions,
+	}
+}
+
+func cwDimensions(dims map[string]string) []*cloudwatch.Dimension {
+	out := []*cloudwatch.Dimension{}
+	for k, v := range dims {
+		ck := k
+		cv := v
+		out = append(out, &cloudwatch.Dimension{Name: &ck, Value: &cv})
+	}
+	return out
+}
+
+func cwDimensionMap(dims []*cloudwatch.Dimension) map[string]string {
+	m := map[string]string{}
+	for _, d := range dims {
+		m[drefStringPtr(d.Name)] = drefStringPtr(d.Value)
+	}
+	return m
+}
+
+// batchBounds splits n items into consecutive [start, end) ranges of at most size items, for APIs that limit
+// how many items each request may carry
+func batchBounds(n int, size int) [][2]int {
+	bounds := [][2]int{}
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		bounds = append(bounds, [2]int{start, end})
+	}
+	return bounds
+}
+
+// PutMetricData publishes data to namespace, split into as many requests as needed
+func (aws *RealAWSService) PutMetricData(namespace string, data []MetricDatum) error {
+	now := time.Now()
+	for _, b := range batchBounds(len(data), maxMetricDataPerRequest) {
+		md := []*cloudwatch.MetricDatum{}
+		for _, d := range data[b[0]:b[1]] {
+			name := d.Name
+			val := d.Value
+			ts := d.Timestamp
+			if ts.IsZero() {
+				ts = now
+			}
+			cwd := &cloudwatch.MetricDatum{
+				MetricName: &name,
+				Value:      &val,
+				Timestamp:  &ts,
+				Dimensions: cwDimensions(d.Dimensions),
+			}
+			if d.Unit != "" {
+				unit := d.Unit
+				cwd.Unit = &unit
+			}
+			md = append(md, cwd)
+		}
+		_, err := aws.cwc.PutMetricData(&cloudwatch.PutMetricDataInput{
+			Namespace:  &namespace,
+			MetricData: md,
+		})
+		if err != nil {
+			return wrapError("PutMetricData", err)
+		}
+	}
+	return nil
+}
+
+// GetMetricStatistics returns the datapoints matching q, oldest first
+func (aws *RealAWSService) GetMetricStatistics(q *MetricStatisticsQuery) ([]MetricDatapoint, error) {
+	result := []MetricDatapoint{}
+	period := int64(q.Period / time.Second)
+	gmsi := &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  &q.Namespace,
+		MetricName: &q.MetricName,
+		Dimensions: cwDimensions(q.Dimensions),
+		StartTime:  &q.Start,
+		EndTime:    &q.End,
+		Period:     &period,
+		Statistics: stringSlicetoStringPointerSlice(q.Statistics),
+	}
+	if q.Unit != "" {
+		gmsi.Unit = &q.Unit
+	}
+	res, err := aws.cwc.GetMetricStatistics(gmsi)
+	if err != nil {
+		return result, wrapError("GetMetricStatistics", err)
+	}
+	for _, dp := range res.Datapoints {
+		result = append(result, MetricDatapoint{
+			Timestamp:   drefTimePtr(dp.Timestamp),
+			Average:     drefFloat64Ptr(dp.Average),
+			Sum:         drefFloat64Ptr(dp.Sum),
+			Minimum:     drefFloat64Ptr(dp.Minimum),
+			Maximum:     drefFloat64Ptr(dp.Maximum),
+			SampleCount: drefFloat64Ptr(dp.SampleCount),
+			Unit:        drefStringPtr(dp.Unit),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result, nil
+}
+
+// CreateAlarm creates the alarm, or replaces it if one with the same name exists
+func (aws *RealAWSService) CreateAlarm(ad *AlarmDefinition) error {
+	period := int64(ad.Period / time.Second)
+	pmai := &cloudwatch.PutMetricAlarmInput{
+		AlarmName:               &ad.Name,
+		Namespace:               &ad.Namespace,
+		MetricName:              &ad.MetricName,
+		Dimensions:              cwDimensions(ad.Dimensions),
+		Statistic:               &ad.Statistic,
+		Period:                  &period,
+		EvaluationPeriods:       &ad.EvaluationPeriods,
+		Threshold:               &ad.Threshold,
+		ComparisonOperator:      &ad.ComparisonOperator,
+		AlarmActions:            stringSlicetoStringPointerSlice(ad.AlarmActions),
+		OKActions:               stringSlicetoStringPointerSlice(ad.OKActions),
+		InsufficientDataActions: stringSlicetoStringPointerSlice(ad.InsufficientDataActions),
+	}
+	if ad.Description != "" {
+		pmai.AlarmDescription = &ad.Description
+	}
+	if ad.DatapointsToAlarm != 0 {
+		pmai.DatapointsToAlarm = &ad.DatapointsToAlarm
+	}
+	if ad.TreatMissingData != "" {
+		pmai.TreatMissingData = &ad.TreatMissingData
+	}
+	if ad.Unit != "" {
+		pmai.Unit = &ad.Unit
+	}
+	_, err := aws.cwc.PutMetricAlarm(pmai)
+	return wrapError("CreateAlarm", err)
+}
+
+// DeleteAlarms deletes the named metric alarms, split into as many requests as needed
+func (aws *RealAWSService) DeleteAlarms(names []string) error {
+	for _, b := range batchBounds(len(names), maxAlarmNamesPerRequest) {
+		_, err := aws.cwc.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
+			AlarmNames: stringSlicetoStringPointerSlice(names[b[0]:b[1]]),
+		})
+		if err != nil {
+			return wrapError("DeleteAlarms", err)
+		}
+	}
+	return nil
+}
+
+// GetAlarmsInfo describes the named metric alarms (all alarms if names is empty), split into as many requests
+// as needed
+func (aws *RealAWSService) GetAlarmsInfo(names []string) ([]AlarmInfo, error) {
+	result := []AlarmInfo{}
+	dais := []*cloudwatch.DescribeAlarmsInput{{}}
+	if len(names) > 0 {
+		dais = []*cloudwatch.DescribeAlarmsInput{}
+		for _, b := range batchBounds(len(names), maxAlarmNamesPerRequest) {
+			dais = append(dais, &cloudwatch.DescribeAlarmsInput{
+				AlarmNames: stringSlicetoStringPointerSlice(names[b[0]:b[1]]),
+			})
+		}
+	}
+	for _, dai := range dais {
+		if err := aws.describeAlarms(dai, &result); err != nil {
+			return result, wrapError("GetAlarmsInfo", err)
+		}
+	}
+	return result, nil
+}
+
+// describeAlarms appends every metric alarm matching dai to result
+func (aws *RealAWSService) describeAlarms(dai *cloudwatch.DescribeAlarmsInput, result *[]AlarmInfo) error {
+	return aws.cwc.DescribeAlarmsPages(dai, func(page *cloudwatch.DescribeAlarmsOutput, last bool) bool {
+		for _, a := range page.MetricAlarms {
+			*result = append(*result, AlarmInfo{
+				Name:               drefStringPtr(a.AlarmName),
+				ARN:                drefStringPtr(a.AlarmArn),
+				Description:        drefStringPtr(a.AlarmDescription),
+				State:              drefStringPtr(a.StateValue),
+				StateReason:        drefStringPtr(a.StateReason),
+				StateUpdated:       drefTimePtr(a.StateUpdatedTimestamp),
+				Namespace:          drefStringPtr(a.Namespace),
+				MetricName:         drefStringPtr(a.MetricName),
+				Dimensions:         cwDimensionMap(a.Dimensions),
+				Statistic:          drefStringPtr(a.Statistic),
+				Period:             time.Duration(drefInt64Ptr(a.Period)) * time.Second,
+				EvaluationPeriods:  drefInt64Ptr(a.EvaluationPeriods),
+				Threshold:          drefFloat64Ptr(a.Threshold),
+				ComparisonOperator: drefStringPtr(a.ComparisonOperator),
+				AlarmActions:       stringPointerSlicetoStringSlice(a.AlarmActions),
+			})
+		}
+		return true
+	})
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) PutMetricData(namespace string, data []MetricDatum) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "PutMetricData",
+		NotableParams: map[string]string{
+			"namespace": namespace,
+			"count":     fmt.Sprintf("%v", len(data)),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetMetricStatistics(q *MetricStatisticsQuery) ([]MetricDatapoint, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetMetricStatistics",
+		NotableParams: map[string]string{
+			"namespace":   q.Namespace,
+			"metric_name": q.MetricName,
+		},
+	})
+	return []MetricDatapoint{}, nil
+}
+
+func (aws *TestingAWSService) CreateAlarm(ad *AlarmDefinition) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateAlarm",
+		NotableParams: map[string]string{
+			"name":        ad.Name,
+			"metric_name": ad.MetricName,
+			"threshold":   fmt.Sprintf("%v", ad.Threshold),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) DeleteAlarms(names []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteAlarms",
+		NotableParams: map[string]string{
+			"names": fmt.Sprintf("%v", names),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetAlarmsInfo(names []string) ([]AlarmInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetAlarmsInfo",
+		NotableParams: map[string]string{
+			"names": fmt.Sprintf("%v", names),
+		},
+	})
+	return []AlarmInfo{}, nil
+}
@@ -0,0 +1,69 @@
+package awsservice
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBatchBounds(t *testing.T) {
+	bounds := batchBounds(2500, maxMetricDataPerRequest)
+	if len(bounds) != 3 || bounds[0] != [2]int{0, 1000} || bounds[2] != [2]int{2000, 2500} {
+		t.Fatalf("incorrect batches: %v", bounds)
+	}
+	if len(batchBounds(0, maxMetricDataPerRequest)) != 0 {
+		t.Fatalf("empty data should have no batches")
+	}
+}
+
+func TestDeleteAlarms(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"DeleteAlarms": `<DeleteAlarmsResponse></DeleteAlarmsResponse>`,
+	})
+	defer done()
+	names := []string{}
+	for i := 0; i < 250; i++ {
+		names = append(names, fmt.Sprintf("alarm-%v", i))
+	}
+	if err := svc.DeleteAlarms(names); err != nil {
+		t.Fatalf("error deleting alarms: %v", err)
+	}
+	if len(f.requests) != 3 {
+		t.Fatalf("incorrect number of requests: %v", len(f.requests))
+	}
+	if f.requests[0].Get("AlarmNames.member.100") != "alarm-99" || f.requests[0].Get("AlarmNames.member.101") != "" ||
+		f.requests[2].Get("AlarmNames.member.1") != "alarm-200" || f.requests[2].Get("AlarmNames.member.51") != "" {
+		t.Fatalf("incorrect batches: %v", f.requests)
+	}
+	if err := svc.DeleteAlarms(nil); err != nil || len(f.requests) != 3 {
+		t.Fatalf("no names should make no requests: %v", err)
+	}
+}
+
+func TestGetAlarmsInfo(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"DescribeAlarms": `<DescribeAlarmsResponse><DescribeAlarmsResult><MetricAlarms><member>` +
+			`<AlarmName>alarm-0</AlarmName><StateValue>OK</StateValue><Period>60</Period><Threshold>90</Threshold>` +
+			`<Dimensions><member><Name>InstanceId</Name><Value>i-1</Value></member></Dimensions>` +
+			`</member></MetricAlarms></DescribeAlarmsResult></DescribeAlarmsResponse>`,
+	})
+	defer done()
+	names := []string{}
+	for i := 0; i < 150; i++ {
+		names = append(names, fmt.Sprintf("alarm-%v", i))
+	}
+	alarms, err := svc.GetAlarmsInfo(names)
+	if err != nil {
+		t.Fatalf("error getting alarms: %v", err)
+	}
+	if len(f.requests) != 2 || f.requests[0].Get("AlarmNames.member.100") != "alarm-99" || f.requests[0].Get("AlarmNames.member.101") != "" ||
+		f.requests[1].Get("AlarmNames.member.1") != "alarm-100" || f.requests[1].Get("AlarmNames.member.51") != "" {
+		t.Fatalf("incorrect batches: %v", f.requests)
+	}
+	if len(alarms) != 2 || alarms[0].Name != "alarm-0" || alarms[0].Period != time.Minute || alarms[0].Threshold != 90 || alarms[0].Dimensions["InstanceId"] != "i-1" {
+		t.Fatalf("bad alarms: %+v", alarms)
+	}
+	if _, err := svc.GetAlarmsInfo(nil); err != nil || len(f.requests) != 3 || f.requests[2].Get("AlarmNames.member.1") != "" {
+		t.Fatalf("no names should describe every alarm in one request: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package awsservice
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ConsoleOutput is the serial console output of an instance. AWS only keeps the most recent 64KB.
+type ConsoleOutput struct {
+	InstanceID string
+	Output     string
+	Timestamp  time.Time // When the output was last updated (zero if there is no output yet)
+}
+
+// StatusCheckDetail is one check of an instance or system status, eg "reachability"
+type StatusCheckDetail struct {
+	Name          string
+	Status        string    // passed, failed, insufficient-data or initializing
+	ImpairedSince time.Time // Zero unless failed
+}
+
+// StatusCheck is the overall result of the instance or system status checks
+type StatusCheck struct {
+	Status  string // ok, impaired, insufficient-data, not-applicable or initializing
+	Details []StatusCheckDetail
+}
+
+// ScheduledEvent is a maintenance event AWS has scheduled for an instance, eg "system-reboot"
+type ScheduledEvent struct {
+	ID          string
+	Code        string
+	Description string
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+type InstanceStatusInfo struct {
+	ID               string
+	AvailabilityZone string
+	State            string
+	InstanceStatus   StatusCheck
+	SystemStatus     StatusCheck
+	Events           []ScheduledEvent
+}
+
+func statusCheck(s *ec2.InstanceStatusSummary) StatusCheck {
+	sc := StatusCheck{Details: []StatusCheckDetail{}}
+	if s == nil {
+		return sc
+	}
+	sc.Status = drefStringPtr(s.Status)
+	for _, d := range s.Details {
+		sc.Details = append(sc.Details, StatusCheckDetail{
+			Name:          drefStringPtr(d.Name),
+			Status:        drefStringPtr(d.Status),
+			ImpairedSince: drefTimePtr(d.ImpairedSince),
+		})
+	}
+	return sc
+}
+
+// GetConsoleOutput returns the decoded console output of an instance. Output is empty until the instance has
+// booted far enough to write some (usually a few minutes after launch).
+func (aws *RealAWSService) GetConsoleOutput(id string) (*ConsoleOutput, error) {
+	result := &ConsoleOutput{InstanceID: id}
+	res, err := aws.ec2.GetConsoleOutput(&ec2.GetConsoleOutputInput{InstanceId: &id})
+	if err != nil {
+		return result, wrapError("GetConsoleOutput", err)
+	}
+	out, err := base64.StdEncoding.DecodeString(drefStringPtr(res.Output))
+	if err != nil {
+		return result, fmt.Errorf("error decoding console output: %v", err)
+	}
+	result.Output = string(out)
+	result.Timestamp = drefTimePtr(res.Timestamp)
+	return result, nil
+}
+
+// GetConsoleScreenshot returns a JPEG screenshot of an instance's console
+func (aws *RealAWSService) GetConsoleScreenshot(id string) ([]byte, error) {
+	res, err := aws.ec2.GetConsoleScreenshot(&ec2.GetConsoleScreenshotInput{InstanceId: &id, WakeUp: &True})
+	if err != nil {
+		return []byte{}, wrapError("GetConsoleScreenshot", err)
+	}
+	img, err := base64.StdEncoding.DecodeString(drefStringPtr(res.ImageData))
+	if err != nil {
+		return []byte{}, fmt.Errorf("error decoding console screenshot: %v", err)
+	}
+	return img, nil
+}
+
+// GetInstanceStatus returns the status checks and scheduled events of instances in any state
+func (aws *RealAWSService) GetInstanceStatus(ids []string) ([]InstanceStatusInfo, error) {
+	result := []InstanceStatusInfo{}
+	disi := &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         stringSlicetoStringPointerSlice(ids),
+		IncludeAllInstances: &True,
+	}
+	err := aws.ec2.DescribeInstanceStatusPages(disi, func(page *ec2.DescribeInstanceStatusOutput, last bool) bool {
+		for _, s := range page.InstanceStatuses {
+			isi := InstanceStatusInfo{
+				ID:               drefStringPtr(s.InstanceId),
+				AvailabilityZone: drefStringPtr(s.AvailabilityZone),
+				InstanceStatus:   statusCheck(s.InstanceStatus),
+				SystemStatus:     statusCheck(s.SystemStatus),
+				Events:           []ScheduledEvent{},
+			}
+			if s.InstanceState != nil {
+				isi.State = drefStringPtr(s.InstanceState.Name)
+			}
+			for _, e := range s.Events {
+				isi.Events = append(isi.Events, ScheduledEvent{
+					ID:          drefStringPtr(e.InstanceEventId),
+					Code:        drefStringPtr(e.Code),
+					Description: drefStringPtr(e.Description),
+					NotBefore:   drefTimePtr(e.NotBefore),
+					NotAfter:    drefTimePtr(e.NotAfter),
+				})
+			}
+			result = append(result, isi)
+		}
+		return true
+	})
+	return result, wrapError("GetInstanceStatus", err)
+}
+
+// BootDiagnostics collects what AWS knows about an instance that failed to come up, for failure reports
+type BootDiagnostics struct {
+	InstanceID    string
+	Instance      *InstanceInfo       // nil if it could not be fetched
+	Status        *InstanceStatusInfo // nil if it could not be fetched
+	ConsoleOutput *ConsoleOutput      // nil if it could not be fetched
+	Screenshot    []byte              // JPEG, empty if not requested or it could not be fetched
+	Errors        []string            // Failures fetching individual diagnostics
+}
+
+// bootDiagnosticsConsoleLines is the number of console output lines included by BootDiagnostics.String
+const bootDiagnosticsConsoleLines = 50
+
+// CollectBootDiagnostics fetches the state, status checks, console output and (optionally) a console
+// screenshot of an instance. It is best effort: anything that can't be fetched is listed in Errors.
+func CollectBootDiagnostics(svc AWSService, id string, screenshot bool) *BootDiagnostics {
+	bd := &BootDiagnostics{InstanceID: id, Screenshot: []byte{}, Errors: []string{}}
+	iis, err := svc.GetInstancesInfo([]string{id})
+	switch {
+	case err != nil:
+		bd.Errors = append(bd.Errors, fmt.Sprintf("instance: %v", err))
+	case len(iis) == 0:
+		bd.Errors = append(bd.Errors, "instance: not found")
+	default:
+		bd.Instance = &iis[0]
+	}
+	iss, err := svc.GetInstanceStatus([]string{id})
+	switch {
+	case err != nil:
+		bd.Errors = append(bd.Errors, fmt.Sprintf("status: %v", err))
+	case len(iss) > 0:
+		bd.Status = &iss[0]
+	}
+	co, err := svc.GetConsoleOutput(id)
+	if err != nil {
+		bd.Errors = append(bd.Errors, fmt.Sprintf("console output: %v", err))
+	} else {
+		bd.ConsoleOutput = co
+	}
+	if screenshot {
+		img, err := svc.GetConsoleScreenshot(id)
+		if err != nil {
+			bd.Errors = append(bd.Errors, fmt.Sprintf("console screenshot: %v", err))
+		} else {
+			bd.Screenshot = img
+		}
+	}
+	return bd
+}
+
+func (sc StatusCheck) String() string {
+	failed := []string{}
+	for _, d := range sc.Details {
+		if d.Status == ec2.StatusTypeFailed {
+			desc := d.Name
+			if !d.ImpairedSince.IsZero() {
+				desc += fmt.Sprintf(" since %v", d.ImpairedSince.Format(time.RFC3339))
+			}
+			failed = append(failed, desc)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Sprintf("%v (failed: %v)", sc.Status, strings.Join(failed, ", "))
+	}
+	return sc.Status
+}
+
+// String formats the diagnostics as a plain text report, including the tail of the console output
+func (bd *BootDiagnostics) String() string {
+	lines := []string{fmt.Sprintf("instance %v", bd.InstanceID)}
+	if ii := bd.Instance; ii != nil {
+		desc := fmt.Sprintf("state: %v", ii.State)
+		if ii.StateReasonCode != "" || ii.StateReasonMessage != "" {
+			desc += fmt.Sprintf(" (%v: %v)", ii.StateReasonCode, ii.StateReasonMessage)
+		}
+		lines = append(lines, desc)
+	}
+	if s := bd.Status; s != nil {
+		lines = append(lines, fmt.Sprintf("instance status: %v", s.InstanceStatus), fmt.Sprintf("system status: %v", s.SystemStatus))
+		for _, e := range s.Events {
+			lines = append(lines, fmt.Sprintf("scheduled event: %v: %v (not before %v)", e.Code, e.Description, e.NotBefore.Format(time.RFC3339)))
+		}
+	}
+	if len(bd.Screenshot) > 0 {
+		lines = append(lines, fmt.Sprintf("console screenshot: %v bytes", len(bd.Screenshot)))
+	}
+	for _, e := range bd.Errors {
+		lines = append(lines, fmt.Sprintf("error: %v", e))
+	}
+	if co := bd.ConsoleOutput; co != nil {
+		out := strings.Split(strings.TrimRight(strings.Replace(co.Output, "\r\n", "\n", -1), "\n"), "\n")
+		if len(out) > bootDiagnosticsConsoleLines {
+			out = out[len(out)-bootDiagnosticsConsoleLines:]
+		}
+		if co.Output == "" {
+			lines = append(lines, "console output: (empty)")
+		} else {
+			lines = append(lines, fmt.Sprintf("console output (last %v lines):", len(out)))
+			lines = append(lines, out...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) GetConsoleOutput(id string) (*ConsoleOutput, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetConsoleOutput",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return &ConsoleOutput{InstanceID: id}, nil
+}
+
+func (aws *TestingAWSService) GetConsoleScreenshot(id string) ([]byte, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetConsoleScreenshot",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return []byte{}, nil
+}
+
+func (aws *TestingAWSService) GetInstanceStatus(ids []string) ([]InstanceStatusInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInstanceStatus",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []InstanceStatusInfo{}, nil
+}
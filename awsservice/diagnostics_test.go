@@ -0,0 +1,64 @@
+package awsservice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectBootDiagnostics(t *testing.T) {
+	svc, rec, err := NewReplayAWSService("testdata/ec2_boot_diagnostics.json")
+	if err != nil {
+		t.Fatalf("error loading recording: %v", err)
+	}
+	bd := CollectBootDiagnostics(svc, "i-0a1b2c3d4e5f60718", true)
+	if len(bd.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", bd.Errors)
+	}
+	if len(rec.Unreplayed()) != 0 {
+		t.Fatalf("expected every interaction to be replayed")
+	}
+	if bd.Instance == nil || bd.Instance.State != "running" {
+		t.Fatalf("bad instance: %+v", bd.Instance)
+	}
+	s := bd.Status
+	if s == nil || s.InstanceStatus.Status != "impaired" || s.SystemStatus.Status != "ok" || s.AvailabilityZone != "us-west-2a" {
+		t.Fatalf("bad status: %+v", s)
+	}
+	if d := s.InstanceStatus.Details; len(d) != 1 || d[0].Status != "failed" || !d[0].ImpairedSince.Equal(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("bad status details: %+v", d)
+	}
+	if len(s.Events) != 1 || s.Events[0].Code != "system-reboot" || s.Events[0].NotAfter.IsZero() {
+		t.Fatalf("bad events: %+v", s.Events)
+	}
+	if !strings.HasSuffix(bd.ConsoleOutput.Output, "Kernel panic - not syncing\r\n") || bd.ConsoleOutput.Timestamp.IsZero() {
+		t.Fatalf("bad console output: %+v", bd.ConsoleOutput)
+	}
+	if string(bd.Screenshot[:2]) != "\xff\xd8" {
+		t.Fatalf("screenshot should be a JPEG: %q", bd.Screenshot)
+	}
+	report := bd.String()
+	for _, s := range []string{
+		"instance status: impaired (failed: reachability since 2026-10-19T12:00:00Z)",
+		"scheduled event: system-reboot",
+		"console output (last 3 lines):\nLinux version 5.10\ncloud-init: error\nKernel panic",
+	} {
+		if !strings.Contains(report, s) {
+			t.Fatalf("report should contain %q: %v", s, report)
+		}
+	}
+}
+
+func TestCollectBootDiagnosticsErrors(t *testing.T) {
+	svc, _, err := NewReplayAWSService("testdata/ec2_describe_instances.json")
+	if err != nil {
+		t.Fatalf("error loading recording: %v", err)
+	}
+	bd := CollectBootDiagnostics(svc, "i-0a1b2c3d4e5f60718", false)
+	if bd.Instance == nil || bd.Status != nil || bd.ConsoleOutput != nil || len(bd.Errors) != 2 {
+		t.Fatalf("missing diagnostics should be reported as errors: %+v", bd)
+	}
+	if !strings.Contains(bd.String(), "error: console output:") {
+		t.Fatalf("report should include errors: %v", bd)
+	}
+}
@@ -0,0 +1,168 @@
+package awsservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// readOnlyPrefixes are the operation name prefixes of AWS calls that never change anything
+var readOnlyPrefixes = []string{"Describe", "Get", "List", "Head", "Search", "Lookup"}
+
+// Mutation is a mutating AWS call made (EC2) or skipped (everything else) in dry-run mode
+type Mutation struct {
+	Service   string      // eg "ec2", "elasticloadbalancing"
+	Operation string      // eg "RunInstances"
+	Params    interface{} // SDK input struct
+	Checked   bool        // Sent to AWS with the DryRun flag to verify permissions (EC2 only)
+	Error     string      // Permission check failure, if any
+}
+
+func (m Mutation) String() string {
+	desc := fmt.Sprintf("%v.%v", m.Service, m.Operation)
+	switch {
+	case m.Error != "":
+		desc += fmt.Sprintf(" (check failed: %v)", m.Error)
+	case m.Checked:
+		desc += " (permitted)"
+	}
+	return desc
+}
+
+// MutationPlan collects the mutations recorded in dry-run mode. It is safe for concurrent use.
+type MutationPlan struct {
+	mu        sync.Mutex
+	mutations []Mutation
+}
+
+func (mp *MutationPlan) add(m Mutation) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.mutations = append(mp.mutations, m)
+}
+
+// Mutations returns the recorded mutations in the order they were made
+func (mp *MutationPlan) Mutations() []Mutation {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return append([]Mutation{}, mp.mutations...)
+}
+
+func (mp *MutationPlan) String() string {
+	lines := []string{}
+	for _, m := range mp.Mutations() {
+		lines = append(lines, m.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MarshalJSON encodes the plan as a list of mutations
+func (mp *MutationPlan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mp.Mutations())
+}
+
+// SetDryRun switches dry-run mode on, recording mutating calls into plan, or off if plan is nil.
+// In dry-run mode read-only calls are made as usual. Mutating EC2 calls are sent with the DryRun flag, so
+// AWS verifies permissions without acting (a failed check is returned as an error); all other mutating calls
+// are skipped. Mutating methods return empty results, eg no instance IDs from RunInstances.
+// It must not be called concurrently with other methods.
+func (aws *RealAWSService) SetDryRun(plan *MutationPlan) {
+	aws.dryRun = plan
+}
+
+func isReadOnlyOperation(name string) bool {
+	for _, p := range readOnlyPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// setDryRunFlag sets the DryRun field of an EC2 input struct, reporting whether it had one
+func setDryRunFlag(params interface{}) bool {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return false
+	}
+	f := v.Elem().FieldByName("DryRun")
+	if !f.IsValid() || !f.CanSet() || f.Type() != reflect.TypeOf((*bool)(nil)) {
+		return false
+	}
+	t := true
+	f.Set(reflect.ValueOf(&t))
+	return true
+}
+
+func dryRunChecked(params interface{}) bool {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return false
+	}
+	f := v.Elem().FieldByName("DryRun")
+	return f.IsValid() && f.Type() == reflect.TypeOf((*bool)(nil)) && !f.IsNil() && f.Elem().Bool()
+}
+
+// dryRunValidateHandler runs after parameter validation. It flags mutating EC2 calls for a permission check
+// and makes every other mutating call a no-op returning an empty output.
+func (aws *RealAWSService) dryRunValidateHandler(r *request.Request) {
+	plan := aws.dryRun
+	if plan == nil || r.Error != nil || r.IsPresigned() || isReadOnlyOperation(r.Operation.Name) {
+		return
+	}
+	if r.ClientInfo.ServiceName == ec2.ServiceName && setDryRunFlag(r.Params) {
+		return
+	}
+	plan.add(Mutation{
+		Service:   r.ClientInfo.ServiceName,
+		Operation: r.Operation.Name,
+		Params:    r.Params,
+	})
+	r.Handlers.Sign.Clear()
+	r.Handlers.Send.Clear()
+	r.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(&bytes.Buffer{}),
+		}
+	})
+	r.Handlers.UnmarshalMeta.Clear()
+	r.Handlers.ValidateResponse.Clear()
+	r.Handlers.Unmarshal.Clear()
+}
+
+// dryRunResultHandler runs after an EC2 error response is unmarshaled. A DryRunOperation error means the call
+// would have succeeded, so it is recorded and cleared.
+func (aws *RealAWSService) dryRunResultHandler(r *request.Request) {
+	plan := aws.dryRun
+	if plan == nil || r.ClientInfo.ServiceName != ec2.ServiceName || !dryRunChecked(r.Params) || isReadOnlyOperation(r.Operation.Name) {
+		return
+	}
+	m := Mutation{
+		Service:   r.ClientInfo.ServiceName,
+		Operation: r.Operation.Name,
+		Params:    r.Params,
+		Checked:   true,
+	}
+	if aerr, ok := r.Error.(awserr.Error); ok && aerr.Code() == "DryRunOperation" {
+		r.Error = nil
+	} else if r.Error != nil {
+		m.Error = r.Error.Error()
+	}
+	plan.add(m)
+}
+
+func (aws *RealAWSService) addDryRunHandlers(h *request.Handlers) {
+	h.Validate.PushBackNamed(request.NamedHandler{Name: "awsservice.DryRunValidate", Fn: aws.dryRunValidateHandler})
+	h.UnmarshalError.PushBackNamed(request.NamedHandler{Name: "awsservice.DryRunResult", Fn: aws.dryRunResultHandler})
+}
@@ -0,0 +1,96 @@
+package awsservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// newEndpointAWSService returns a RealAWSService sending every request to endpoint. S3 requests use path-style
+// addressing, so bucket names appear in the path rather than the host.
+func newEndpointAWSService(endpoint string) *RealAWSService {
+	s := session.New(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Region:           aws.String("us-west-2"),
+		Endpoint:         aws.String(endpoint),
+		MaxRetries:       aws.Int(0),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	return newRealAWSService(s)
+}
+
+const ec2DryRunResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>DryRunOperation</Code><Message>Request would have succeeded, but DryRun flag is set.</Message></Error></Errors><RequestID>req-1</RequestID></Response>`
+
+const ec2UnauthorizedResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>UnauthorizedOperation</Code><Message>You are not authorized to perform this operation.</Message></Error></Errors><RequestID>req-2</RequestID></Response>`
+
+func TestDryRun(t *testing.T) {
+	var mu sync.Mutex
+	actions := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		actions = append(actions, r.Form.Get("Action"))
+		mu.Unlock()
+		switch {
+		case r.Form.Get("DryRun") != "true":
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.Form.Get("Action") == "StopInstances":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(ec2UnauthorizedResponse))
+		default:
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(ec2DryRunResponse))
+		}
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	plan := &MutationPlan{}
+	svc.SetDryRun(plan)
+
+	if err := svc.TerminateInstances([]string{"i-1"}); err != nil {
+		t.Fatalf("dry run terminate should have succeeded: %v", err)
+	}
+	if err := svc.StopInstances([]string{"i-1"}); err == nil || !strings.Contains(err.Error(), "UnauthorizedOperation") {
+		t.Fatalf("expected permission failure: %v", err)
+	}
+	dns, err := svc.CreateLoadBalancer(&LoadBalancerDefinition{Name: "lb", Subnets: []string{"subnet-1"}})
+	if err != nil || dns != "" {
+		t.Fatalf("load balancer creation should have been skipped: %v: %v", dns, err)
+	}
+	if err := svc.CreateDNSRecord(&Route53RecordDefinition{ZoneID: "Z1", Name: "a.example.com", Type: "A", Value: "10.0.0.1", TTL: 60}); err != nil {
+		t.Fatalf("DNS record creation should have been skipped: %v", err)
+	}
+
+	if len(actions) != 2 || actions[0] != "TerminateInstances" || actions[1] != "StopInstances" {
+		t.Fatalf("only EC2 calls should have been sent: %v", actions)
+	}
+	ms := plan.Mutations()
+	if len(ms) != 4 {
+		t.Fatalf("expected 4 mutations: %v", plan)
+	}
+	if !ms[0].Checked || ms[0].Error != "" || ms[0].Operation != "TerminateInstances" {
+		t.Fatalf("bad terminate mutation: %+v", ms[0])
+	}
+	if !ms[1].Checked || ms[1].Error == "" {
+		t.Fatalf("bad stop mutation: %+v", ms[1])
+	}
+	if ms[2].Checked || ms[2].Operation != "CreateLoadBalancer" || ms[3].Operation != "ChangeResourceRecordSets" {
+		t.Fatalf("bad skipped mutations: %v", plan)
+	}
+
+	svc.SetDryRun(nil)
+	if err := svc.TerminateInstances([]string{"i-1"}); err == nil {
+		t.Fatalf("request without DryRun flag should have failed against the stub")
+	}
+	if len(plan.Mutations()) != 4 {
+		t.Fatalf("nothing should be recorded with dry run off: %v", plan)
+	}
+}
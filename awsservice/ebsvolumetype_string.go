@@ -4,9 +4,9 @@ package awsservice
 
 import "fmt"
 
-const _EBSVolumeType_name = "Gp2Io1Sc1St1Standard"
+const _EBSVolumeType_name = "Gp2Io1Sc1St1StandardGp3Io2"
 
-var _EBSVolumeType_index = [...]uint8{0, 3, 6, 9, 12, 20}
+var _EBSVolumeType_index = [...]uint8{0, 3, 6, 9, 12, 20, 23, 26}
 
 func (i EBSVolumeType) String() string {
 	if i < 0 || i >= EBSVolumeType(len(_EBSVolumeType_index)-1) {
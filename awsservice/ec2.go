@@ -1,11 +1,10 @@
 package awsservice
 
 import (
-	"bytes"
-	"compress/gzip"
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
@@ -16,6 +15,8 @@ import (
 var True = true
 var False = false
 
+const rootDeviceName = "/dev/xvda"
+
 // EBSVolumeType represents the different types of EBS volumes available
 type EBSVolumeType int
 
@@ -25,31 +26,89 @@ const (
 	Sc1
 	St1
 	Standard
+	Gp3
+	Io2
 )
 
+// ParseEBSVolumeType returns the EBSVolumeType named by s (case-insensitive, eg "gp3" or "Io2")
+func ParseEBSVolumeType(s string) (EBSVolumeType, error) {
+	for i := 0; i < len(_EBSVolumeType_index)-1; i++ {
+		t := EBSVolumeType(i)
+		if strings.EqualFold(t.String(), s) {
+			return t, nil
+		}
+	}
+	return Gp2, fmt.Errorf("unknown EBS volume type: %v", s)
+}
+
+// apiName returns the volume type as expected by the EC2 API
+func (i EBSVolumeType) apiName() string {
+	return strings.ToLower(i.String())
+}
+
+// MarshalText encodes the volume type as its EC2 API name
+func (i EBSVolumeType) MarshalText() ([]byte, error) {
+	if _, err := ParseEBSVolumeType(i.String()); err != nil {
+		return nil, err
+	}
+	return []byte(i.apiName()), nil
+}
+
+// UnmarshalText parses the volume type from its name
+func (i *EBSVolumeType) UnmarshalText(text []byte) error {
+	t, err := ParseEBSVolumeType(string(text))
+	if err != nil {
+		return err
+	}
+	*i = t
+	return nil
+}
+
+// UnmarshalJSON accepts either the volume type name or the legacy integer encoding
+func (i *EBSVolumeType) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		if _, err := ParseEBSVolumeType(EBSVolumeType(n).String()); err != nil {
+			return err
+		}
+		*i = EBSVolumeType(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("EBS volume type must be a string or integer: %v", err)
+	}
+	return i.UnmarshalText([]byte(s))
+}
+
 type BlockDeviceDefinition struct {
 	Name                string
 	DeleteOnTermination bool
 	Encrypted           bool
-	Iops                int64
+	Iops                int64 // Required for Io1/Io2, optional for Gp3
 	SnapshotID          string
 	Size                int64
+	Throughput          int64 // Optional, Gp3 only (MiB/s)
 	Type                EBSVolumeType
 }
 
 type InstancesDefinition struct {
-	AMI           string
-	Subnet        string
-	SecurityGroup string
-	Keypair       string
-	Type          string
-	GetPublicIP   bool
-	PrivateIPs    []string // Optional. Must be valid unused IPs within Subnet with length matching Count
-	UserData      []byte
-	Count         int
-	RootSizeGB    int // Optional (default: 20)
-	EncryptedRoot bool
-	BlockDevices  []BlockDeviceDefinition
+	AMI            string
+	Subnet         string
+	SecurityGroup  string
+	Keypair        string
+	Type           string
+	GetPublicIP    bool
+	PrivateIPs     []string // Optional. Must be valid unused IPs within Subnet with length matching Count
+	UserData       []byte
+	Count          int
+	RootSizeGB     int           // Optional (default: 20)
+	RootVolumeType EBSVolumeType // Optional (default: Gp2)
+	RootIops       int64         // Required for Io1/Io2 root volumes, optional for Gp3
+	RootThroughput int64         // Optional, Gp3 only (MiB/s)
+	EncryptedRoot  bool
+	BlockDevices   []BlockDeviceDefinition
+	Tags           map[string]string // Optional, applied to the instances at launch
 }
 
 type InstanceInfo struct {
@@ -64,7 +123,9 @@ type InstanceInfo struct {
 	State              string
 	StateReasonCode    string
 	StateReasonMessage string
+	LaunchTime         time.Time
 	Tags               map[string]string
+	NetworkInterfaces  []InstanceNetworkInterface
 }
 
 type SubnetInfo struct {
@@ -77,71 +138,83 @@ type SubnetInfo struct {
 	VPC                  string
 }
 
-func encodeUserData(ud []byte) (string, error) {
-	var buf bytes.Buffer
-	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
-	if err != nil {
-		return "", err
+func ebsBlockDevice(bd BlockDeviceDefinition) *ec2.EbsBlockDevice {
+	// allocate new objects so pointers in struct are unique
+	dot := bd.DeleteOnTermination
+	vt := bd.Type.apiName()
+	ebs := &ec2.EbsBlockDevice{
+		DeleteOnTermination: &dot,
+		VolumeType:          &vt,
+	}
+	if bd.Encrypted {
+		enc := bd.Encrypted
+		ebs.Encrypted = &enc // leave nil otherwise
+	}
+	if bd.Size != 0 {
+		size := bd.Size
+		ebs.VolumeSize = &size
 	}
-	if _, err := w.Write(ud); err != nil {
-		return "", err
+	if bd.SnapshotID != "" {
+		sid := bd.SnapshotID
+		ebs.SnapshotId = &sid
 	}
-	if err := w.Close(); err != nil {
-		return "", err
+	if bd.Iops != 0 {
+		iops := bd.Iops
+		ebs.Iops = &iops
+	}
+	if bd.Throughput != 0 {
+		tp := bd.Throughput
+		ebs.Throughput = &tp
+	}
+	return ebs
+}
+
+// rootBlockDevice returns the root volume described by idef
+func (idef *InstancesDefinition) rootBlockDevice() BlockDeviceDefinition {
+	rs := int64(20)
+	if idef.RootSizeGB != 0 {
+		rs = int64(idef.RootSizeGB)
+	}
+	return BlockDeviceDefinition{
+		Name:                rootDeviceName,
+		DeleteOnTermination: true,
+		Encrypted:           idef.EncryptedRoot,
+		Iops:                idef.RootIops,
+		Size:                rs,
+		Throughput:          idef.RootThroughput,
+		Type:                idef.RootVolumeType,
 	}
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
 }
 
 func (aws *RealAWSService) RunInstances(idef *InstancesDefinition) ([]string, error) {
+	if err := idef.Validate(); err != nil {
+		return []string{}, err
+	}
 	count := int64(idef.Count)
-	rs := int64(20)
-	vt := "gp2"
-	rdn := "/dev/xvda"
 	ud, err := encodeUserData(idef.UserData)
 	if err != nil {
 		return []string{}, err
 	}
-	if idef.RootSizeGB != 0 {
-		rs = int64(idef.RootSizeGB)
-	}
-	root := ec2.BlockDeviceMapping{
+	rdn := rootDeviceName
+	bdm := []*ec2.BlockDeviceMapping{&ec2.BlockDeviceMapping{
 		DeviceName: &rdn,
-		Ebs: &ec2.EbsBlockDevice{
-			DeleteOnTermination: &True,
-			VolumeSize:          &rs,
-			VolumeType:          &vt,
-		},
-	}
-	if idef.EncryptedRoot {
-		root.Ebs.Encrypted = &idef.EncryptedRoot // leave nil otherwise
-	}
-	bdm := []*ec2.BlockDeviceMapping{&root}
+		Ebs:        ebsBlockDevice(idef.rootBlockDevice()),
+	}}
 	for _, bd := range idef.BlockDevices {
-		vt := strings.ToLower(bd.Type.String())
-		nbd := &ec2.BlockDeviceMapping{
-			DeviceName: &bd.Name,
-			Ebs: &ec2.EbsBlockDevice{
-				DeleteOnTermination: &bd.DeleteOnTermination,
-				Encrypted:           &bd.Encrypted,
-				Iops:                &bd.Iops,
-				SnapshotId:          &bd.SnapshotID,
-				VolumeSize:          &bd.Size,
-				VolumeType:          &vt,
-			},
-		}
-		bdm = append(bdm, nbd)
-	}
-	if len(idef.PrivateIPs) > 0 && len(idef.PrivateIPs) != idef.Count {
-		return []string{}, fmt.Errorf("invalid private ip count: %v (expected: %v)", len(idef.PrivateIPs), idef.Count)
+		name := bd.Name
+		bdm = append(bdm, &ec2.BlockDeviceMapping{
+			DeviceName: &name,
+			Ebs:        ebsBlockDevice(bd),
+		})
 	}
 	run := func(ri ec2.RunInstancesInput) ([]string, error) {
 		if idef.GetPublicIP {
 			devindx := int64(0)
 			ri.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{&ec2.InstanceNetworkInterfaceSpecification{
 				AssociatePublicIpAddress: &True,
-				Groups:      []*string{&idef.SecurityGroup},
-				DeviceIndex: &devindx,
-				SubnetId:    &idef.Subnet,
+				Groups:                   []*string{&idef.SecurityGroup},
+				DeviceIndex:              &devindx,
+				SubnetId:                 &idef.Subnet,
 			}}
 		} else {
 			ri.SubnetId = &idef.Subnet
@@ -149,7 +222,7 @@ func (aws *RealAWSService) RunInstances(idef *InstancesDefinition) ([]string, er
 		}
 		r, err := aws.ec2.RunInstances(&ri)
 		if err != nil {
-			return []string{}, err
+			return []string{}, wrapError("RunInstances", err)
 		}
 		instances := []string{}
 		for _, inst := range r.Instances {
@@ -166,6 +239,7 @@ func (aws *RealAWSService) RunInstances(idef *InstancesDefinition) ([]string, er
 			InstanceType:        &idef.Type,
 			BlockDeviceMappings: bdm,
 			UserData:            &ud,
+			TagSpecifications:   ec2TagSpecification(ec2.ResourceTypeInstance, idef.Tags),
 		}
 	}
 	if len(idef.PrivateIPs) == 0 {
@@ -174,7 +248,8 @@ func (aws *RealAWSService) RunInstances(idef *InstancesDefinition) ([]string, er
 	allinstances := []string{}
 	for _, pip := range idef.PrivateIPs {
 		ri := getri()
-		ri.PrivateIpAddress = &pip
+		curip := pip
+		ri.PrivateIpAddress = &curip
 		insts, err := run(ri)
 		if err != nil {
 			return []string{}, err
@@ -189,7 +264,7 @@ func (aws *RealAWSService) StartInstances(ids []string) error {
 		InstanceIds: stringSlicetoStringPointerSlice(ids),
 	}
 	_, err := aws.ec2.StartInstances(&si)
-	return err
+	return wrapError("StartInstances", err)
 }
 
 func (aws *RealAWSService) StopInstances(ids []string) error {
@@ -197,7 +272,7 @@ func (aws *RealAWSService) StopInstances(ids []string) error {
 		InstanceIds: stringSlicetoStringPointerSlice(ids),
 	}
 	_, err := aws.ec2.StopInstances(&si)
-	return err
+	return protectedError(wrapError("StopInstances", err), "disableApiStop", ErrStopProtected)
 }
 
 func (aws *RealAWSService) FindInstancesByTag(n string, v string) ([]string, error) {
@@ -212,7 +287,7 @@ func (aws *RealAWSService) FindInstancesByTag(n string, v string) ([]string, err
 	instances := []string{}
 	r, err := aws.ec2.DescribeInstances(&dii)
 	if err != nil {
-		return instances, err
+		return instances, wrapError("FindInstancesByTag", err)
 	}
 	for _, rev := range r.Reservations {
 		for _, inst := range rev.Instances {
@@ -232,7 +307,7 @@ func (aws *RealAWSService) TagInstances(ids []string, n string, v string) error
 		Resources: stringSlicetoStringPointerSlice(ids),
 	}
 	_, err := aws.ec2.CreateTags(&cti)
-	return err
+	return wrapError("TagInstances", err)
 }
 
 func (aws *RealAWSService) DeleteTag(ids []string, n string) error {
@@ -244,7 +319,19 @@ func (aws *RealAWSService) DeleteTag(ids []string, n string) error {
 		Resources: stringSlicetoStringPointerSlice(ids),
 	}
 	_, err := aws.ec2.DeleteTags(&dti)
-	return err
+	return wrapError("DeleteTag", err)
+}
+
+func subnetInfo(s *ec2.Subnet) SubnetInfo {
+	return SubnetInfo{
+		AvailabilityZone:     drefStringPtr(s.AvailabilityZone),
+		AvailableIPAddresses: drefInt64Ptr(s.AvailableIpAddressCount),
+		CIDR:                 drefStringPtr(s.CidrBlock),
+		State:                drefStringPtr(s.State),
+		ID:                   drefStringPtr(s.SubnetId),
+		Tags:                 ec2TagMap(s.Tags),
+		VPC:                  drefStringPtr(s.VpcId),
+	}
 }
 
 func (aws *RealAWSService) GetSubnetInfo(id string) (*SubnetInfo, error) {
@@ -254,19 +341,12 @@ func (aws *RealAWSService) GetSubnetInfo(id string) (*SubnetInfo, error) {
 	}
 	res, err := aws.ec2.DescribeSubnets(&dsi)
 	if err != nil {
-		return result, err
-	}
-	result.AvailabilityZone = drefStringPtr(res.Subnets[0].AvailabilityZone)
-	result.AvailableIPAddresses = drefInt64Ptr(res.Subnets[0].AvailableIpAddressCount)
-	result.CIDR = drefStringPtr(res.Subnets[0].CidrBlock)
-	result.State = drefStringPtr(res.Subnets[0].State)
-	result.ID = drefStringPtr(res.Subnets[0].SubnetId)
-	result.VPC = drefStringPtr(res.Subnets[0].VpcId)
-	tags := map[string]string{}
-	for _, t := range res.Subnets[0].Tags {
-		tags[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
-	}
-	result.Tags = tags
+		return result, wrapError("GetSubnetInfo", err)
+	}
+	if len(res.Subnets) == 0 {
+		return result, notFoundError("GetSubnetInfo", "subnet not found: %v", id)
+	}
+	*result = subnetInfo(res.Subnets[0])
 	return result, nil
 }
 
@@ -277,7 +357,7 @@ func (aws *RealAWSService) GetInstancesInfo(ids []string) ([]InstanceInfo, error
 	}
 	res, err := aws.ec2.DescribeInstances(&dii)
 	if err != nil {
-		return result, err
+		return result, wrapError("GetInstancesInfo", err)
 	}
 	for _, r := range res.Reservations {
 		for _, i := range r.Instances {
@@ -291,6 +371,7 @@ func (aws *RealAWSService) GetInstancesInfo(ids []string) ([]InstanceInfo, error
 				PublicIP:  drefStringPtr(i.PublicIpAddress),
 				State:     drefStringPtr(i.State.Name),
 			}
+			ii.LaunchTime = drefTimePtr(i.LaunchTime)
 			if i.StateReason != nil {
 				ii.StateReasonCode = drefStringPtr(i.StateReason.Code)
 				ii.StateReasonMessage = drefStringPtr(i.StateReason.Message)
@@ -303,8 +384,13 @@ func (aws *RealAWSService) GetInstancesInfo(ids []string) ([]InstanceInfo, error
 			for _, t := range i.Tags {
 				tags[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
 			}
+			nis := []InstanceNetworkInterface{}
+			for _, ni := range i.NetworkInterfaces {
+				nis = append(nis, instanceNetworkInterface(ni))
+			}
 			ii.SecurityGroups = sgl
 			ii.Tags = tags
+			ii.NetworkInterfaces = nis
 			result = append(result, ii)
 		}
 	}
@@ -316,5 +402,104 @@ func (aws *RealAWSService) TerminateInstances(ids []string) error {
 		InstanceIds: stringSlicetoStringPointerSlice(ids),
 	}
 	_, err := aws.ec2.TerminateInstances(&tii)
-	return err
+	return protectedError(wrapError("TerminateInstances", err), "disableApiTermination", ErrTerminationProtected)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) RunInstances(idef *InstancesDefinition) ([]string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "RunInstances",
+		NotableParams: map[string]string{
+			"ami":    idef.AMI,
+			"subnet": idef.Subnet,
+			"type":   idef.Type,
+			"count":  fmt.Sprintf("%v", idef.Count),
+		},
+	})
+	return []string{}, nil
+}
+
+func (aws *TestingAWSService) StartInstances(ids []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "StartInstances",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) StopInstances(ids []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "StopInstances",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) FindInstancesByTag(n string, v string) ([]string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindInstancesByTag",
+		NotableParams: map[string]string{
+			"tag":   n,
+			"value": v,
+		},
+	})
+	return []string{}, nil
+}
+
+func (aws *TestingAWSService) TagInstances(ids []string, n string, v string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "TagInstances",
+		NotableParams: map[string]string{
+			"ids":   fmt.Sprintf("%v", ids),
+			"tag":   n,
+			"value": v,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) DeleteTag(ids []string, n string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteTag",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+			"tag": n,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetSubnetInfo(id string) (*SubnetInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetSubnetInfo",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return &SubnetInfo{ID: id}, nil
+}
+
+func (aws *TestingAWSService) GetInstancesInfo(ids []string) ([]InstanceInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInstancesInfo",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []InstanceInfo{}, nil
+}
+
+func (aws *TestingAWSService) TerminateInstances(ids []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "TerminateInstances",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return nil
 }
@@ -0,0 +1,167 @@
+package awsservice
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type ElasticIPInfo struct {
+	AllocationID       string
+	PublicIP           string
+	AssociationID      string // Empty if unassociated
+	InstanceID         string
+	NetworkInterfaceID string
+	PrivateIP          string
+	Tags               map[string]string
+}
+
+// AllocateElasticIP allocates a new VPC Elastic IP with optional tags
+func (aws *RealAWSService) AllocateElasticIP(tags map[string]string) (*ElasticIPInfo, error) {
+	result := &ElasticIPInfo{}
+	domain := ec2.DomainTypeVpc
+	o, err := aws.ec2.AllocateAddress(&ec2.AllocateAddressInput{
+		Domain:            &domain,
+		TagSpecifications: ec2TagSpecification(ec2.ResourceTypeElasticIp, tags),
+	})
+	if err != nil {
+		return result, wrapError("AllocateElasticIP", err)
+	}
+	result.AllocationID = drefStringPtr(o.AllocationId)
+	result.PublicIP = drefStringPtr(o.PublicIp)
+	result.Tags = tags
+	return result, nil
+}
+
+// AssociateElasticIP associates an Elastic IP with an instance's primary network interface, returning the association ID
+func (aws *RealAWSService) AssociateElasticIP(allocID string, instanceID string) (string, error) {
+	o, err := aws.ec2.AssociateAddress(&ec2.AssociateAddressInput{
+		AllocationId: &allocID,
+		InstanceId:   &instanceID,
+	})
+	if err != nil {
+		return "", wrapError("AssociateElasticIP", err)
+	}
+	return drefStringPtr(o.AssociationId), nil
+}
+
+// AssociateElasticIPWithInterface associates an Elastic IP with a specific private IP of a network interface
+// (empty privateIP means the primary), returning the association ID
+func (aws *RealAWSService) AssociateElasticIPWithInterface(allocID string, eni string, privateIP string) (string, error) {
+	aai := &ec2.AssociateAddressInput{
+		AllocationId:       &allocID,
+		NetworkInterfaceId: &eni,
+	}
+	if privateIP != "" {
+		aai.PrivateIpAddress = &privateIP
+	}
+	o, err := aws.ec2.AssociateAddress(aai)
+	if err != nil {
+		return "", wrapError("AssociateElasticIPWithInterface", err)
+	}
+	return drefStringPtr(o.AssociationId), nil
+}
+
+func (aws *RealAWSService) DisassociateElasticIP(assocID string) error {
+	_, err := aws.ec2.DisassociateAddress(&ec2.DisassociateAddressInput{
+		AssociationId: &assocID,
+	})
+	return wrapError("DisassociateElasticIP", err)
+}
+
+func (aws *RealAWSService) ReleaseElasticIP(allocID string) error {
+	_, err := aws.ec2.ReleaseAddress(&ec2.ReleaseAddressInput{
+		AllocationId: &allocID,
+	})
+	return wrapError("ReleaseElasticIP", err)
+}
+
+// GetElasticIPsInfo describes the given allocations (all Elastic IPs in the region if ids is empty)
+func (aws *RealAWSService) GetElasticIPsInfo(ids []string) ([]ElasticIPInfo, error) {
+	result := []ElasticIPInfo{}
+	dai := &ec2.DescribeAddressesInput{}
+	if len(ids) > 0 {
+		dai.AllocationIds = stringSlicetoStringPointerSlice(ids)
+	}
+	res, err := aws.ec2.DescribeAddresses(dai)
+	if err != nil {
+		return result, wrapError("GetElasticIPsInfo", err)
+	}
+	for _, a := range res.Addresses {
+		result = append(result, ElasticIPInfo{
+			AllocationID:       drefStringPtr(a.AllocationId),
+			PublicIP:           drefStringPtr(a.PublicIp),
+			AssociationID:      drefStringPtr(a.AssociationId),
+			InstanceID:         drefStringPtr(a.InstanceId),
+			NetworkInterfaceID: drefStringPtr(a.NetworkInterfaceId),
+			PrivateIP:          drefStringPtr(a.PrivateIpAddress),
+			Tags:               ec2TagMap(a.Tags),
+		})
+	}
+	return result, nil
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) AllocateElasticIP(tags map[string]string) (*ElasticIPInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AllocateElasticIP",
+		NotableParams: map[string]string{
+			"tags": fmt.Sprintf("%v", tags),
+		},
+	})
+	return &ElasticIPInfo{Tags: tags}, nil
+}
+
+func (aws *TestingAWSService) AssociateElasticIP(allocID string, instanceID string) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AssociateElasticIP",
+		NotableParams: map[string]string{
+			"allocation_id": allocID,
+			"instance_id":   instanceID,
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) AssociateElasticIPWithInterface(allocID string, eni string, privateIP string) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AssociateElasticIPWithInterface",
+		NotableParams: map[string]string{
+			"allocation_id":        allocID,
+			"network_interface_id": eni,
+			"private_ip":           privateIP,
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) DisassociateElasticIP(assocID string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DisassociateElasticIP",
+		NotableParams: map[string]string{
+			"association_id": assocID,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) ReleaseElasticIP(allocID string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ReleaseElasticIP",
+		NotableParams: map[string]string{
+			"allocation_id": allocID,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetElasticIPsInfo(ids []string) ([]ElasticIPInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetElasticIPsInfo",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []ElasticIPInfo{}, nil
+}
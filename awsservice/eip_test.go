@@ -0,0 +1,81 @@
+package awsservice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocateElasticIP(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"AllocateAddress": `<AllocateAddressResponse><publicIp>54.0.0.1</publicIp><domain>vpc</domain><allocationId>eipalloc-1</allocationId></AllocateAddressResponse>`,
+	})
+	defer done()
+	eip, err := svc.AllocateElasticIP(map[string]string{"Name": "web"})
+	if err != nil {
+		t.Fatalf("error allocating: %v", err)
+	}
+	if eip.AllocationID != "eipalloc-1" || eip.PublicIP != "54.0.0.1" || eip.Tags["Name"] != "web" {
+		t.Fatalf("bad Elastic IP: %+v", eip)
+	}
+	if form := f.requests[0]; form.Get("Domain") != "vpc" || form.Get("TagSpecification.1.ResourceType") != "elastic-ip" {
+		t.Fatalf("bad request: %v", form)
+	}
+}
+
+func TestAssociateElasticIPWithInterface(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"AssociateAddress": `<AssociateAddressResponse><associationId>eipassoc-1</associationId></AssociateAddressResponse>`,
+	})
+	defer done()
+	id, err := svc.AssociateElasticIPWithInterface("eipalloc-1", "eni-1", "")
+	if err != nil {
+		t.Fatalf("error associating: %v", err)
+	}
+	if id != "eipassoc-1" {
+		t.Fatalf("bad association ID: %v", id)
+	}
+	if form := f.requests[0]; form.Get("NetworkInterfaceId") != "eni-1" || form.Get("InstanceId") != "" || form.Get("PrivateIpAddress") != "" {
+		t.Fatalf("bad request: %v", form)
+	}
+}
+
+func TestGetElasticIPsInfo(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"DescribeAddresses": `<DescribeAddressesResponse><addressesSet>` +
+			`<item><allocationId>eipalloc-1</allocationId><publicIp>54.0.0.1</publicIp><associationId>eipassoc-1</associationId>` +
+			`<instanceId>i-1</instanceId><networkInterfaceId>eni-1</networkInterfaceId><privateIpAddress>10.0.0.5</privateIpAddress>` +
+			`<tagSet><item><key>Name</key><value>web</value></item></tagSet></item>` +
+			`<item><allocationId>eipalloc-2</allocationId><publicIp>54.0.0.2</publicIp></item>` +
+			`</addressesSet></DescribeAddressesResponse>`,
+	})
+	defer done()
+	eips, err := svc.GetElasticIPsInfo(nil)
+	if err != nil {
+		t.Fatalf("error getting Elastic IPs: %v", err)
+	}
+	if len(eips) != 2 {
+		t.Fatalf("bad Elastic IPs: %+v", eips)
+	}
+	expected := ElasticIPInfo{
+		AllocationID:       "eipalloc-1",
+		PublicIP:           "54.0.0.1",
+		AssociationID:      "eipassoc-1",
+		InstanceID:         "i-1",
+		NetworkInterfaceID: "eni-1",
+		PrivateIP:          "10.0.0.5",
+		Tags:               map[string]string{"Name": "web"},
+	}
+	if !reflect.DeepEqual(eips[0], expected) {
+		t.Fatalf("bad Elastic IP: %+v", eips[0])
+	}
+	if eips[1].AssociationID != "" {
+		t.Fatalf("unassociated Elastic IP should have no association: %+v", eips[1])
+	}
+	if _, ok := f.requests[0]["AllocationId.1"]; ok {
+		t.Fatalf("no IDs should describe every Elastic IP: %v", f.requests[0])
+	}
+	svc.GetElasticIPsInfo([]string{"eipalloc-2"})
+	if f.requests[1].Get("AllocationId.1") != "eipalloc-2" {
+		t.Fatalf("bad request: %v", f.requests[1])
+	}
+}
@@ -2,6 +2,7 @@ package awsservice
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/elb"
 )
@@ -20,6 +21,7 @@ type LoadBalancerDefinition struct {
 	SecurityGroups []string
 	Scheme         string
 	Subnets        []string
+	Tags           map[string]string // Optional, applied to the load balancer at creation
 }
 
 type LBHealthCheck struct {
@@ -51,6 +53,8 @@ type LoadBalancerInfo struct {
 	AvailabilityZones []string
 	DNSName           string
 	Instances         []string
+	HealthCheck       *LBHealthCheck
+	CreatedTime       time.Time
 }
 
 func (aws *RealAWSService) CreateLoadBalancer(lbd *LoadBalancerDefinition) (string, error) {
@@ -69,16 +73,26 @@ func (aws *RealAWSService) CreateLoadBalancer(lbd *LoadBalancerDefinition) (stri
 			SSLCertificateId: &cid,
 		})
 	}
-	o, err := aws.elbc.CreateLoadBalancer(&elb.CreateLoadBalancerInput{
+	tags := []*elb.Tag{}
+	for k, v := range lbd.Tags {
+		ck := k
+		cv := v
+		tags = append(tags, &elb.Tag{Key: &ck, Value: &cv})
+	}
+	clbi := &elb.CreateLoadBalancerInput{
 		Listeners:        listeners,
 		LoadBalancerName: &lbd.Name,
 		SecurityGroups:   stringSlicetoStringPointerSlice(lbd.SecurityGroups),
 		Subnets:          stringSlicetoStringPointerSlice(lbd.Subnets),
-	})
+	}
+	if len(tags) > 0 {
+		clbi.Tags = tags
+	}
+	o, err := aws.elbc.CreateLoadBalancer(clbi)
 	if err != nil {
-		return "", err
+		return "", wrapError("CreateLoadBalancer", err)
 	}
-	return *o.DNSName, nil
+	return drefStringPtr(o.DNSName), nil
 }
 
 func (aws *RealAWSService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, error) {
@@ -88,20 +102,34 @@ func (aws *RealAWSService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, err
 	result := &LoadBalancerInfo{}
 	res, err := aws.elbc.DescribeLoadBalancers(dlbi)
 	if err != nil {
-		return result, err
-	}
-	result.AvailabilityZones = stringPointerSlicetoStringSlice(res.LoadBalancerDescriptions[0].AvailabilityZones)
-	result.SecurityGroups = stringPointerSlicetoStringSlice(res.LoadBalancerDescriptions[0].SecurityGroups)
-	result.Subnets = stringPointerSlicetoStringSlice(res.LoadBalancerDescriptions[0].Subnets)
-	result.DNSName = drefStringPtr(res.LoadBalancerDescriptions[0].DNSName)
-	result.Name = drefStringPtr(res.LoadBalancerDescriptions[0].LoadBalancerName)
-	result.Scheme = drefStringPtr(res.LoadBalancerDescriptions[0].Scheme)
-	result.VPCID = drefStringPtr(res.LoadBalancerDescriptions[0].VPCId)
+		return result, wrapError("GetLoadBalancerInfo", err)
+	}
+	if len(res.LoadBalancerDescriptions) == 0 {
+		return result, notFoundError("GetLoadBalancerInfo", "load balancer not found: %v", n)
+	}
+	lb := res.LoadBalancerDescriptions[0]
+	result.AvailabilityZones = stringPointerSlicetoStringSlice(lb.AvailabilityZones)
+	result.SecurityGroups = stringPointerSlicetoStringSlice(lb.SecurityGroups)
+	result.Subnets = stringPointerSlicetoStringSlice(lb.Subnets)
+	result.DNSName = drefStringPtr(lb.DNSName)
+	result.Name = drefStringPtr(lb.LoadBalancerName)
+	result.Scheme = drefStringPtr(lb.Scheme)
+	result.VPCID = drefStringPtr(lb.VPCId)
+	result.CreatedTime = drefTimePtr(lb.CreatedTime)
 	il := []string{}
-	for _, inst := range res.LoadBalancerDescriptions[0].Instances {
+	for _, inst := range lb.Instances {
 		il = append(il, drefStringPtr(inst.InstanceId))
 	}
 	result.Instances = il
+	if lb.HealthCheck != nil {
+		result.HealthCheck = &LBHealthCheck{
+			Target:             drefStringPtr(lb.HealthCheck.Target),
+			Interval:           drefInt64Ptr(lb.HealthCheck.Interval),
+			Timeout:            drefInt64Ptr(lb.HealthCheck.Timeout),
+			HealthyThreshold:   drefInt64Ptr(lb.HealthCheck.HealthyThreshold),
+			UnhealthyThreshold: drefInt64Ptr(lb.HealthCheck.UnhealthyThreshold),
+		}
+	}
 	return result, nil
 }
 
@@ -114,7 +142,7 @@ func (aws *RealAWSService) GetInstanceHealth(n string) (*LBInstanceHealthInfo, e
 	}
 	r, err := aws.elbc.DescribeInstanceHealth(dih)
 	if err != nil {
-		return result, err
+		return result, wrapError("GetInstanceHealth", err)
 	}
 	instances := []LBInstanceHealth{}
 	for _, is := range r.InstanceStates {
@@ -142,14 +170,14 @@ func (aws *RealAWSService) SetHealthCheck(n string, hc *LBHealthCheck) error {
 		},
 	}
 	_, err := aws.elbc.ConfigureHealthCheck(chk)
-	return err
+	return wrapError("SetHealthCheck", err)
 }
 
 func (aws *RealAWSService) DeleteLoadBalancer(n string) error {
 	_, err := aws.elbc.DeleteLoadBalancer(&elb.DeleteLoadBalancerInput{
 		LoadBalancerName: &n,
 	})
-	return err
+	return wrapError("DeleteLoadBalancer", err)
 }
 
 func (aws *RealAWSService) RegisterInstances(n string, ids []string) error {
@@ -157,7 +185,7 @@ func (aws *RealAWSService) RegisterInstances(n string, ids []string) error {
 		Instances:        instanceIDSlice(ids),
 		LoadBalancerName: &n,
 	})
-	return err
+	return wrapError("RegisterInstances", err)
 }
 
 func (aws *RealAWSService) DeregisterInstances(n string, ids []string) error {
@@ -165,7 +193,61 @@ func (aws *RealAWSService) DeregisterInstances(n string, ids []string) error {
 		Instances:        instanceIDSlice(ids),
 		LoadBalancerName: &n,
 	})
-	return err
+	return wrapError("DeregisterInstances", err)
+}
+
+// elbDescribeTagsMax is the most load balancers DescribeTags accepts at once
+const elbDescribeTagsMax = 20
+
+func (aws *RealAWSService) GetLoadBalancerTags(n string) (map[string]string, error) {
+	tags := map[string]string{}
+	res, err := aws.elbc.DescribeTags(&elb.DescribeTagsInput{
+		LoadBalancerNames: []*string{&n},
+	})
+	if err != nil {
+		return tags, wrapError("GetLoadBalancerTags", err)
+	}
+	if len(res.TagDescriptions) == 0 {
+		return tags, notFoundError("GetLoadBalancerTags", "load balancer not found: %v", n)
+	}
+	for _, t := range res.TagDescriptions[0].Tags {
+		tags[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
+	}
+	return tags, nil
+}
+
+// FindLoadBalancersByTag returns the names of the load balancers with tag n set to v, or to any value if v is "*"
+func (aws *RealAWSService) FindLoadBalancersByTag(n string, v string) ([]string, error) {
+	result := []string{}
+	names := []*string{}
+	err := aws.elbc.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(page *elb.DescribeLoadBalancersOutput, last bool) bool {
+		for _, lb := range page.LoadBalancerDescriptions {
+			names = append(names, lb.LoadBalancerName)
+		}
+		return true
+	})
+	if err != nil {
+		return result, wrapError("FindLoadBalancersByTag", err)
+	}
+	for i := 0; i < len(names); i += elbDescribeTagsMax {
+		end := i + elbDescribeTagsMax
+		if end > len(names) {
+			end = len(names)
+		}
+		res, err := aws.elbc.DescribeTags(&elb.DescribeTagsInput{LoadBalancerNames: names[i:end]})
+		if err != nil {
+			return []string{}, wrapError("FindLoadBalancersByTag", err)
+		}
+		for _, td := range res.TagDescriptions {
+			for _, t := range td.Tags {
+				if drefStringPtr(t.Key) == n && (v == "*" || drefStringPtr(t.Value) == v) {
+					result = append(result, drefStringPtr(td.LoadBalancerName))
+					break
+				}
+			}
+		}
+	}
+	return result, nil
 }
 
 // Testing mocks
@@ -179,6 +261,7 @@ func (aws *TestingAWSService) CreateLoadBalancer(lbd *LoadBalancerDefinition) (s
 			"scheme":          lbd.Scheme,
 			"subnets":         fmt.Sprintf("%v", lbd.Subnets),
 			"listeners":       fmt.Sprintf("%v", lbd.Listeners),
+			"tags":            fmt.Sprintf("%v", lbd.Tags),
 		},
 	})
 	return "", nil
@@ -213,3 +296,55 @@ func (aws *TestingAWSService) DeregisterInstances(n string, ids []string) error
 	})
 	return nil
 }
+
+func (aws *TestingAWSService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetLoadBalancerInfo",
+		NotableParams: map[string]string{
+			"name": n,
+		},
+	})
+	return &LoadBalancerInfo{Name: n}, nil
+}
+
+func (aws *TestingAWSService) GetInstanceHealth(n string) (*LBInstanceHealthInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInstanceHealth",
+		NotableParams: map[string]string{
+			"name": n,
+		},
+	})
+	return &LBInstanceHealthInfo{LBName: n}, nil
+}
+
+func (aws *TestingAWSService) SetHealthCheck(n string, hc *LBHealthCheck) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SetHealthCheck",
+		NotableParams: map[string]string{
+			"name":   n,
+			"target": hc.Target,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetLoadBalancerTags(n string) (map[string]string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetLoadBalancerTags",
+		NotableParams: map[string]string{
+			"name": n,
+		},
+	})
+	return map[string]string{}, nil
+}
+
+func (aws *TestingAWSService) FindLoadBalancersByTag(n string, v string) ([]string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindLoadBalancersByTag",
+		NotableParams: map[string]string{
+			"tag":   n,
+			"value": v,
+		},
+	})
+	return []string{}, nil
+}
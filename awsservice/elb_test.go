@@ -0,0 +1,52 @@
+package awsservice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplayLoadBalancerInfo(t *testing.T) {
+	svc, rec, err := NewReplayAWSService("testdata/elb_describe_load_balancer.json")
+	if err != nil {
+		t.Fatalf("error loading recording: %v", err)
+	}
+	lbi, err := svc.GetLoadBalancerInfo("web-lb")
+	if err != nil {
+		t.Fatalf("error getting load balancer: %v", err)
+	}
+	expected := &LoadBalancerInfo{
+		Name:              "web-lb",
+		Scheme:            "internet-facing",
+		SecurityGroups:    []string{"sg-11111111"},
+		Subnets:           []string{"subnet-1a2b3c4d", "subnet-5e6f7a8b"},
+		VPCID:             "vpc-0a1b2c3d",
+		AvailabilityZones: []string{"us-west-2a", "us-west-2b"},
+		DNSName:           "web-lb-1234567890.us-west-2.elb.amazonaws.com",
+		Instances:         []string{"i-0a1b2c3d4e5f60718", "i-0f1e2d3c4b5a69788"},
+		HealthCheck:       &LBHealthCheck{Target: "HTTP:80/health", Interval: 10, Timeout: 5, HealthyThreshold: 2, UnhealthyThreshold: 3},
+		CreatedTime:       time.Date(2026, 9, 1, 8, 30, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(lbi, expected) {
+		t.Fatalf("bad load balancer: %+v", lbi)
+	}
+	lbhi, err := svc.GetInstanceHealth("web-lb")
+	if err != nil {
+		t.Fatalf("error getting instance health: %v", err)
+	}
+	if lbhi.LBName != "web-lb" || len(lbhi.Instances) != 2 {
+		t.Fatalf("bad instance health: %+v", lbhi)
+	}
+	if ih := lbhi.Instances[0]; ih.ID != "i-0a1b2c3d4e5f60718" || ih.State != "InService" {
+		t.Fatalf("bad healthy instance: %+v", ih)
+	}
+	if ih := lbhi.Instances[1]; ih.State != "OutOfService" || ih.ReasonCode != "Instance" || ih.Description == "" {
+		t.Fatalf("bad unhealthy instance: %+v", ih)
+	}
+	if _, err := svc.GetLoadBalancerInfo("missing-lb"); !IsNotFound(err) {
+		t.Fatalf("expected not found error: %v", err)
+	}
+	if n := len(rec.Unreplayed()); n != 0 {
+		t.Fatalf("expected every interaction to be replayed: %v", n)
+	}
+}
@@ -0,0 +1,290 @@
+package awsservice
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type NetworkInterfaceDefinition struct {
+	Subnet                  string
+	SecurityGroups          []string
+	Description             string
+	PrivateIP               string   // Optional primary private IP (default: assigned by AWS)
+	SecondaryPrivateIPs     []string // Optional. Mutually exclusive with SecondaryPrivateIPCount
+	SecondaryPrivateIPCount int64    // Optional number of secondary IPs for AWS to assign
+	Tags                    map[string]string
+}
+
+type NetworkInterfaceInfo struct {
+	ID                  string
+	Subnet              string
+	VPC                 string
+	Description         string
+	Status              string
+	PrivateIP           string
+	SecondaryPrivateIPs []string
+	PublicIP            string
+	SecurityGroups      []string
+	AttachmentID        string
+	InstanceID          string
+	DeviceIndex         int64
+	Tags                map[string]string
+}
+
+// InstanceNetworkInterface is a network interface attached to an instance (see InstanceInfo)
+type InstanceNetworkInterface struct {
+	ID                  string
+	AttachmentID        string
+	DeviceIndex         int64
+	Subnet              string
+	PrivateIP           string
+	SecondaryPrivateIPs []string
+	PublicIP            string
+}
+
+func instanceNetworkInterface(ni *ec2.InstanceNetworkInterface) InstanceNetworkInterface {
+	ini := InstanceNetworkInterface{
+		ID:                  drefStringPtr(ni.NetworkInterfaceId),
+		Subnet:              drefStringPtr(ni.SubnetId),
+		PrivateIP:           drefStringPtr(ni.PrivateIpAddress),
+		SecondaryPrivateIPs: []string{},
+	}
+	if ni.Association != nil {
+		ini.PublicIP = drefStringPtr(ni.Association.PublicIp)
+	}
+	if ni.Attachment != nil {
+		ini.AttachmentID = drefStringPtr(ni.Attachment.AttachmentId)
+		ini.DeviceIndex = drefInt64Ptr(ni.Attachment.DeviceIndex)
+	}
+	for _, pip := range ni.PrivateIpAddresses {
+		if pip.Primary == nil || !*pip.Primary {
+			ini.SecondaryPrivateIPs = append(ini.SecondaryPrivateIPs, drefStringPtr(pip.PrivateIpAddress))
+		}
+	}
+	return ini
+}
+
+func (aws *RealAWSService) CreateNetworkInterface(nid *NetworkInterfaceDefinition) (string, error) {
+	if len(nid.SecondaryPrivateIPs) > 0 && nid.SecondaryPrivateIPCount > 0 {
+		return "", invalidDefinition("secondary private IPs and secondary private IP count are mutually exclusive")
+	}
+	cnii := &ec2.CreateNetworkInterfaceInput{
+		SubnetId:          &nid.Subnet,
+		Groups:            stringSlicetoStringPointerSlice(nid.SecurityGroups),
+		TagSpecifications: ec2TagSpecification(ec2.ResourceTypeNetworkInterface, nid.Tags),
+	}
+	if nid.Description != "" {
+		cnii.Description = &nid.Description
+	}
+	if nid.PrivateIP != "" {
+		cnii.PrivateIpAddress = &nid.PrivateIP
+	}
+	if nid.SecondaryPrivateIPCount > 0 {
+		cnii.SecondaryPrivateIpAddressCount = &nid.SecondaryPrivateIPCount
+	}
+	if len(nid.SecondaryPrivateIPs) > 0 {
+		if nid.PrivateIP != "" {
+			cnii.PrivateIpAddress = nil // must be given as the primary entry of PrivateIpAddresses instead
+			cnii.PrivateIpAddresses = []*ec2.PrivateIpAddressSpecification{{PrivateIpAddress: &nid.PrivateIP, Primary: &True}}
+		}
+		for _, ip := range nid.SecondaryPrivateIPs {
+			cip := ip
+			cnii.PrivateIpAddresses = append(cnii.PrivateIpAddresses, &ec2.PrivateIpAddressSpecification{
+				PrivateIpAddress: &cip,
+				Primary:          &False,
+			})
+		}
+	}
+	o, err := aws.ec2.CreateNetworkInterface(cnii)
+	if err != nil {
+		return "", wrapError("CreateNetworkInterface", err)
+	}
+	if o.NetworkInterface == nil {
+		return "", nil
+	}
+	return drefStringPtr(o.NetworkInterface.NetworkInterfaceId), nil
+}
+
+func (aws *RealAWSService) DeleteNetworkInterface(id string) error {
+	_, err := aws.ec2.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: &id,
+	})
+	return wrapError("DeleteNetworkInterface", err)
+}
+
+// AttachNetworkInterface attaches an interface to an instance at deviceIndex, returning the attachment ID
+func (aws *RealAWSService) AttachNetworkInterface(id string, instanceID string, deviceIndex int64) (string, error) {
+	o, err := aws.ec2.AttachNetworkInterface(&ec2.AttachNetworkInterfaceInput{
+		NetworkInterfaceId: &id,
+		InstanceId:         &instanceID,
+		DeviceIndex:        &deviceIndex,
+	})
+	if err != nil {
+		return "", wrapError("AttachNetworkInterface", err)
+	}
+	return drefStringPtr(o.AttachmentId), nil
+}
+
+func (aws *RealAWSService) DetachNetworkInterface(attachmentID string, force bool) error {
+	_, err := aws.ec2.DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
+		AttachmentId: &attachmentID,
+		Force:        &force,
+	})
+	return wrapError("DetachNetworkInterface", err)
+}
+
+// AssignPrivateIPs adds secondary private IPs to an interface: either the given ips, or count addresses chosen
+// by AWS if ips is empty. It returns the addresses assigned.
+func (aws *RealAWSService) AssignPrivateIPs(id string, ips []string, count int64) ([]string, error) {
+	if len(ips) == 0 && count <= 0 {
+		return []string{}, invalidDefinition("private IPs or a positive count is required")
+	}
+	apii := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId: &id,
+	}
+	if len(ips) > 0 {
+		apii.PrivateIpAddresses = stringSlicetoStringPointerSlice(ips)
+	} else {
+		apii.SecondaryPrivateIpAddressCount = &count
+	}
+	o, err := aws.ec2.AssignPrivateIpAddresses(apii)
+	if err != nil {
+		return []string{}, wrapError("AssignPrivateIPs", err)
+	}
+	assigned := []string{}
+	for _, a := range o.AssignedPrivateIpAddresses {
+		assigned = append(assigned, drefStringPtr(a.PrivateIpAddress))
+	}
+	return assigned, nil
+}
+
+func (aws *RealAWSService) UnassignPrivateIPs(id string, ips []string) error {
+	_, err := aws.ec2.UnassignPrivateIpAddresses(&ec2.UnassignPrivateIpAddressesInput{
+		NetworkInterfaceId: &id,
+		PrivateIpAddresses: stringSlicetoStringPointerSlice(ips),
+	})
+	return wrapError("UnassignPrivateIPs", err)
+}
+
+func (aws *RealAWSService) GetNetworkInterfacesInfo(ids []string) ([]NetworkInterfaceInfo, error) {
+	result := []NetworkInterfaceInfo{}
+	dnii := &ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: stringSlicetoStringPointerSlice(ids),
+	}
+	err := aws.ec2.DescribeNetworkInterfacesPages(dnii, func(page *ec2.DescribeNetworkInterfacesOutput, last bool) bool {
+		for _, ni := range page.NetworkInterfaces {
+			nii := NetworkInterfaceInfo{
+				ID:                  drefStringPtr(ni.NetworkInterfaceId),
+				Subnet:              drefStringPtr(ni.SubnetId),
+				VPC:                 drefStringPtr(ni.VpcId),
+				Description:         drefStringPtr(ni.Description),
+				Status:              drefStringPtr(ni.Status),
+				PrivateIP:           drefStringPtr(ni.PrivateIpAddress),
+				SecondaryPrivateIPs: []string{},
+				SecurityGroups:      []string{},
+				Tags:                ec2TagMap(ni.TagSet),
+			}
+			if ni.Association != nil {
+				nii.PublicIP = drefStringPtr(ni.Association.PublicIp)
+			}
+			if ni.Attachment != nil {
+				nii.AttachmentID = drefStringPtr(ni.Attachment.AttachmentId)
+				nii.InstanceID = drefStringPtr(ni.Attachment.InstanceId)
+				nii.DeviceIndex = drefInt64Ptr(ni.Attachment.DeviceIndex)
+			}
+			for _, pip := range ni.PrivateIpAddresses {
+				if pip.Primary == nil || !*pip.Primary {
+					nii.SecondaryPrivateIPs = append(nii.SecondaryPrivateIPs, drefStringPtr(pip.PrivateIpAddress))
+				}
+			}
+			for _, g := range ni.Groups {
+				nii.SecurityGroups = append(nii.SecurityGroups, drefStringPtr(g.GroupId))
+			}
+			result = append(result, nii)
+		}
+		return true
+	})
+	return result, wrapError("GetNetworkInterfacesInfo", err)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) CreateNetworkInterface(nid *NetworkInterfaceDefinition) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateNetworkInterface",
+		NotableParams: map[string]string{
+			"subnet":                nid.Subnet,
+			"security_groups":       fmt.Sprintf("%v", nid.SecurityGroups),
+			"private_ip":            nid.PrivateIP,
+			"secondary_private_ips": fmt.Sprintf("%v", nid.SecondaryPrivateIPs),
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) DeleteNetworkInterface(id string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteNetworkInterface",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) AttachNetworkInterface(id string, instanceID string, deviceIndex int64) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AttachNetworkInterface",
+		NotableParams: map[string]string{
+			"id":           id,
+			"instance_id":  instanceID,
+			"device_index": fmt.Sprintf("%v", deviceIndex),
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) DetachNetworkInterface(attachmentID string, force bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DetachNetworkInterface",
+		NotableParams: map[string]string{
+			"attachment_id": attachmentID,
+			"force":         fmt.Sprintf("%v", force),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) AssignPrivateIPs(id string, ips []string, count int64) ([]string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AssignPrivateIPs",
+		NotableParams: map[string]string{
+			"id":    id,
+			"ips":   fmt.Sprintf("%v", ips),
+			"count": fmt.Sprintf("%v", count),
+		},
+	})
+	return ips, nil
+}
+
+func (aws *TestingAWSService) UnassignPrivateIPs(id string, ips []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "UnassignPrivateIPs",
+		NotableParams: map[string]string{
+			"id":  id,
+			"ips": fmt.Sprintf("%v", ips),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetNetworkInterfacesInfo(ids []string) ([]NetworkInterfaceInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetNetworkInterfacesInfo",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []NetworkInterfaceInfo{}, nil
+}
@@ -0,0 +1,151 @@
+package awsservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ec2Fake answers each query API (EC2, Auto Scaling, ...) action with a canned response body, recording the request parameters
+type ec2Fake struct {
+	mu        sync.Mutex
+	responses map[string]string // Action -> response body
+	requests  []url.Values
+}
+
+func (f *ec2Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Form)
+	f.mu.Unlock()
+	body, ok := f.responses[r.Form.Get("Action")]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<Response><Errors><Error><Code>InvalidAction</Code><Message>unexpected action</Message></Error></Errors><RequestID>req-1</RequestID></Response>`))
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func newEC2FakeService(responses map[string]string) (*RealAWSService, *ec2Fake, func()) {
+	f := &ec2Fake{responses: responses}
+	ts := httptest.NewServer(f)
+	return newEndpointAWSService(ts.URL), f, ts.Close
+}
+
+func TestInstanceNetworkInterface(t *testing.T) {
+	ini := instanceNetworkInterface(&ec2.InstanceNetworkInterface{
+		NetworkInterfaceId: aws.String("eni-1"),
+		SubnetId:           aws.String("subnet-1"),
+		PrivateIpAddress:   aws.String("10.0.0.5"),
+		Association:        &ec2.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("54.0.0.1")},
+		Attachment:         &ec2.InstanceNetworkInterfaceAttachment{AttachmentId: aws.String("eni-attach-1"), DeviceIndex: aws.Int64(1)},
+		PrivateIpAddresses: []*ec2.InstancePrivateIpAddress{
+			{PrivateIpAddress: aws.String("10.0.0.5"), Primary: aws.Bool(true)},
+			{PrivateIpAddress: aws.String("10.0.0.6"), Primary: aws.Bool(false)},
+			{PrivateIpAddress: aws.String("10.0.0.7")},
+		},
+	})
+	if ini.ID != "eni-1" || ini.Subnet != "subnet-1" || ini.PrivateIP != "10.0.0.5" || ini.PublicIP != "54.0.0.1" {
+		t.Fatalf("bad interface: %+v", ini)
+	}
+	if ini.AttachmentID != "eni-attach-1" || ini.DeviceIndex != 1 {
+		t.Fatalf("bad attachment: %+v", ini)
+	}
+	if strings.Join(ini.SecondaryPrivateIPs, ",") != "10.0.0.6,10.0.0.7" {
+		t.Fatalf("bad secondary IPs: %v", ini.SecondaryPrivateIPs)
+	}
+	if ini = instanceNetworkInterface(&ec2.InstanceNetworkInterface{}); ini.PublicIP != "" || ini.SecondaryPrivateIPs == nil {
+		t.Fatalf("bad empty interface: %+v", ini)
+	}
+}
+
+func TestCreateNetworkInterface(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"CreateNetworkInterface": `<CreateNetworkInterfaceResponse><networkInterface><networkInterfaceId>eni-1</networkInterfaceId></networkInterface></CreateNetworkInterfaceResponse>`,
+	})
+	defer done()
+	id, err := svc.CreateNetworkInterface(&NetworkInterfaceDefinition{
+		Subnet:              "subnet-1",
+		PrivateIP:           "10.0.0.5",
+		SecondaryPrivateIPs: []string{"10.0.0.6"},
+	})
+	if err != nil {
+		t.Fatalf("error creating interface: %v", err)
+	}
+	if id != "eni-1" {
+		t.Fatalf("bad ID: %v", id)
+	}
+	form := f.requests[0]
+	if form.Get("PrivateIpAddress") != "" {
+		t.Fatalf("primary IP should be sent in PrivateIpAddresses: %v", form)
+	}
+	if form.Get("PrivateIpAddresses.1.PrivateIpAddress") != "10.0.0.5" || form.Get("PrivateIpAddresses.1.Primary") != "true" ||
+		form.Get("PrivateIpAddresses.2.PrivateIpAddress") != "10.0.0.6" || form.Get("PrivateIpAddresses.2.Primary") != "false" {
+		t.Fatalf("bad private IPs: %v", form)
+	}
+	_, err = svc.CreateNetworkInterface(&NetworkInterfaceDefinition{Subnet: "subnet-1", SecondaryPrivateIPs: []string{"10.0.0.6"}, SecondaryPrivateIPCount: 2})
+	if !errors.Is(err, ErrInvalidParameter) || len(f.requests) != 1 {
+		t.Fatalf("IPs with a count should be rejected locally: %v", err)
+	}
+}
+
+func TestAssignPrivateIPs(t *testing.T) {
+	svc, f, done := newEC2FakeService(map[string]string{
+		"AssignPrivateIpAddresses": `<AssignPrivateIpAddressesResponse><networkInterfaceId>eni-1</networkInterfaceId><assignedPrivateIpAddressesSet>` +
+			`<item><privateIpAddress>10.0.0.8</privateIpAddress></item><item><privateIpAddress>10.0.0.9</privateIpAddress></item>` +
+			`</assignedPrivateIpAddressesSet></AssignPrivateIpAddressesResponse>`,
+	})
+	defer done()
+	ips, err := svc.AssignPrivateIPs("eni-1", nil, 2)
+	if err != nil {
+		t.Fatalf("error assigning IPs: %v", err)
+	}
+	if strings.Join(ips, ",") != "10.0.0.8,10.0.0.9" || f.requests[0].Get("SecondaryPrivateIpAddressCount") != "2" {
+		t.Fatalf("bad assignment: %v: %v", ips, f.requests[0])
+	}
+	if _, err := svc.AssignPrivateIPs("eni-1", []string{"10.0.0.8"}, 0); err != nil {
+		t.Fatalf("error assigning IPs: %v", err)
+	}
+	if form := f.requests[1]; form.Get("PrivateIpAddress.1") != "10.0.0.8" || form.Get("SecondaryPrivateIpAddressCount") != "" {
+		t.Fatalf("bad request: %v", form)
+	}
+	if _, err := svc.AssignPrivateIPs("eni-1", nil, 0); !errors.Is(err, ErrInvalidParameter) || len(f.requests) != 2 {
+		t.Fatalf("no IPs and no count should be rejected locally: %v", err)
+	}
+}
+
+func TestGetNetworkInterfacesInfo(t *testing.T) {
+	svc, _, done := newEC2FakeService(map[string]string{
+		"DescribeNetworkInterfaces": `<DescribeNetworkInterfacesResponse><networkInterfaceSet><item>` +
+			`<networkInterfaceId>eni-1</networkInterfaceId><subnetId>subnet-1</subnetId><vpcId>vpc-1</vpcId><status>in-use</status>` +
+			`<privateIpAddress>10.0.0.5</privateIpAddress><groupSet><item><groupId>sg-1</groupId></item></groupSet>` +
+			`<attachment><attachmentId>eni-attach-1</attachmentId><instanceId>i-1</instanceId><deviceIndex>1</deviceIndex></attachment>` +
+			`<privateIpAddressesSet><item><privateIpAddress>10.0.0.5</privateIpAddress><primary>true</primary></item>` +
+			`<item><privateIpAddress>10.0.0.6</privateIpAddress><primary>false</primary></item></privateIpAddressesSet>` +
+			`<tagSet><item><key>Name</key><value>web</value></item></tagSet>` +
+			`</item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`,
+	})
+	defer done()
+	nis, err := svc.GetNetworkInterfacesInfo([]string{"eni-1"})
+	if err != nil {
+		t.Fatalf("error getting interfaces: %v", err)
+	}
+	if len(nis) != 1 {
+		t.Fatalf("bad interfaces: %+v", nis)
+	}
+	ni := nis[0]
+	if ni.ID != "eni-1" || ni.VPC != "vpc-1" || ni.Status != "in-use" || ni.InstanceID != "i-1" || ni.DeviceIndex != 1 || ni.Tags["Name"] != "web" {
+		t.Fatalf("bad interface: %+v", ni)
+	}
+	if strings.Join(ni.SecondaryPrivateIPs, ",") != "10.0.0.6" || strings.Join(ni.SecurityGroups, ",") != "sg-1" {
+		t.Fatalf("bad IPs or groups: %+v", ni)
+	}
+}
@@ -0,0 +1,156 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Error kinds. Errors returned by RealAWSService can be tested against these with errors.Is,
+// and unpacked with errors.As into *AWSError for the AWS error code and request ID.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrThrottled           = errors.New("throttled")
+	ErrAlreadyExists       = errors.New("already exists")
+	ErrInvalidParameter    = errors.New("invalid parameter")
+	ErrDependencyViolation = errors.New("dependency violation")
+
+	// Returned by TerminateInstances and StopInstances/HibernateInstances respectively when an instance has
+	// termination or stop protection enabled
+	ErrTerminationProtected = errors.New("termination protected")
+	ErrStopProtected        = errors.New("stop protected")
+)
+
+// AWSError is an error returned by an AWS API call (or an equivalent condition detected locally,
+// such as an empty describe result)
+type AWSError struct {
+	Op         string // awsservice method, eg "GetSubnetInfo"
+	Kind       error  // One of the Err* kinds, or nil if unclassified
+	Code       string // AWS error code, eg "InvalidSubnetID.NotFound" (empty if detected locally)
+	Message    string
+	RequestID  string
+	StatusCode int
+	Err        error // Underlying SDK error (nil if detected locally)
+}
+
+func (e *AWSError) Error() string {
+	msg := fmt.Sprintf("%v: ", e.Op)
+	if e.Code != "" {
+		msg += fmt.Sprintf("%v: ", e.Code)
+	}
+	msg += e.Message
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id: %v)", e.RequestID)
+	}
+	return msg
+}
+
+func (e *AWSError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of e
+func (e *AWSError) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// Is makes validation failures match ErrInvalidParameter
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidParameter
+}
+
+var throttleCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"RequestThrottledException":              true,
+	"TooManyRequestsException":               true,
+	"SlowDown":                               true,
+	"PriorRequestNotComplete":                true,
+	"EC2ThrottledException":                  true,
+	"ProvisionedThroughputExceededException": true,
+}
+
+var notFoundCodes = map[string]bool{
+	"LoadBalancerNotFound": true,
+	"NoSuchBucket":         true,
+	"NoSuchKey":            true,
+	"NoSuchHostedZone":     true,
+	"NoSuchDelegationSet":  true,
+	"NotFound":             true,
+}
+
+// classifyErrorCode maps an AWS error code onto one of the error kinds
+func classifyErrorCode(code string, status int) error {
+	switch {
+	case code == "":
+		return nil
+	case throttleCodes[code] || status == 429:
+		return ErrThrottled
+	case notFoundCodes[code] || strings.HasSuffix(code, ".NotFound") || strings.HasSuffix(code, "NotFound") ||
+		strings.HasSuffix(code, "NotFoundException") || strings.HasSuffix(code, "NotFoundFault"):
+		return ErrNotFound
+	case strings.Contains(code, "AlreadyExists") || strings.Contains(code, "Duplicate") || code == "BucketAlreadyOwnedByYou":
+		return ErrAlreadyExists
+	case code == "DependencyViolation" || strings.Contains(code, "InUse") || strings.HasSuffix(code, "NotEmpty"):
+		return ErrDependencyViolation
+	case strings.HasPrefix(code, "Invalid") || strings.HasPrefix(code, "Validation") || strings.HasPrefix(code, "MissingParameter") ||
+		code == request.InvalidParameterErrCode || code == request.ParamRequiredErrCode || code == request.ParamMinValueErrCode ||
+		code == request.ParamMinLenErrCode || code == request.ParamMaxLenErrCode:
+		return ErrInvalidParameter
+	case status == 404:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// wrapError converts SDK errors into *AWSError. nil, *AWSError and non-AWS errors are returned unchanged.
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ae *AWSError
+	if errors.As(err, &ae) {
+		return err
+	}
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	e := &AWSError{
+		Op:      op,
+		Code:    aerr.Code(),
+		Message: aerr.Message(),
+		Err:     err,
+	}
+	if rf, ok := err.(awserr.RequestFailure); ok {
+		e.RequestID = rf.RequestID()
+		e.StatusCode = rf.StatusCode()
+	}
+	e.Kind = classifyErrorCode(e.Code, e.StatusCode)
+	return e
+}
+
+// notFoundError reports a missing resource detected without an AWS error (eg an empty describe result)
+func notFoundError(op string, format string, args ...interface{}) error {
+	return &AWSError{
+		Op:      op,
+		Kind:    ErrNotFound,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// IsNotFound is shorthand for errors.Is(err, ErrNotFound)
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsThrottled is shorthand for errors.Is(err, ErrThrottled)
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
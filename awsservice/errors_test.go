@@ -0,0 +1,81 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestWrapErrorClassification(t *testing.T) {
+	cases := []struct {
+		code   string
+		status int
+		kind   error
+	}{
+		{"InvalidSubnetID.NotFound", 400, ErrNotFound},
+		{"InvalidInstanceID.NotFound", 400, ErrNotFound},
+		{"LoadBalancerNotFound", 400, ErrNotFound},
+		{"ParameterNotFound", 400, ErrNotFound},
+		{"NoSuchKey", 404, ErrNotFound},
+		{"RequestLimitExceeded", 503, ErrThrottled},
+		{"Throttling", 400, ErrThrottled},
+		{"InvalidGroup.Duplicate", 400, ErrAlreadyExists},
+		{"AlreadyExists", 400, ErrAlreadyExists},
+		{"DependencyViolation", 400, ErrDependencyViolation},
+		{"ResourceInUse", 400, ErrDependencyViolation},
+		{"InvalidParameterValue", 400, ErrInvalidParameter},
+		{"ValidationError", 400, ErrInvalidParameter},
+		{"UnauthorizedOperation", 403, nil},
+	}
+	for _, c := range cases {
+		err := wrapError("Op", awserr.NewRequestFailure(awserr.New(c.code, "msg", nil), c.status, "req-1"))
+		var ae *AWSError
+		if !errors.As(err, &ae) {
+			t.Fatalf("%v: expected *AWSError: %T", c.code, err)
+		}
+		if ae.Kind != c.kind {
+			t.Fatalf("%v: bad kind: %v (expected %v)", c.code, ae.Kind, c.kind)
+		}
+		if ae.Code != c.code || ae.RequestID != "req-1" || ae.StatusCode != c.status || ae.Op != "Op" {
+			t.Fatalf("%v: bad error fields: %+v", c.code, ae)
+		}
+		if c.kind != nil && !errors.Is(err, c.kind) {
+			t.Fatalf("%v: errors.Is should have matched %v", c.code, c.kind)
+		}
+	}
+}
+
+func TestWrapErrorPassthrough(t *testing.T) {
+	if wrapError("Op", nil) != nil {
+		t.Fatalf("nil should stay nil")
+	}
+	plain := fmt.Errorf("plain")
+	if wrapError("Op", plain) != plain {
+		t.Fatalf("non-AWS errors should be returned unchanged")
+	}
+	inner := wrapError("Inner", awserr.New("InvalidVpcID.NotFound", "msg", nil))
+	outer := wrapError("Outer", fmt.Errorf("context: %w", inner))
+	var ae *AWSError
+	if !errors.As(outer, &ae) || ae.Op != "Inner" {
+		t.Fatalf("already wrapped error should keep its op: %v", outer)
+	}
+	if !IsNotFound(outer) {
+		t.Fatalf("should have been not found: %v", outer)
+	}
+}
+
+func TestLocalErrorKinds(t *testing.T) {
+	if err := notFoundError("GetSubnetInfo", "subnet not found: %v", "subnet-1"); !IsNotFound(err) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+	idef := testInstancesDefinition()
+	idef.Count = 0
+	if err := idef.Validate(); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("validation error should match ErrInvalidParameter: %v", err)
+	}
+	if err := invalidDefinition("bad"); errors.Is(err, ErrNotFound) {
+		t.Fatalf("validation error should not match ErrNotFound: %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package awsservice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// HostedZoneDefinition describes a Route53 hosted zone to create. A zone is private if it has any VPCs.
+type HostedZoneDefinition struct {
+	Name            string // Domain name, eg "internal.example.com"
+	Comment         string
+	VPCs            []HostedZoneVPC // Private zones only
+	DelegationSetID string          // Optional, public zones only: reusable delegation set providing the name servers
+}
+
+// HostedZoneVPC is a VPC associated with a private hosted zone
+type HostedZoneVPC struct {
+	ID     string
+	Region string // Optional (default: the service's region)
+}
+
+type HostedZoneInfo struct {
+	ID              string // Usable as Route53RecordDefinition.ZoneID
+	Name            string
+	Comment         string
+	Private         bool
+	RecordCount     int64
+	DelegationSetID string          // Public zones created with a reusable delegation set
+	NameServers     []string        // Public zones only
+	VPCs            []HostedZoneVPC // Private zones only
+}
+
+// DelegationSet is a reusable set of name servers that can be shared by public hosted zones
+type DelegationSet struct {
+	ID          string
+	NameServers []string
+}
+
+// Validate checks the definition before any API call is made, returning a *ValidationError listing every problem found
+func (hzd *HostedZoneDefinition) Validate() error {
+	ve := &ValidationError{}
+	if hzd.Name == "" {
+		ve.add("zone name is required")
+	}
+	for _, v := range hzd.VPCs {
+		if v.ID == "" {
+			ve.add("VPC ID is required")
+		}
+	}
+	if len(hzd.VPCs) > 0 && hzd.DelegationSetID != "" {
+		ve.add("delegation sets can only be used by public zones")
+	}
+	return ve.errOrNil()
+}
+
+// Route53 returns IDs as paths, eg "/hostedzone/Z123"
+func hostedZoneID(id *string) string {
+	return strings.TrimPrefix(drefStringPtr(id), "/hostedzone/")
+}
+
+func delegationSetID(id *string) string {
+	return strings.TrimPrefix(drefStringPtr(id), "/delegationset/")
+}
+
+// callerReference returns a unique reference, which Route53 uses to make creation requests idempotent
+func callerReference() string {
+	return fmt.Sprintf("awsservice-%v", time.Now().UnixNano())
+}
+
+func hostedZoneInfo(hz *route53.HostedZone, ds *route53.DelegationSet, vpcs []*route53.VPC) *HostedZoneInfo {
+	hzi := &HostedZoneInfo{
+		ID:          hostedZoneID(hz.Id),
+		Name:        drefStringPtr(hz.Name),
+		RecordCount: drefInt64Ptr(hz.ResourceRecordSetCount),
+		NameServers: []string{},
+		VPCs:        []HostedZoneVPC{},
+	}
+	if hz.Config != nil {
+		hzi.Comment = drefStringPtr(hz.Config.Comment)
+		hzi.Private = hz.Config.PrivateZone != nil && *hz.Config.PrivateZone
+	}
+	if ds != nil {
+		hzi.DelegationSetID = delegationSetID(ds.Id)
+		hzi.NameServers = stringPointerSlicetoStringSlice(ds.NameServers)
+	}
+	for _, v := range vpcs {
+		hzi.VPCs = append(hzi.VPCs, HostedZoneVPC{ID: drefStringPtr(v.VPCId), Region: drefStringPtr(v.VPCRegion)})
+	}
+	return hzi
+}
+
+func (aws *RealAWSService) r53VPC(v HostedZoneVPC) *route53.VPC {
+	region := v.Region
+	if region == "" {
+		// Route53 is global, so use the region of a regional client
+		region = drefStringPtr(aws.ec2.Config.Region)
+	}
+	return &route53.VPC{VPCId: &v.ID, VPCRegion: &region}
+}
+
+// CreateHostedZone creates a public zone, or a private zone associated with hzd.VPCs. If associating a VPC after the
+// first fails, the zone is left in place and returned along with the error.
+func (aws *RealAWSService) CreateHostedZone(hzd *HostedZoneDefinition) (*HostedZoneInfo, error) {
+	hzi := &HostedZoneInfo{}
+	if err := hzd.Validate(); err != nil {
+		return hzi, err
+	}
+	ref := callerReference()
+	chzi := &route53.CreateHostedZoneInput{
+		Name:            &hzd.Name,
+		CallerReference: &ref,
+		HostedZoneConfig: &route53.HostedZoneConfig{
+			Comment:     &hzd.Comment,
+			PrivateZone: &False,
+		},
+	}
+	if len(hzd.VPCs) > 0 {
+		chzi.HostedZoneConfig.PrivateZone = &True
+		chzi.VPC = aws.r53VPC(hzd.VPCs[0])
+	}
+	if hzd.DelegationSetID != "" {
+		chzi.DelegationSetId = &hzd.DelegationSetID
+	}
+	res, err := aws.r53c.CreateHostedZone(chzi)
+	if err != nil {
+		return hzi, wrapError("CreateHostedZone", err)
+	}
+	if res.HostedZone == nil {
+		// dry run
+		return hzi, nil
+	}
+	vpcs := []*route53.VPC{}
+	if res.VPC != nil {
+		vpcs = append(vpcs, res.VPC)
+	}
+	hzi = hostedZoneInfo(res.HostedZone, res.DelegationSet, vpcs)
+	if hzi.Private {
+		// private zones have no public name servers
+		hzi.NameServers = []string{}
+	}
+	for i := 1; i < len(hzd.VPCs); i++ {
+		if err := aws.AssociateVPCWithHostedZone(hzi.ID, hzd.VPCs[i]); err != nil {
+			return hzi, err
+		}
+		vpc := aws.r53VPC(hzd.VPCs[i])
+		hzi.VPCs = append(hzi.VPCs, HostedZoneVPC{ID: *vpc.VPCId, Region: *vpc.VPCRegion})
+	}
+	return hzi, nil
+}
+
+// DeleteHostedZone deletes a zone. It fails with ErrDependencyViolation if the zone has records other than
+// the default SOA and NS records.
+func (aws *RealAWSService) DeleteHostedZone(id string) error {
+	_, err := aws.r53c.DeleteHostedZone(&route53.DeleteHostedZoneInput{Id: &id})
+	return wrapError("DeleteHostedZone", err)
+}
+
+// GetHostedZoneInfo returns a zone, with its name servers (public zones) or VPCs (private zones)
+func (aws *RealAWSService) GetHostedZoneInfo(id string) (*HostedZoneInfo, error) {
+	res, err := aws.r53c.GetHostedZone(&route53.GetHostedZoneInput{Id: &id})
+	if err != nil {
+		return &HostedZoneInfo{}, wrapError("GetHostedZoneInfo", err)
+	}
+	if res.HostedZone == nil {
+		return &HostedZoneInfo{}, notFoundError("GetHostedZoneInfo", "hosted zone not found: %v", id)
+	}
+	return hostedZoneInfo(res.HostedZone, res.DelegationSet, res.VPCs), nil
+}
+
+// FindHostedZonesByName returns the zones with a domain name (there may be several, eg a public zone and private
+// zones for different VPCs). Names are compared without the trailing dot. Name servers and VPCs are not included;
+// use GetHostedZoneInfo for those.
+func (aws *RealAWSService) FindHostedZonesByName(name string) ([]HostedZoneInfo, error) {
+	result := []HostedZoneInfo{}
+	want := strings.TrimSuffix(name, ".")
+	lhzbni := &route53.ListHostedZonesByNameInput{DNSName: &name}
+	for {
+		res, err := aws.r53c.ListHostedZonesByName(lhzbni)
+		if err != nil {
+			return result, wrapError("FindHostedZonesByName", err)
+		}
+		for _, hz := range res.HostedZones {
+			// zones are returned in name order starting at name, so stop at the first other name
+			if !strings.EqualFold(strings.TrimSuffix(drefStringPtr(hz.Name), "."), want) {
+				return result, nil
+			}
+			result = append(result, *hostedZoneInfo(hz, nil, nil))
+		}
+		if res.IsTruncated == nil || !*res.IsTruncated {
+			return result, nil
+		}
+		lhzbni.DNSName = res.NextDNSName
+		lhzbni.HostedZoneId = res.NextHostedZoneId
+	}
+}
+
+// AssociateVPCWithHostedZone allows a VPC to resolve a private zone's records
+func (aws *RealAWSService) AssociateVPCWithHostedZone(id string, vpc HostedZoneVPC) error {
+	_, err := aws.r53c.AssociateVPCWithHostedZone(&route53.AssociateVPCWithHostedZoneInput{
+		HostedZoneId: &id,
+		VPC:          aws.r53VPC(vpc),
+	})
+	return wrapError("AssociateVPCWithHostedZone", err)
+}
+
+// DisassociateVPCFromHostedZone removes a VPC from a private zone. The last VPC can't be removed; delete the zone instead.
+func (aws *RealAWSService) DisassociateVPCFromHostedZone(id string, vpc HostedZoneVPC) error {
+	_, err := aws.r53c.DisassociateVPCFromHostedZone(&route53.DisassociateVPCFromHostedZoneInput{
+		HostedZoneId: &id,
+		VPC:          aws.r53VPC(vpc),
+	})
+	return wrapError("DisassociateVPCFromHostedZone", err)
+}
+
+// CreateDelegationSet creates a reusable delegation set. If zoneID is not empty the set reuses the name servers
+// of that public zone.
+func (aws *RealAWSService) CreateDelegationSet(zoneID string) (*DelegationSet, error) {
+	ref := callerReference()
+	crdsi := &route53.CreateReusableDelegationSetInput{
+		CallerReference: &ref,
+	}
+	if zoneID != "" {
+		crdsi.HostedZoneId = &zoneID
+	}
+	res, err := aws.r53c.CreateReusableDelegationSet(crdsi)
+	if err != nil || res.DelegationSet == nil {
+		return &DelegationSet{NameServers: []string{}}, wrapError("CreateDelegationSet", err)
+	}
+	return &DelegationSet{
+		ID:          delegationSetID(res.DelegationSet.Id),
+		NameServers: stringPointerSlicetoStringSlice(res.DelegationSet.NameServers),
+	}, nil
+}
+
+// GetDelegationSets returns every reusable delegation set in the account
+func (aws *RealAWSService) GetDelegationSets() ([]DelegationSet, error) {
+	result := []DelegationSet{}
+	lrdsi := &route53.ListReusableDelegationSetsInput{}
+	for {
+		res, err := aws.r53c.ListReusableDelegationSets(lrdsi)
+		if err != nil {
+			return result, wrapError("GetDelegationSets", err)
+		}
+		for _, ds := range res.DelegationSets {
+			result = append(result, DelegationSet{
+				ID:          delegationSetID(ds.Id),
+				NameServers: stringPointerSlicetoStringSlice(ds.NameServers),
+			})
+		}
+		if res.IsTruncated == nil || !*res.IsTruncated {
+			return result, nil
+		}
+		lrdsi.Marker = res.NextMarker
+	}
+}
+
+// DeleteDelegationSet deletes a reusable delegation set. It fails with ErrDependencyViolation while zones use it.
+func (aws *RealAWSService) DeleteDelegationSet(id string) error {
+	_, err := aws.r53c.DeleteReusableDelegationSet(&route53.DeleteReusableDelegationSetInput{Id: &id})
+	return wrapError("DeleteDelegationSet", err)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) CreateHostedZone(hzd *HostedZoneDefinition) (*HostedZoneInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateHostedZone",
+		NotableParams: map[string]string{
+			"name": hzd.Name,
+			"vpcs": fmt.Sprintf("%v", hzd.VPCs),
+		},
+	})
+	return &HostedZoneInfo{}, nil
+}
+
+func (aws *TestingAWSService) DeleteHostedZone(id string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteHostedZone",
+		NotableParams: map[string]string{
+			"zone_id": id,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetHostedZoneInfo(id string) (*HostedZoneInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetHostedZoneInfo",
+		NotableParams: map[string]string{
+			"zone_id": id,
+		},
+	})
+	return &HostedZoneInfo{}, nil
+}
+
+func (aws *TestingAWSService) FindHostedZonesByName(name string) ([]HostedZoneInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindHostedZonesByName",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return []HostedZoneInfo{}, nil
+}
+
+func (aws *TestingAWSService) AssociateVPCWithHostedZone(id string, vpc HostedZoneVPC) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AssociateVPCWithHostedZone",
+		NotableParams: map[string]string{
+			"zone_id": id,
+			"vpc":     vpc.ID,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) DisassociateVPCFromHostedZone(id string, vpc HostedZoneVPC) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DisassociateVPCFromHostedZone",
+		NotableParams: map[string]string{
+			"zone_id": id,
+			"vpc":     vpc.ID,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) CreateDelegationSet(zoneID string) (*DelegationSet, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateDelegationSet",
+		NotableParams: map[string]string{
+			"zone_id": zoneID,
+		},
+	})
+	return &DelegationSet{}, nil
+}
+
+func (aws *TestingAWSService) GetDelegationSets() ([]DelegationSet, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action:        "GetDelegationSets",
+		NotableParams: map[string]string{},
+	})
+	return []DelegationSet{}, nil
+}
+
+func (aws *TestingAWSService) DeleteDelegationSet(id string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteDelegationSet",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return nil
+}
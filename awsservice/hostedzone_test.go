@@ -0,0 +1,107 @@
+package awsservice
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const r53CreatePrivateZoneResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<CreateHostedZoneResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><HostedZone><Id>/hostedzone/Z1</Id><Name>internal.example.com.</Name><CallerReference>ref</CallerReference><Config><Comment>discovery</Comment><PrivateZone>true</PrivateZone></Config><ResourceRecordSetCount>2</ResourceRecordSetCount></HostedZone><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status><SubmittedAt>2026-10-19T12:00:00Z</SubmittedAt></ChangeInfo><DelegationSet><NameServers><NameServer>ns-1.awsdns-00.com</NameServer></NameServers></DelegationSet><VPC><VPCRegion>us-west-2</VPCRegion><VPCId>vpc-1</VPCId></VPC></CreateHostedZoneResponse>`
+
+const r53AssociateVPCResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<AssociateVPCWithHostedZoneResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C2</Id><Status>PENDING</Status><SubmittedAt>2026-10-19T12:00:00Z</SubmittedAt></ChangeInfo></AssociateVPCWithHostedZoneResponse>`
+
+const r53ListZonesByNameResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<ListHostedZonesByNameResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><HostedZones><HostedZone><Id>/hostedzone/Z1</Id><Name>internal.example.com.</Name><CallerReference>a</CallerReference><Config><PrivateZone>true</PrivateZone></Config></HostedZone><HostedZone><Id>/hostedzone/Z2</Id><Name>internal.example.com.</Name><CallerReference>b</CallerReference><Config><PrivateZone>false</PrivateZone></Config></HostedZone><HostedZone><Id>/hostedzone/Z3</Id><Name>other.example.com.</Name><CallerReference>c</CallerReference></HostedZone></HostedZones><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListHostedZonesByNameResponse>`
+
+const r53NoSuchHostedZoneResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<ErrorResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><Error><Type>Sender</Type><Code>NoSuchHostedZone</Code><Message>No hosted zone found with ID: Z9</Message></Error><RequestId>req-1</RequestId></ErrorResponse>`
+
+func TestCreatePrivateHostedZone(t *testing.T) {
+	var mu sync.Mutex
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/2013-04-01/hostedzone":
+			w.Header().Set("Location", "https://route53.amazonaws.com/2013-04-01/hostedzone/Z1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(r53CreatePrivateZoneResponse))
+		case r.URL.Path == "/2013-04-01/hostedzone/Z1/associatevpc":
+			w.Write([]byte(r53AssociateVPCResponse))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	hzi, err := svc.CreateHostedZone(&HostedZoneDefinition{
+		Name:    "internal.example.com",
+		Comment: "discovery",
+		VPCs:    []HostedZoneVPC{{ID: "vpc-1"}, {ID: "vpc-2", Region: "us-east-1"}},
+	})
+	if err != nil {
+		t.Fatalf("error creating zone: %v", err)
+	}
+	if hzi.ID != "Z1" || !hzi.Private || len(hzi.NameServers) != 0 {
+		t.Fatalf("bad zone: %+v", hzi)
+	}
+	if len(hzi.VPCs) != 2 || hzi.VPCs[1] != (HostedZoneVPC{ID: "vpc-2", Region: "us-east-1"}) {
+		t.Fatalf("bad VPCs: %v", hzi.VPCs)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("bad requests: %v", requests)
+	}
+	// The SDK does not write the VPC elements in a fixed order
+	if !strings.Contains(requests[0], "<PrivateZone>true</PrivateZone>") || !strings.Contains(requests[0], "<VPCId>vpc-1</VPCId>") ||
+		!strings.Contains(requests[0], "<VPCRegion>us-west-2</VPCRegion>") {
+		t.Fatalf("bad create request: %v", requests[0])
+	}
+	if !strings.Contains(requests[1], "<VPCId>vpc-2</VPCId>") || !strings.Contains(requests[1], "<VPCRegion>us-east-1</VPCRegion>") {
+		t.Fatalf("bad associate request: %v", requests[1])
+	}
+}
+
+func TestFindHostedZonesByName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2013-04-01/hostedzonesbyname" || r.URL.Query().Get("dnsname") != "internal.example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(r53ListZonesByNameResponse))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	hzis, err := svc.FindHostedZonesByName("internal.example.com")
+	if err != nil {
+		t.Fatalf("error finding zones: %v", err)
+	}
+	if len(hzis) != 2 || hzis[0].ID != "Z1" || !hzis[0].Private || hzis[1].ID != "Z2" || hzis[1].Private {
+		t.Fatalf("bad zones: %+v", hzis)
+	}
+}
+
+func TestHostedZoneErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(r53NoSuchHostedZoneResponse))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if err := svc.DeleteHostedZone("Z9"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+	_, err := svc.CreateHostedZone(&HostedZoneDefinition{VPCs: []HostedZoneVPC{{}}, DelegationSetID: "N1"})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 3 {
+		t.Fatalf("should have failed validation with 3 problems: %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package awsservice
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyPairDefinition describes a key pair to create or import
+type KeyPairDefinition struct {
+	Name      string
+	Type      string // "rsa" or "ed25519" (default: rsa). Ignored on import.
+	PublicKey []byte // Import only: OpenSSH authorized_keys format, eg "ssh-ed25519 AAAA... comment"
+	Tags      map[string]string
+}
+
+type KeyPairInfo struct {
+	Name        string
+	ID          string
+	Type        string
+	Fingerprint string
+	PublicKey   string // OpenSSH format
+	CreateTime  time.Time
+	Tags        map[string]string
+}
+
+// KeyPair is a newly created key pair with its private key, which AWS does not keep
+type KeyPair struct {
+	KeyPairInfo
+	PrivateKey []byte // PEM (RSA) or OpenSSH (ED25519) format
+}
+
+func keyPairInfo(kp *ec2.KeyPairInfo) KeyPairInfo {
+	tags := map[string]string{}
+	for _, t := range kp.Tags {
+		tags[drefStringPtr(t.Key)] = drefStringPtr(t.Value)
+	}
+	return KeyPairInfo{
+		Name:        drefStringPtr(kp.KeyName),
+		ID:          drefStringPtr(kp.KeyPairId),
+		Type:        drefStringPtr(kp.KeyType),
+		Fingerprint: drefStringPtr(kp.KeyFingerprint),
+		PublicKey:   drefStringPtr(kp.PublicKey),
+		CreateTime:  drefTimePtr(kp.CreateTime),
+		Tags:        tags,
+	}
+}
+
+// CreateKeyPair creates a key pair, returning its private key. The private key can't be retrieved again.
+func (aws *RealAWSService) CreateKeyPair(kpd *KeyPairDefinition) (*KeyPair, error) {
+	result := &KeyPair{}
+	if kpd.Name == "" {
+		return result, invalidDefinition("key pair name is required")
+	}
+	kt := kpd.Type
+	if kt == "" {
+		kt = ec2.KeyTypeRsa
+	}
+	res, err := aws.ec2.CreateKeyPair(&ec2.CreateKeyPairInput{
+		KeyName:           &kpd.Name,
+		KeyType:           &kt,
+		TagSpecifications: ec2TagSpecification(ec2.ResourceTypeKeyPair, kpd.Tags),
+	})
+	if err != nil {
+		return result, wrapError("CreateKeyPair", err)
+	}
+	result.KeyPairInfo = keyPairInfo(&ec2.KeyPairInfo{
+		KeyName:        res.KeyName,
+		KeyPairId:      res.KeyPairId,
+		KeyType:        &kt,
+		KeyFingerprint: res.KeyFingerprint,
+		Tags:           res.Tags,
+	})
+	result.PrivateKey = []byte(drefStringPtr(res.KeyMaterial))
+	return result, nil
+}
+
+// ImportKeyPair creates a key pair from an existing public key
+func (aws *RealAWSService) ImportKeyPair(kpd *KeyPairDefinition) (*KeyPairInfo, error) {
+	result := &KeyPairInfo{}
+	if kpd.Name == "" || len(kpd.PublicKey) == 0 {
+		return result, invalidDefinition("key pair name and public key are required")
+	}
+	res, err := aws.ec2.ImportKeyPair(&ec2.ImportKeyPairInput{
+		KeyName:           &kpd.Name,
+		PublicKeyMaterial: kpd.PublicKey,
+		TagSpecifications: ec2TagSpecification(ec2.ResourceTypeKeyPair, kpd.Tags),
+	})
+	if err != nil {
+		return result, wrapError("ImportKeyPair", err)
+	}
+	*result = keyPairInfo(&ec2.KeyPairInfo{
+		KeyName:        res.KeyName,
+		KeyPairId:      res.KeyPairId,
+		KeyFingerprint: res.KeyFingerprint,
+		Tags:           res.Tags,
+	})
+	result.PublicKey = strings.TrimSpace(string(kpd.PublicKey))
+	return result, nil
+}
+
+// GetKeyPairsInfo returns the named key pairs, or all key pairs in the region if names is empty
+func (aws *RealAWSService) GetKeyPairsInfo(names []string) ([]KeyPairInfo, error) {
+	result := []KeyPairInfo{}
+	dkpi := &ec2.DescribeKeyPairsInput{IncludePublicKey: &True}
+	if len(names) > 0 {
+		dkpi.KeyNames = stringSlicetoStringPointerSlice(names)
+	}
+	res, err := aws.ec2.DescribeKeyPairs(dkpi)
+	if err != nil {
+		return result, wrapError("GetKeyPairsInfo", err)
+	}
+	for _, kp := range res.KeyPairs {
+		result = append(result, keyPairInfo(kp))
+	}
+	return result, nil
+}
+
+func (aws *RealAWSService) DeleteKeyPair(name string) error {
+	_, err := aws.ec2.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: &name})
+	return wrapError("DeleteKeyPair", err)
+}
+
+func colonHex(b []byte) string {
+	parts := []string{}
+	for _, c := range b {
+		parts = append(parts, fmt.Sprintf("%02x", c))
+	}
+	return strings.Join(parts, ":")
+}
+
+// rsaImportedFingerprint is the fingerprint AWS shows for an imported RSA key: the MD5 digest of the DER
+// encoded public key
+func rsaImportedFingerprint(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("error encoding public key: %v", err)
+	}
+	sum := md5.Sum(der)
+	return colonHex(sum[:]), nil
+}
+
+// ed25519Fingerprint is the fingerprint AWS shows for ED25519 keys: the base64 SHA-256 digest of the
+// OpenSSH public key
+func ed25519Fingerprint(pub ssh.PublicKey) string {
+	sum := sha256.Sum256(pub.Marshal())
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// KeyFingerprints returns the fingerprints AWS may show for a key, given its private key (PEM or OpenSSH
+// format) or its public key (OpenSSH authorized_keys format). An RSA private key has two: AWS fingerprints
+// the private key of the key pairs it creates, and the public key of those imported.
+func KeyFingerprints(key []byte) ([]string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(key), []byte("-----BEGIN")) {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %v", err)
+		}
+		switch pub.Type() {
+		case ssh.KeyAlgoRSA:
+			rpk, ok := pub.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("bad RSA public key")
+			}
+			fp, err := rsaImportedFingerprint(rpk)
+			if err != nil {
+				return nil, err
+			}
+			return []string{fp}, nil
+		case ssh.KeyAlgoED25519:
+			return []string{ed25519Fingerprint(pub)}, nil
+		}
+		return nil, fmt.Errorf("unsupported key type: %v", pub.Type())
+	}
+	priv, err := ssh.ParseRawPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding private key: %v", err)
+		}
+		sum := sha1.Sum(der)
+		imported, err := rsaImportedFingerprint(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return []string{colonHex(sum[:]), imported}, nil
+	case *ed25519.PrivateKey:
+		priv = *k
+	}
+	if k, ok := priv.(ed25519.PrivateKey); ok {
+		pub, err := ssh.NewPublicKey(k.Public())
+		if err != nil {
+			return nil, fmt.Errorf("error encoding public key: %v", err)
+		}
+		return []string{ed25519Fingerprint(pub)}, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type: %T", priv)
+}
+
+// normalizeFingerprint makes fingerprints formatted by AWS and by ssh-keygen comparable
+func normalizeFingerprint(fp string) string {
+	return strings.TrimRight(strings.TrimPrefix(strings.TrimSpace(fp), "SHA256:"), "=")
+}
+
+// Matches reports whether key (a private or public key, as for KeyFingerprints) belongs to the key pair
+func (kpi *KeyPairInfo) Matches(key []byte) (bool, error) {
+	fps, err := KeyFingerprints(key)
+	if err != nil {
+		return false, err
+	}
+	for _, fp := range fps {
+		if normalizeFingerprint(fp) == normalizeFingerprint(kpi.Fingerprint) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) CreateKeyPair(kpd *KeyPairDefinition) (*KeyPair, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateKeyPair",
+		NotableParams: map[string]string{
+			"name": kpd.Name,
+			"type": kpd.Type,
+		},
+	})
+	return &KeyPair{KeyPairInfo: KeyPairInfo{Name: kpd.Name}, PrivateKey: []byte{}}, nil
+}
+
+func (aws *TestingAWSService) ImportKeyPair(kpd *KeyPairDefinition) (*KeyPairInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ImportKeyPair",
+		NotableParams: map[string]string{
+			"name": kpd.Name,
+		},
+	})
+	return &KeyPairInfo{Name: kpd.Name}, nil
+}
+
+func (aws *TestingAWSService) GetKeyPairsInfo(names []string) ([]KeyPairInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetKeyPairsInfo",
+		NotableParams: map[string]string{
+			"names": fmt.Sprintf("%v", names),
+		},
+	})
+	return []KeyPairInfo{}, nil
+}
+
+func (aws *TestingAWSService) DeleteKeyPair(name string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteKeyPair",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return nil
+}
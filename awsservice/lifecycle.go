@@ -0,0 +1,237 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// protectedError marks an OperationNotPermitted error caused by an instance protection attribute (eg
+// "disableApiTermination") with kind
+func protectedError(err error, attribute string, kind error) error {
+	var ae *AWSError
+	if errors.As(err, &ae) && ae.Code == "OperationNotPermitted" && strings.Contains(ae.Message, attribute) {
+		ae.Kind = kind
+	}
+	return err
+}
+
+// RebootInstances requests a reboot of the instances. It returns without waiting for them to come back.
+func (aws *RealAWSService) RebootInstances(ids []string) error {
+	rii := ec2.RebootInstancesInput{
+		InstanceIds: stringSlicetoStringPointerSlice(ids),
+	}
+	_, err := aws.ec2.RebootInstances(&rii)
+	return wrapError("RebootInstances", err)
+}
+
+// HibernateInstances stops the instances with hibernation. They must have been launched with hibernation
+// enabled.
+func (aws *RealAWSService) HibernateInstances(ids []string) error {
+	sii := ec2.StopInstancesInput{
+		InstanceIds: stringSlicetoStringPointerSlice(ids),
+		Hibernate:   &True,
+	}
+	_, err := aws.ec2.StopInstances(&sii)
+	return protectedError(wrapError("HibernateInstances", err), "disableApiStop", ErrStopProtected)
+}
+
+func (aws *RealAWSService) modifyInstanceAttribute(op string, mii *ec2.ModifyInstanceAttributeInput) error {
+	_, err := aws.ec2.ModifyInstanceAttribute(mii)
+	return wrapError(op, err)
+}
+
+// ModifyInstanceType changes the type of a stopped instance (see ResizeInstance)
+func (aws *RealAWSService) ModifyInstanceType(id string, t string) error {
+	return aws.modifyInstanceAttribute("ModifyInstanceType", &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   &id,
+		InstanceType: &ec2.AttributeValue{Value: &t},
+	})
+}
+
+// SetTerminationProtection enables or disables termination through the API
+func (aws *RealAWSService) SetTerminationProtection(id string, enabled bool) error {
+	return aws.modifyInstanceAttribute("SetTerminationProtection", &ec2.ModifyInstanceAttributeInput{
+		InstanceId:            &id,
+		DisableApiTermination: &ec2.AttributeBooleanValue{Value: &enabled},
+	})
+}
+
+// SetStopProtection enables or disables stopping (and hibernating) through the API
+func (aws *RealAWSService) SetStopProtection(id string, enabled bool) error {
+	return aws.modifyInstanceAttribute("SetStopProtection", &ec2.ModifyInstanceAttributeInput{
+		InstanceId:     &id,
+		DisableApiStop: &ec2.AttributeBooleanValue{Value: &enabled},
+	})
+}
+
+// SetSourceDestCheck enables or disables source/destination checking (disable it for NAT instances and
+// other instances that route traffic)
+func (aws *RealAWSService) SetSourceDestCheck(id string, enabled bool) error {
+	return aws.modifyInstanceAttribute("SetSourceDestCheck", &ec2.ModifyInstanceAttributeInput{
+		InstanceId:      &id,
+		SourceDestCheck: &ec2.AttributeBooleanValue{Value: &enabled},
+	})
+}
+
+// waitForInstanceState polls an instance every interval until it reaches state, or the deadline passes
+func (aws *RealAWSService) waitForInstanceState(op string, id string, state string, interval time.Duration, deadline time.Time) (*InstanceInfo, error) {
+	for {
+		iis, err := aws.GetInstancesInfo([]string{id})
+		if err != nil {
+			return nil, err
+		}
+		if len(iis) == 0 {
+			return nil, notFoundError(op, "instance not found: %v", id)
+		}
+		ii := &iis[0]
+		switch ii.State {
+		case state:
+			return ii, nil
+		case ec2.InstanceStateNameTerminated, ec2.InstanceStateNameShuttingDown:
+			return ii, fmt.Errorf("instance %v is %v", id, ii.State)
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return ii, fmt.Errorf("timed out waiting for instance %v to be %v (%v)", id, state, ii.State)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ResizeInstance changes the type of an instance, stopping it first and starting it again afterwards if it
+// was running, polling every interval. It fails if the whole operation takes longer than timeout.
+// If the type change is rejected the instance is started again with its old type before the error is returned.
+// In dry-run mode only the stop is checked and recorded; the instance is not waited for, modified or restarted.
+func (aws *RealAWSService) ResizeInstance(id string, t string, interval time.Duration, timeout time.Duration) error {
+	if interval <= 0 {
+		return invalidDefinition("poll interval must be positive: %v", interval)
+	}
+	deadline := time.Now().Add(timeout)
+	iis, err := aws.GetInstancesInfo([]string{id})
+	if err != nil {
+		return err
+	}
+	if len(iis) == 0 {
+		return notFoundError("ResizeInstance", "instance not found: %v", id)
+	}
+	ii := iis[0]
+	if ii.Type == t {
+		return nil
+	}
+	running := ii.State == ec2.InstanceStateNameRunning || ii.State == ec2.InstanceStateNamePending
+	if running {
+		if ii.State == ec2.InstanceStateNamePending && aws.dryRun == nil {
+			if _, err := aws.waitForInstanceState("ResizeInstance", id, ec2.InstanceStateNameRunning, interval, deadline); err != nil {
+				return fmt.Errorf("error waiting for instance to start: %w", err)
+			}
+		}
+		if err := aws.StopInstances([]string{id}); err != nil {
+			return fmt.Errorf("error stopping instance: %w", err)
+		}
+	}
+	// the instance never stops in dry-run mode, and modifying a running instance would fail the check
+	if aws.dryRun != nil {
+		return nil
+	}
+	if ii.State != ec2.InstanceStateNameStopped {
+		if _, err := aws.waitForInstanceState("ResizeInstance", id, ec2.InstanceStateNameStopped, interval, deadline); err != nil {
+			return fmt.Errorf("error waiting for instance to stop: %w", err)
+		}
+	}
+	merr := aws.ModifyInstanceType(id, t)
+	if merr != nil {
+		merr = fmt.Errorf("error modifying instance type: %w", merr)
+		if !running {
+			return merr
+		}
+	}
+	if running {
+		if err := aws.StartInstances([]string{id}); err != nil {
+			return fmt.Errorf("error starting instance: %w", err)
+		}
+		if _, err := aws.waitForInstanceState("ResizeInstance", id, ec2.InstanceStateNameRunning, interval, deadline); err != nil {
+			return fmt.Errorf("error waiting for instance to start: %w", err)
+		}
+	}
+	return merr
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) RebootInstances(ids []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "RebootInstances",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) HibernateInstances(ids []string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "HibernateInstances",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) ModifyInstanceType(id string, t string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ModifyInstanceType",
+		NotableParams: map[string]string{
+			"id":   id,
+			"type": t,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) SetTerminationProtection(id string, enabled bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SetTerminationProtection",
+		NotableParams: map[string]string{
+			"id":      id,
+			"enabled": fmt.Sprintf("%v", enabled),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) SetStopProtection(id string, enabled bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SetStopProtection",
+		NotableParams: map[string]string{
+			"id":      id,
+			"enabled": fmt.Sprintf("%v", enabled),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) SetSourceDestCheck(id string, enabled bool) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "SetSourceDestCheck",
+		NotableParams: map[string]string{
+			"id":      id,
+			"enabled": fmt.Sprintf("%v", enabled),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) ResizeInstance(id string, t string, interval time.Duration, timeout time.Duration) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ResizeInstance",
+		NotableParams: map[string]string{
+			"id":   id,
+			"type": t,
+		},
+	})
+	return nil
+}
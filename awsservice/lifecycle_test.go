@@ -0,0 +1,142 @@
+package awsservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEC2Instance serves a single instance whose state and type change like a real one (transitions take
+// one DescribeInstances call)
+type fakeEC2Instance struct {
+	state      string
+	itype      string
+	actions    []string
+	rejectType string
+}
+
+func (f *fakeEC2Instance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	action := r.Form.Get("Action")
+	if action != "DescribeInstances" {
+		f.actions = append(f.actions, action)
+	}
+	if r.Form.Get("DryRun") == "true" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		w.Write([]byte(ec2DryRunResponse))
+		return
+	}
+	switch action {
+	case "DescribeInstances":
+		fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet><item><instanceId>i-1</instanceId>`+
+			`<instanceType>%v</instanceType><instanceState><name>%v</name></instanceState></item></instancesSet></item></reservationSet></DescribeInstancesResponse>`, f.itype, f.state)
+		switch f.state {
+		case "stopping":
+			f.state = "stopped"
+		case "pending":
+			f.state = "running"
+		}
+	case "StopInstances":
+		f.state = "stopping"
+		w.Write([]byte(`<StopInstancesResponse></StopInstancesResponse>`))
+	case "StartInstances":
+		f.state = "pending"
+		w.Write([]byte(`<StartInstancesResponse></StartInstancesResponse>`))
+	case "ModifyInstanceAttribute":
+		if f.state != "stopped" || r.Form.Get("InstanceType.Value") == f.rejectType {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<Response><Errors><Error><Code>IncorrectInstanceState</Code><Message>bad state</Message></Error></Errors><RequestID>req-1</RequestID></Response>`))
+			return
+		}
+		f.itype = r.Form.Get("InstanceType.Value")
+		w.Write([]byte(`<ModifyInstanceAttributeResponse><return>true</return></ModifyInstanceAttributeResponse>`))
+	case "TerminateInstances":
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<Response><Errors><Error><Code>OperationNotPermitted</Code><Message>The instance 'i-1' may not be terminated. Modify its 'disableApiTermination' instance attribute and try again.</Message></Error></Errors><RequestID>req-2</RequestID></Response>`))
+	}
+}
+
+func TestResizeInstance(t *testing.T) {
+	f := &fakeEC2Instance{state: "running", itype: "m5.large"}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if err := svc.ResizeInstance("i-1", "m5.xlarge", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("error resizing: %v", err)
+	}
+	if f.itype != "m5.xlarge" || f.state != "running" {
+		t.Fatalf("bad instance after resize: %+v", f)
+	}
+	if strings.Join(f.actions, ",") != "StopInstances,ModifyInstanceAttribute,StartInstances" {
+		t.Fatalf("bad actions: %v", f.actions)
+	}
+}
+
+func TestResizeInstanceRejected(t *testing.T) {
+	f := &fakeEC2Instance{state: "running", itype: "m5.large", rejectType: "p4d.24xlarge"}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	err := svc.ResizeInstance("i-1", "p4d.24xlarge", time.Millisecond, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "IncorrectInstanceState") {
+		t.Fatalf("expected modify error: %v", err)
+	}
+	if f.itype != "m5.large" || f.state != "running" {
+		t.Fatalf("instance should have been restarted with its old type: %+v", f)
+	}
+}
+
+func TestResizeInstanceStopped(t *testing.T) {
+	f := &fakeEC2Instance{state: "stopped", itype: "m5.large"}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if err := svc.ResizeInstance("i-1", "m5.xlarge", time.Millisecond, time.Second); err != nil {
+		t.Fatalf("error resizing: %v", err)
+	}
+	if f.itype != "m5.xlarge" || f.state != "stopped" || len(f.actions) != 1 {
+		t.Fatalf("stopped instance should only have been modified: %+v", f)
+	}
+}
+
+func TestResizeInstanceDryRun(t *testing.T) {
+	f := &fakeEC2Instance{state: "running", itype: "m5.large"}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	plan := &MutationPlan{}
+	svc.SetDryRun(plan)
+	if err := svc.ResizeInstance("i-1", "m5.xlarge", time.Millisecond, 20*time.Millisecond); err != nil {
+		t.Fatalf("error resizing: %v", err)
+	}
+	if f.itype != "m5.large" || f.state != "running" || strings.Join(f.actions, ",") != "StopInstances" {
+		t.Fatalf("dry run should only have checked the stop: %+v", f)
+	}
+	if ms := plan.Mutations(); len(ms) != 1 || ms[0].Operation != "StopInstances" || !ms[0].Checked {
+		t.Fatalf("bad plan: %v", plan)
+	}
+}
+
+func TestResizeInstanceInterval(t *testing.T) {
+	svc := newEndpointAWSService("http://127.0.0.1:1")
+	if err := svc.ResizeInstance("i-1", "m5.xlarge", 0, time.Second); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("zero interval should be rejected: %v", err)
+	}
+}
+
+func TestTerminateInstancesProtected(t *testing.T) {
+	ts := httptest.NewServer(&fakeEC2Instance{state: "running"})
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	err := svc.TerminateInstances([]string{"i-1"})
+	if !errors.Is(err, ErrTerminationProtected) {
+		t.Fatalf("expected termination protection error: %v", err)
+	}
+	if errors.Is(err, ErrStopProtected) {
+		t.Fatalf("should not be a stop protection error: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package awsservice
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AWSMetrics holds Prometheus metrics for AWS usage. Method metrics are labelled by AWSService method and
+// are recorded by NewInstrumentedAWSService for any AWSService; API metrics are labelled by AWS service and
+// operation and are only recorded by RealAWSService (see SetMetrics), as they count individual HTTP attempts.
+//
+// AWSMetrics is a prometheus.Collector: register it with a registry to export the metrics.
+type AWSMetrics struct {
+	calls       *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	apiRequests *prometheus.CounterVec
+	apiRetries  *prometheus.CounterVec
+	apiThrottle *prometheus.CounterVec
+}
+
+// NewAWSMetrics creates the metrics with names prefixed by namespace (eg "myapp" gives "myapp_aws_calls_total")
+func NewAWSMetrics(namespace string) *AWSMetrics {
+	return &AWSMetrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "calls_total",
+			Help:      "AWSService method calls.",
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "call_errors_total",
+			Help:      "AWSService method calls that returned an error, by AWS error code (empty for errors detected locally).",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "call_duration_seconds",
+			Help:      "AWSService method call latency, including retries.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300},
+		}, []string{"method"}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "api_requests_total",
+			Help:      "AWS API requests, excluding retries.",
+		}, []string{"service", "operation"}),
+		apiRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "api_retries_total",
+			Help:      "AWS API request retries made by the SDK.",
+		}, []string{"service", "operation"}),
+		apiThrottle: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "aws",
+			Name:      "api_throttles_total",
+			Help:      "AWS API request attempts rejected by throttling, whether or not they were retried.",
+		}, []string{"service", "operation"}),
+	}
+}
+
+func (m *AWSMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.calls, m.errors, m.duration, m.apiRequests, m.apiRetries, m.apiThrottle}
+}
+
+func (m *AWSMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *AWSMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *AWSMetrics) BeforeCall(c *AWSCall) {}
+
+func (m *AWSMetrics) AfterCall(c *AWSCall) {
+	m.calls.WithLabelValues(c.Method).Inc()
+	m.duration.WithLabelValues(c.Method).Observe(c.Duration.Seconds())
+	if c.Err != nil {
+		code := ""
+		var ae *AWSError
+		if errors.As(c.Err, &ae) {
+			code = ae.Code
+		}
+		m.errors.WithLabelValues(c.Method, code).Inc()
+	}
+}
+
+// NewInstrumentedAWSService decorates svc so every call is recorded in m. If svc is a *RealAWSService its
+// API request metrics are recorded too.
+func NewInstrumentedAWSService(svc AWSService, m *AWSMetrics) *WrappedAWSService {
+	if rs, ok := svc.(*RealAWSService); ok {
+		rs.SetMetrics(m)
+	}
+	return WrapAWSService(svc, m)
+}
+
+// SetMetrics records API request, retry and throttling metrics in m, or stops recording them if m is nil.
+// It must not be called concurrently with other methods.
+func (aws *RealAWSService) SetMetrics(m *AWSMetrics) {
+	aws.metrics = m
+}
+
+// metricsAttemptHandler runs after every attempt of a request
+func (aws *RealAWSService) metricsAttemptHandler(r *request.Request) {
+	m := aws.metrics
+	if m == nil || r.Error == nil {
+		return
+	}
+	status := 0
+	if r.HTTPResponse != nil {
+		status = r.HTTPResponse.StatusCode
+	}
+	if aerr, ok := r.Error.(awserr.Error); ok && classifyErrorCode(aerr.Code(), status) == ErrThrottled {
+		m.apiThrottle.WithLabelValues(r.ClientInfo.ServiceName, r.Operation.Name).Inc()
+	}
+}
+
+// metricsCompleteHandler runs once a request has finished, successfully or not
+func (aws *RealAWSService) metricsCompleteHandler(r *request.Request) {
+	m := aws.metrics
+	if m == nil {
+		return
+	}
+	m.apiRequests.WithLabelValues(r.ClientInfo.ServiceName, r.Operation.Name).Inc()
+	if r.RetryCount > 0 {
+		m.apiRetries.WithLabelValues(r.ClientInfo.ServiceName, r.Operation.Name).Add(float64(r.RetryCount))
+	}
+}
+
+func (aws *RealAWSService) addMetricsHandlers(h *request.Handlers) {
+	h.CompleteAttempt.PushBackNamed(request.NamedHandler{Name: "awsservice.MetricsAttempt", Fn: aws.metricsAttemptHandler})
+	h.Complete.PushBackNamed(request.NamedHandler{Name: "awsservice.MetricsComplete", Fn: aws.metricsCompleteHandler})
+}
@@ -0,0 +1,81 @@
+package awsservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ AWSService = &TestingAWSService{}
+
+const ec2ThrottleResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>req-3</RequestID></Response>`
+
+func TestAWSMetricsTestingService(t *testing.T) {
+	m := NewAWSMetrics("test")
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(m); err != nil {
+		t.Fatalf("error registering metrics: %v", err)
+	}
+	svc := NewInstrumentedAWSService(&TestingAWSService{}, m)
+	svc.TerminateInstances([]string{"i-1"})
+	svc.TerminateInstances([]string{"i-2"})
+	svc.CreateDNSRecord(&Route53RecordDefinition{Name: "a.example.com"})
+	if v := testutil.ToFloat64(m.calls.WithLabelValues("TerminateInstances")); v != 2 {
+		t.Fatalf("bad call count: %v", v)
+	}
+	if n := testutil.CollectAndCount(m.duration); n != 2 {
+		t.Fatalf("expected latency histograms for 2 methods: %v", n)
+	}
+	if n := testutil.CollectAndCount(m.errors); n != 0 {
+		t.Fatalf("expected no errors: %v", n)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Fatalf("error gathering: %v", err)
+	}
+}
+
+func TestAWSMetricsRealService(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(ec2ThrottleResponse))
+	}))
+	defer ts.Close()
+	s := session.New(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(ts.URL),
+		Retryer: client.DefaultRetryer{
+			NumMaxRetries:    2,
+			MinRetryDelay:    time.Millisecond,
+			MaxRetryDelay:    time.Millisecond,
+			MinThrottleDelay: time.Millisecond,
+			MaxThrottleDelay: time.Millisecond,
+		},
+	})
+	m := NewAWSMetrics("test")
+	svc := NewInstrumentedAWSService(newRealAWSService(s), m)
+	if err := svc.TerminateInstances([]string{"i-1"}); !IsThrottled(err) {
+		t.Fatalf("expected throttling error: %v", err)
+	}
+	if v := testutil.ToFloat64(m.errors.WithLabelValues("TerminateInstances", "RequestLimitExceeded")); v != 1 {
+		t.Fatalf("bad error count: %v", v)
+	}
+	if v := testutil.ToFloat64(m.apiRequests.WithLabelValues("ec2", "TerminateInstances")); v != 1 {
+		t.Fatalf("bad request count: %v", v)
+	}
+	if v := testutil.ToFloat64(m.apiRetries.WithLabelValues("ec2", "TerminateInstances")); v != 2 {
+		t.Fatalf("bad retry count: %v", v)
+	}
+	if v := testutil.ToFloat64(m.apiThrottle.WithLabelValues("ec2", "TerminateInstances")); v != 3 {
+		t.Fatalf("bad throttle count: %v", v)
+	}
+}
@@ -0,0 +1,372 @@
+package awsservice
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type VPCInfo struct {
+	ID          string
+	CIDR        string
+	CIDRs       []string // All associated IPv4 blocks, including CIDR
+	IPv6CIDRs   []string
+	State       string
+	IsDefault   bool
+	DHCPOptions string
+	Tags        map[string]string
+}
+
+type RouteInfo struct {
+	Destination string // CIDR (IPv4 or IPv6) or prefix list ID
+	Target      string // Gateway, NAT gateway, instance, network interface, peering connection or transit gateway ID
+	State       string
+	Origin      string
+}
+
+type RouteTableInfo struct {
+	ID      string
+	VPC     string
+	Main    bool
+	Subnets []string // Explicitly associated subnets
+	Routes  []RouteInfo
+	Tags    map[string]string
+}
+
+type InternetGatewayInfo struct {
+	ID   string
+	VPCs []string
+	Tags map[string]string
+}
+
+type NATGatewayInfo struct {
+	ID               string
+	VPC              string
+	Subnet           string
+	State            string
+	ConnectivityType string
+	PublicIPs        []string
+	PrivateIPs       []string
+	Tags             map[string]string
+}
+
+type VPCEndpointInfo struct {
+	ID                string
+	VPC               string
+	ServiceName       string
+	Type              string
+	State             string
+	RouteTables       []string
+	Subnets           []string
+	SecurityGroups    []string
+	PrivateDNSEnabled bool
+	Tags              map[string]string
+}
+
+func vpcInfo(v *ec2.Vpc) VPCInfo {
+	vi := VPCInfo{
+		ID:          drefStringPtr(v.VpcId),
+		CIDR:        drefStringPtr(v.CidrBlock),
+		State:       drefStringPtr(v.State),
+		DHCPOptions: drefStringPtr(v.DhcpOptionsId),
+		Tags:        ec2TagMap(v.Tags),
+		CIDRs:       []string{},
+		IPv6CIDRs:   []string{},
+	}
+	if v.IsDefault != nil {
+		vi.IsDefault = *v.IsDefault
+	}
+	for _, a := range v.CidrBlockAssociationSet {
+		vi.CIDRs = append(vi.CIDRs, drefStringPtr(a.CidrBlock))
+	}
+	for _, a := range v.Ipv6CidrBlockAssociationSet {
+		vi.IPv6CIDRs = append(vi.IPv6CIDRs, drefStringPtr(a.Ipv6CidrBlock))
+	}
+	return vi
+}
+
+func (aws *RealAWSService) describeVPCs(dvi *ec2.DescribeVpcsInput) ([]VPCInfo, error) {
+	result := []VPCInfo{}
+	err := aws.ec2.DescribeVpcsPages(dvi, func(page *ec2.DescribeVpcsOutput, last bool) bool {
+		for _, v := range page.Vpcs {
+			result = append(result, vpcInfo(v))
+		}
+		return true
+	})
+	return result, err
+}
+
+func (aws *RealAWSService) GetVPCsInfo(ids []string) ([]VPCInfo, error) {
+	vpcs, err := aws.describeVPCs(&ec2.DescribeVpcsInput{
+		VpcIds: stringSlicetoStringPointerSlice(ids),
+	})
+	return vpcs, wrapError("GetVPCsInfo", err)
+}
+
+// FindVPCs returns the VPCs carrying all of the given tags
+func (aws *RealAWSService) FindVPCs(tags map[string]string) ([]VPCInfo, error) {
+	vpcs, err := aws.describeVPCs(&ec2.DescribeVpcsInput{
+		Filters: ec2Filters(nil, tags),
+	})
+	return vpcs, wrapError("FindVPCs", err)
+}
+
+func routeTableInfo(rt *ec2.RouteTable) RouteTableInfo {
+	rti := RouteTableInfo{
+		ID:      drefStringPtr(rt.RouteTableId),
+		VPC:     drefStringPtr(rt.VpcId),
+		Subnets: []string{},
+		Routes:  []RouteInfo{},
+		Tags:    ec2TagMap(rt.Tags),
+	}
+	for _, a := range rt.Associations {
+		if a.Main != nil && *a.Main {
+			rti.Main = true
+		}
+		if a.SubnetId != nil {
+			rti.Subnets = append(rti.Subnets, *a.SubnetId)
+		}
+	}
+	for _, r := range rt.Routes {
+		ri := RouteInfo{
+			State:  drefStringPtr(r.State),
+			Origin: drefStringPtr(r.Origin),
+		}
+		for _, d := range []*string{r.DestinationCidrBlock, r.DestinationIpv6CidrBlock, r.DestinationPrefixListId} {
+			if d != nil {
+				ri.Destination = *d
+				break
+			}
+		}
+		for _, t := range []*string{r.GatewayId, r.NatGatewayId, r.InstanceId, r.NetworkInterfaceId, r.VpcPeeringConnectionId, r.TransitGatewayId, r.EgressOnlyInternetGatewayId} {
+			if t != nil {
+				ri.Target = *t
+				break
+			}
+		}
+		rti.Routes = append(rti.Routes, ri)
+	}
+	return rti
+}
+
+func (aws *RealAWSService) describeRouteTables(drti *ec2.DescribeRouteTablesInput) ([]RouteTableInfo, error) {
+	result := []RouteTableInfo{}
+	err := aws.ec2.DescribeRouteTablesPages(drti, func(page *ec2.DescribeRouteTablesOutput, last bool) bool {
+		for _, rt := range page.RouteTables {
+			result = append(result, routeTableInfo(rt))
+		}
+		return true
+	})
+	return result, err
+}
+
+// GetRouteTables returns all route tables in a VPC. The VPC ID is required.
+func (aws *RealAWSService) GetRouteTables(vpc string) ([]RouteTableInfo, error) {
+	if vpc == "" {
+		return []RouteTableInfo{}, invalidDefinition("VPC ID is required")
+	}
+	rts, err := aws.describeRouteTables(&ec2.DescribeRouteTablesInput{
+		Filters: ec2Filters(map[string]string{"vpc-id": vpc}, nil),
+	})
+	return rts, wrapError("GetRouteTables", err)
+}
+
+// GetSubnetRouteTable returns the route table in effect for a subnet: its explicitly associated table, or the VPC main table
+func (aws *RealAWSService) GetSubnetRouteTable(subnet string) (*RouteTableInfo, error) {
+	result := &RouteTableInfo{}
+	if subnet == "" {
+		return result, invalidDefinition("subnet ID is required")
+	}
+	rts, err := aws.describeRouteTables(&ec2.DescribeRouteTablesInput{
+		Filters: ec2Filters(map[string]string{"association.subnet-id": subnet}, nil),
+	})
+	if err != nil {
+		return result, wrapError("GetSubnetRouteTable", err)
+	}
+	if len(rts) == 0 {
+		si, err := aws.GetSubnetInfo(subnet)
+		if err != nil {
+			return result, err
+		}
+		rts, err = aws.describeRouteTables(&ec2.DescribeRouteTablesInput{
+			Filters: ec2Filters(map[string]string{"vpc-id": si.VPC, "association.main": "true"}, nil),
+		})
+		if err != nil {
+			return result, wrapError("GetSubnetRouteTable", err)
+		}
+		if len(rts) == 0 {
+			return result, notFoundError("GetSubnetRouteTable", "no route table found for subnet: %v", subnet)
+		}
+	}
+	*result = rts[0]
+	return result, nil
+}
+
+// GetInternetGateways returns the internet gateways attached to a VPC. The VPC ID is required.
+func (aws *RealAWSService) GetInternetGateways(vpc string) ([]InternetGatewayInfo, error) {
+	result := []InternetGatewayInfo{}
+	if vpc == "" {
+		return result, invalidDefinition("VPC ID is required")
+	}
+	digi := &ec2.DescribeInternetGatewaysInput{
+		Filters: ec2Filters(map[string]string{"attachment.vpc-id": vpc}, nil),
+	}
+	err := aws.ec2.DescribeInternetGatewaysPages(digi, func(page *ec2.DescribeInternetGatewaysOutput, last bool) bool {
+		for _, igw := range page.InternetGateways {
+			ii := InternetGatewayInfo{
+				ID:   drefStringPtr(igw.InternetGatewayId),
+				VPCs: []string{},
+				Tags: ec2TagMap(igw.Tags),
+			}
+			for _, a := range igw.Attachments {
+				ii.VPCs = append(ii.VPCs, drefStringPtr(a.VpcId))
+			}
+			result = append(result, ii)
+		}
+		return true
+	})
+	return result, wrapError("GetInternetGateways", err)
+}
+
+// GetNATGateways returns the NAT gateways in a VPC. The VPC ID is required.
+func (aws *RealAWSService) GetNATGateways(vpc string) ([]NATGatewayInfo, error) {
+	result := []NATGatewayInfo{}
+	if vpc == "" {
+		return result, invalidDefinition("VPC ID is required")
+	}
+	dngi := &ec2.DescribeNatGatewaysInput{
+		Filter: ec2Filters(map[string]string{"vpc-id": vpc}, nil),
+	}
+	err := aws.ec2.DescribeNatGatewaysPages(dngi, func(page *ec2.DescribeNatGatewaysOutput, last bool) bool {
+		for _, ngw := range page.NatGateways {
+			ni := NATGatewayInfo{
+				ID:               drefStringPtr(ngw.NatGatewayId),
+				VPC:              drefStringPtr(ngw.VpcId),
+				Subnet:           drefStringPtr(ngw.SubnetId),
+				State:            drefStringPtr(ngw.State),
+				ConnectivityType: drefStringPtr(ngw.ConnectivityType),
+				PublicIPs:        []string{},
+				PrivateIPs:       []string{},
+				Tags:             ec2TagMap(ngw.Tags),
+			}
+			for _, a := range ngw.NatGatewayAddresses {
+				if a.PublicIp != nil {
+					ni.PublicIPs = append(ni.PublicIPs, *a.PublicIp)
+				}
+				if a.PrivateIp != nil {
+					ni.PrivateIPs = append(ni.PrivateIPs, *a.PrivateIp)
+				}
+			}
+			result = append(result, ni)
+		}
+		return true
+	})
+	return result, wrapError("GetNATGateways", err)
+}
+
+// GetVPCEndpoints returns the VPC endpoints in a VPC. The VPC ID is required.
+func (aws *RealAWSService) GetVPCEndpoints(vpc string) ([]VPCEndpointInfo, error) {
+	result := []VPCEndpointInfo{}
+	if vpc == "" {
+		return result, invalidDefinition("VPC ID is required")
+	}
+	dvei := &ec2.DescribeVpcEndpointsInput{
+		Filters: ec2Filters(map[string]string{"vpc-id": vpc}, nil),
+	}
+	err := aws.ec2.DescribeVpcEndpointsPages(dvei, func(page *ec2.DescribeVpcEndpointsOutput, last bool) bool {
+		for _, ep := range page.VpcEndpoints {
+			ei := VPCEndpointInfo{
+				ID:             drefStringPtr(ep.VpcEndpointId),
+				VPC:            drefStringPtr(ep.VpcId),
+				ServiceName:    drefStringPtr(ep.ServiceName),
+				Type:           drefStringPtr(ep.VpcEndpointType),
+				State:          drefStringPtr(ep.State),
+				RouteTables:    stringPointerSlicetoStringSlice(ep.RouteTableIds),
+				Subnets:        stringPointerSlicetoStringSlice(ep.SubnetIds),
+				SecurityGroups: []string{},
+				Tags:           ec2TagMap(ep.Tags),
+			}
+			if ep.PrivateDnsEnabled != nil {
+				ei.PrivateDNSEnabled = *ep.PrivateDnsEnabled
+			}
+			for _, g := range ep.Groups {
+				ei.SecurityGroups = append(ei.SecurityGroups, drefStringPtr(g.GroupId))
+			}
+			result = append(result, ei)
+		}
+		return true
+	})
+	return result, wrapError("GetVPCEndpoints", err)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) GetVPCsInfo(ids []string) ([]VPCInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetVPCsInfo",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []VPCInfo{}, nil
+}
+
+func (aws *TestingAWSService) FindVPCs(tags map[string]string) ([]VPCInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindVPCs",
+		NotableParams: map[string]string{
+			"tags": fmt.Sprintf("%v", tags),
+		},
+	})
+	return []VPCInfo{}, nil
+}
+
+func (aws *TestingAWSService) GetRouteTables(vpc string) ([]RouteTableInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetRouteTables",
+		NotableParams: map[string]string{
+			"vpc": vpc,
+		},
+	})
+	return []RouteTableInfo{}, nil
+}
+
+func (aws *TestingAWSService) GetSubnetRouteTable(subnet string) (*RouteTableInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetSubnetRouteTable",
+		NotableParams: map[string]string{
+			"subnet": subnet,
+		},
+	})
+	return &RouteTableInfo{}, nil
+}
+
+func (aws *TestingAWSService) GetInternetGateways(vpc string) ([]InternetGatewayInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInternetGateways",
+		NotableParams: map[string]string{
+			"vpc": vpc,
+		},
+	})
+	return []InternetGatewayInfo{}, nil
+}
+
+func (aws *TestingAWSService) GetNATGateways(vpc string) ([]NATGatewayInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetNATGateways",
+		NotableParams: map[string]string{
+			"vpc": vpc,
+		},
+	})
+	return []NATGatewayInfo{}, nil
+}
+
+func (aws *TestingAWSService) GetVPCEndpoints(vpc string) ([]VPCEndpointInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetVPCEndpoints",
+		NotableParams: map[string]string{
+			"vpc": vpc,
+		},
+	})
+	return []VPCEndpointInfo{}, nil
+}
@@ -0,0 +1,126 @@
+package awsservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestRouteTableInfo(t *testing.T) {
+	rti := routeTableInfo(&ec2.RouteTable{
+		RouteTableId: aws.String("rtb-1"),
+		VpcId:        aws.String("vpc-1"),
+		Associations: []*ec2.RouteTableAssociation{
+			{Main: aws.Bool(true)},
+			{Main: aws.Bool(false), SubnetId: aws.String("subnet-1")},
+		},
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local"), State: aws.String("active"), Origin: aws.String("CreateRouteTable")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1"), State: aws.String("blackhole")},
+			{DestinationIpv6CidrBlock: aws.String("::/0"), EgressOnlyInternetGatewayId: aws.String("eigw-1")},
+			{DestinationPrefixListId: aws.String("pl-1"), GatewayId: aws.String("vpce-1")},
+		},
+		Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("private")}},
+	})
+	if rti.ID != "rtb-1" || rti.VPC != "vpc-1" || !rti.Main || rti.Tags["Name"] != "private" {
+		t.Fatalf("bad route table: %+v", rti)
+	}
+	if len(rti.Subnets) != 1 || rti.Subnets[0] != "subnet-1" {
+		t.Fatalf("bad subnets: %v", rti.Subnets)
+	}
+	expected := []RouteInfo{
+		{Destination: "10.0.0.0/16", Target: "local", State: "active", Origin: "CreateRouteTable"},
+		{Destination: "0.0.0.0/0", Target: "nat-1", State: "blackhole"},
+		{Destination: "::/0", Target: "eigw-1"},
+		{Destination: "pl-1", Target: "vpce-1"},
+	}
+	if len(rti.Routes) != len(expected) {
+		t.Fatalf("bad routes: %+v", rti.Routes)
+	}
+	for i := range expected {
+		if rti.Routes[i] != expected[i] {
+			t.Fatalf("bad route %v: %+v (expected: %+v)", i, rti.Routes[i], expected[i])
+		}
+	}
+}
+
+func TestVPCInfo(t *testing.T) {
+	vi := vpcInfo(&ec2.Vpc{
+		VpcId:     aws.String("vpc-1"),
+		CidrBlock: aws.String("10.0.0.0/16"),
+		IsDefault: aws.Bool(true),
+		CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+			{CidrBlock: aws.String("10.0.0.0/16")},
+			{CidrBlock: aws.String("10.1.0.0/16")},
+		},
+		Ipv6CidrBlockAssociationSet: []*ec2.VpcIpv6CidrBlockAssociation{{Ipv6CidrBlock: aws.String("2600:1f14::/56")}},
+	})
+	if vi.ID != "vpc-1" || vi.CIDR != "10.0.0.0/16" || !vi.IsDefault {
+		t.Fatalf("bad VPC: %+v", vi)
+	}
+	if strings.Join(vi.CIDRs, ",") != "10.0.0.0/16,10.1.0.0/16" || strings.Join(vi.IPv6CIDRs, ",") != "2600:1f14::/56" {
+		t.Fatalf("bad CIDRs: %v %v", vi.CIDRs, vi.IPv6CIDRs)
+	}
+}
+
+// filterNames returns the names of the EC2 filters in a query request
+func filterNames(r *http.Request) []string {
+	names := []string{}
+	for k, v := range r.Form {
+		if strings.HasPrefix(k, "Filter.") && strings.HasSuffix(k, ".Name") {
+			names = append(names, v...)
+		}
+	}
+	return names
+}
+
+func TestGetSubnetRouteTableMainFallback(t *testing.T) {
+	actions := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action := r.Form.Get("Action")
+		actions = append(actions, action)
+		switch {
+		case action == "DescribeSubnets":
+			w.Write([]byte(`<DescribeSubnetsResponse><subnetSet><item><subnetId>subnet-1</subnetId><vpcId>vpc-1</vpcId></item></subnetSet></DescribeSubnetsResponse>`))
+		case action == "DescribeRouteTables" && stringInSlice("association.main", filterNames(r)):
+			if r.Form.Get("Filter.1.Value.1") != "vpc-1" && r.Form.Get("Filter.2.Value.1") != "vpc-1" {
+				t.Errorf("main route table should be looked up in the subnet's VPC: %v", r.Form)
+			}
+			w.Write([]byte(`<DescribeRouteTablesResponse><routeTableSet><item><routeTableId>rtb-main</routeTableId><vpcId>vpc-1</vpcId>` +
+				`<associationSet><item><main>true</main></item></associationSet></item></routeTableSet></DescribeRouteTablesResponse>`))
+		default:
+			w.Write([]byte(`<DescribeRouteTablesResponse><routeTableSet></routeTableSet></DescribeRouteTablesResponse>`))
+		}
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	rti, err := svc.GetSubnetRouteTable("subnet-1")
+	if err != nil {
+		t.Fatalf("error getting route table: %v", err)
+	}
+	if rti.ID != "rtb-main" || !rti.Main || len(rti.Subnets) != 0 {
+		t.Fatalf("should have fallen back to the main route table: %+v", rti)
+	}
+	if strings.Join(actions, " ") != "DescribeRouteTables DescribeSubnets DescribeRouteTables" {
+		t.Fatalf("bad actions: %v", actions)
+	}
+}
+
+func TestNetworkRequiresVPC(t *testing.T) {
+	svc := newEndpointAWSService("http://127.0.0.1:1")
+	if _, err := svc.GetRouteTables(""); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("empty VPC should be rejected: %v", err)
+	}
+	if _, err := svc.GetNATGateways(""); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("empty VPC should be rejected: %v", err)
+	}
+	if _, err := svc.GetSubnetRouteTable(""); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("empty subnet should be rejected: %v", err)
+	}
+}
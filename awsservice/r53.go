@@ -1,33 +1,60 @@
 package awsservice
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/service/route53"
 )
 
 type Route53RecordDefinition struct {
-	ZoneID string
-	Name   string
-	Value  string
-	Type   string
-	TTL    int64
+	ZoneID      string
+	Name        string
+	Value       string
+	Type        string
+	TTL         int64
+	ExtraValues []string            // Further values of a multi-value record set, changed together with Value
+	Alias       *Route53AliasTarget // Set instead of Value and TTL for alias records
+}
+
+// Route53AliasTarget is the AWS resource (or other record in the zone) that an alias record points at
+type Route53AliasTarget struct {
+	ZoneID               string // Hosted zone of the target, eg the canonical zone of a load balancer
+	DNSName              string
+	EvaluateTargetHealth bool
+}
+
+// Values returns Value followed by any ExtraValues
+func (rd *Route53RecordDefinition) Values() []string {
+	return append([]string{rd.Value}, rd.ExtraValues...)
 }
 
 func (aws *RealAWSService) executeR53Action(a string, rd *Route53RecordDefinition) error {
+	rrs := []*route53.ResourceRecord{}
+	for _, v := range rd.Values() {
+		cv := v
+		rrs = append(rrs, &route53.ResourceRecord{Value: &cv})
+	}
+	rrset := &route53.ResourceRecordSet{
+		Name:            &rd.Name,
+		Type:            &rd.Type,
+		ResourceRecords: rrs,
+		TTL:             &rd.TTL,
+	}
+	if rd.Alias != nil {
+		rrset.ResourceRecords = nil
+		rrset.TTL = nil
+		rrset.AliasTarget = &route53.AliasTarget{
+			HostedZoneId:         &rd.Alias.ZoneID,
+			DNSName:              &rd.Alias.DNSName,
+			EvaluateTargetHealth: &rd.Alias.EvaluateTargetHealth,
+		}
+	}
 	param := &route53.ChangeResourceRecordSetsInput{
 		ChangeBatch: &route53.ChangeBatch{
 			Changes: []*route53.Change{
 				{
-					Action: &a,
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: &rd.Name,
-						Type: &rd.Type,
-						ResourceRecords: []*route53.ResourceRecord{
-							{
-								Value: &rd.Value,
-							},
-						},
-						TTL: &rd.TTL,
-					},
+					Action:            &a,
+					ResourceRecordSet: rrset,
 				},
 			},
 		},
@@ -38,11 +65,80 @@ func (aws *RealAWSService) executeR53Action(a string, rd *Route53RecordDefinitio
 }
 
 func (aws *RealAWSService) CreateDNSRecord(rd *Route53RecordDefinition) error {
-	return aws.executeR53Action("CREATE", rd)
+	return wrapError("CreateDNSRecord", aws.executeR53Action("CREATE", rd))
 }
 
+// DeleteDNSRecord deletes a record set. Route 53 only deletes a set when every value is given, so
+// multi-value sets need their other values in ExtraValues (see DNSRecordSets).
 func (aws *RealAWSService) DeleteDNSRecord(rd *Route53RecordDefinition) error {
-	return aws.executeR53Action("DELETE", rd)
+	return wrapError("DeleteDNSRecord", aws.executeR53Action("DELETE", rd))
+}
+
+// UpsertDNSRecord creates the record, or replaces the value and TTL of an existing record with the same name and type
+func (aws *RealAWSService) UpsertDNSRecord(rd *Route53RecordDefinition) error {
+	return wrapError("UpsertDNSRecord", aws.executeR53Action("UPSERT", rd))
+}
+
+// GetDNSRecords returns the records of every type with the given name in a zone. Names are compared
+// without the trailing dot. Records with several values are returned once per value; alias records are
+// returned once, with Alias set and no Value.
+func (aws *RealAWSService) GetDNSRecords(zoneID string, name string) ([]Route53RecordDefinition, error) {
+	result := []Route53RecordDefinition{}
+	want := strings.TrimSuffix(name, ".")
+	lrrsi := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    &zoneID,
+		StartRecordName: &name,
+	}
+	err := aws.r53c.ListResourceRecordSetsPages(lrrsi, func(page *route53.ListResourceRecordSetsOutput, last bool) bool {
+		for _, rrs := range page.ResourceRecordSets {
+			// records are returned in name order starting at name, so stop at the first other name
+			if strings.TrimSuffix(drefStringPtr(rrs.Name), ".") != want {
+				return false
+			}
+			if at := rrs.AliasTarget; at != nil {
+				result = append(result, Route53RecordDefinition{
+					ZoneID: zoneID,
+					Name:   drefStringPtr(rrs.Name),
+					Type:   drefStringPtr(rrs.Type),
+					Alias: &Route53AliasTarget{
+						ZoneID:               drefStringPtr(at.HostedZoneId),
+						DNSName:              drefStringPtr(at.DNSName),
+						EvaluateTargetHealth: at.EvaluateTargetHealth != nil && *at.EvaluateTargetHealth,
+					},
+				})
+			}
+			for _, rr := range rrs.ResourceRecords {
+				result = append(result, Route53RecordDefinition{
+					ZoneID: zoneID,
+					Name:   drefStringPtr(rrs.Name),
+					Value:  drefStringPtr(rr.Value),
+					Type:   drefStringPtr(rrs.Type),
+					TTL:    drefInt64Ptr(rrs.TTL),
+				})
+			}
+		}
+		return true
+	})
+	return result, wrapError("GetDNSRecords", err)
+}
+
+// DNSRecordSets groups records as returned by GetDNSRecords (one per value) into one record per name and
+// type, with the further values in ExtraValues, so that each can be deleted with a single DeleteDNSRecord
+func DNSRecordSets(rds []Route53RecordDefinition) []Route53RecordDefinition {
+	result := []Route53RecordDefinition{}
+	index := map[string]int{}
+	for _, rd := range rds {
+		k := rd.ZoneID + "/" + strings.TrimSuffix(rd.Name, ".") + "/" + rd.Type
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(result)
+			rd.ExtraValues = append([]string{}, rd.ExtraValues...)
+			result = append(result, rd)
+			continue
+		}
+		result[i].ExtraValues = append(result[i].ExtraValues, rd.Values()...)
+	}
+	return result
 }
 
 // Testing mocks
@@ -60,10 +156,32 @@ func (aws *TestingAWSService) CreateDNSRecord(rd *Route53RecordDefinition) error
 
 func (aws *TestingAWSService) DeleteDNSRecord(rd *Route53RecordDefinition) error {
 	aws.Log = append(aws.Log, AWSActionLog{
-		Action: "CreateDNSRecord",
+		Action: "DeleteDNSRecord",
 		NotableParams: map[string]string{
 			"name": rd.Name,
 		},
 	})
 	return nil
 }
+
+func (aws *TestingAWSService) UpsertDNSRecord(rd *Route53RecordDefinition) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "UpsertDNSRecord",
+		NotableParams: map[string]string{
+			"name":  rd.Name,
+			"value": rd.Value,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetDNSRecords(zoneID string, name string) ([]Route53RecordDefinition, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetDNSRecords",
+		NotableParams: map[string]string{
+			"zone_id": zoneID,
+			"name":    name,
+		},
+	})
+	return []Route53RecordDefinition{}, nil
+}
@@ -0,0 +1,110 @@
+package awsservice
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const r53ChangeResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<ChangeResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status><SubmittedAt>2026-10-19T12:00:00Z</SubmittedAt></ChangeInfo></ChangeResourceRecordSetsResponse>`
+
+func TestDNSRecordSets(t *testing.T) {
+	sets := DNSRecordSets([]Route53RecordDefinition{
+		{ZoneID: "Z1", Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.0.1"},
+		{ZoneID: "Z1", Name: "web.example.com.", Type: "TXT", TTL: 60, Value: `"v=1"`},
+		{ZoneID: "Z1", Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.0.2"},
+	})
+	if len(sets) != 2 || sets[0].Type != "A" || strings.Join(sets[0].Values(), ",") != "10.0.0.1,10.0.0.2" || len(sets[1].ExtraValues) != 0 {
+		t.Fatalf("bad record sets: %+v", sets)
+	}
+	if len(DNSRecordSets([]Route53RecordDefinition{})) != 0 {
+		t.Fatalf("no records should have no record sets")
+	}
+}
+
+func TestDeleteDNSRecordSet(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.Write([]byte(r53ChangeResponse))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	rd := &Route53RecordDefinition{ZoneID: "Z1", Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.0.1", ExtraValues: []string{"10.0.0.2"}}
+	if err := svc.DeleteDNSRecord(rd); err != nil {
+		t.Fatalf("error deleting record set: %v", err)
+	}
+	if len(requests) != 1 || strings.Count(requests[0], "<Change>") != 1 ||
+		!strings.Contains(requests[0], "<Value>10.0.0.1</Value>") || !strings.Contains(requests[0], "<Value>10.0.0.2</Value>") {
+		t.Fatalf("record set should be deleted in a single change: %v", requests)
+	}
+}
+
+const r53ListAliasResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ResourceRecordSets>` +
+	`<ResourceRecordSet><Name>web.example.com.</Name><Type>A</Type><AliasTarget><HostedZoneId>Z2</HostedZoneId>` +
+	`<DNSName>lb.example.</DNSName><EvaluateTargetHealth>true</EvaluateTargetHealth></AliasTarget></ResourceRecordSet>` +
+	`<ResourceRecordSet><Name>zz.example.com.</Name><Type>A</Type><TTL>60</TTL><ResourceRecords><ResourceRecord><Value>10.0.0.9</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>` +
+	`</ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListResourceRecordSetsResponse>`
+
+func TestDNSAliasRecords(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(r53ListAliasResponse))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.Write([]byte(r53ChangeResponse))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	rds, err := svc.GetDNSRecords("Z1", "web.example.com")
+	if err != nil {
+		t.Fatalf("error getting records: %v", err)
+	}
+	if len(rds) != 1 || rds[0].Alias == nil || *rds[0].Alias != (Route53AliasTarget{ZoneID: "Z2", DNSName: "lb.example.", EvaluateTargetHealth: true}) {
+		t.Fatalf("bad alias records: %+v", rds)
+	}
+	if err := svc.DeleteDNSRecord(&rds[0]); err != nil {
+		t.Fatalf("error deleting alias record: %v", err)
+	}
+	if len(requests) != 1 || !strings.Contains(requests[0], "<DNSName>lb.example.</DNSName>") ||
+		strings.Contains(requests[0], "<TTL>") || strings.Contains(requests[0], "<ResourceRecords>") {
+		t.Fatalf("bad alias delete: %v", requests)
+	}
+}
+
+func TestReplayDNSRecords(t *testing.T) {
+	svc, rec, err := NewReplayAWSService("testdata/route53_list_records.json")
+	if err != nil {
+		t.Fatalf("error loading recording: %v", err)
+	}
+	rds, err := svc.GetDNSRecords("Z0123456789ABCDEFGHIJ", "web.example.com")
+	if err != nil {
+		t.Fatalf("error getting records: %v", err)
+	}
+	expected := []Route53RecordDefinition{
+		{ZoneID: "Z0123456789ABCDEFGHIJ", Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.1.12"},
+		{ZoneID: "Z0123456789ABCDEFGHIJ", Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.2.34"},
+		{ZoneID: "Z0123456789ABCDEFGHIJ", Name: "web.example.com.", Type: "AAAA", Alias: &Route53AliasTarget{
+			ZoneID:  "Z1H1FL5HABSF5",
+			DNSName: "dualstack.web-lb-1234567890.us-west-2.elb.amazonaws.com.",
+		}},
+	}
+	if !reflect.DeepEqual(rds, expected) {
+		t.Fatalf("bad records: %+v", rds)
+	}
+	if _, err := svc.GetDNSRecords("Z9999999999999999999", "web.example.com"); !IsNotFound(err) {
+		t.Fatalf("expected not found error: %v", err)
+	}
+	if n := len(rec.Unreplayed()); n != 0 {
+		t.Fatalf("expected every interaction to be replayed: %v", n)
+	}
+}
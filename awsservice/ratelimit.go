@@ -0,0 +1,229 @@
+package awsservice
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a token bucket: Rate requests per second on average, with bursts of up to Burst requests
+type RateLimit struct {
+	Rate  float64
+	Burst int // Optional (default: 1)
+}
+
+// RateLimitConfig limits the AWS API requests made by a RealAWSService. Each request (and each retry) takes a
+// token from the most specific bucket configured for it: its operation, then its service, then the default.
+// Service and operation buckets are shared by every request they match; the default gives each operation its
+// own bucket. Requests with no bucket are not limited.
+//
+// When AWS throttles a request the bucket's rate is cut by ThrottleBackoff, and each successful request
+// afterwards raises it by RecoveryFactor until the configured rate is restored.
+type RateLimitConfig struct {
+	Default         *RateLimit
+	Services        map[string]RateLimit // By service name, eg "ec2"
+	Operations      map[string]RateLimit // By service and operation, eg "ec2.CreateTags"
+	ThrottleBackoff float64              // Optional (default: 0.5)
+	RecoveryFactor  float64              // Optional (default: 1.05)
+	MinRate         float64              // Optional floor for adaptive slow-down, capped at each configured rate (default: 0.1/s)
+}
+
+const (
+	defaultThrottleBackoff = 0.5
+	defaultRecoveryFactor  = 1.05
+	defaultMinRate         = 0.1
+)
+
+// DefaultRateLimitConfig stays below the documented EC2 API request rate limits, leaving headroom for other
+// clients in the account
+func DefaultRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Default: &RateLimit{Rate: 10, Burst: 20},
+		Services: map[string]RateLimit{
+			"route53": {Rate: 4, Burst: 5},
+		},
+		Operations: map[string]RateLimit{
+			"ec2.RunInstances":       {Rate: 2, Burst: 5},
+			"ec2.TerminateInstances": {Rate: 2, Burst: 5},
+			"ec2.CreateTags":         {Rate: 5, Burst: 10},
+			"ec2.DeleteTags":         {Rate: 5, Burst: 10},
+		},
+	}
+}
+
+// Validate checks the config
+func (rlc *RateLimitConfig) Validate() error {
+	ve := &ValidationError{}
+	check := func(name string, rl RateLimit) {
+		if rl.Rate <= 0 {
+			ve.add("%v: rate must be positive: %v", name, rl.Rate)
+		}
+		if rl.Burst < 0 {
+			ve.add("%v: burst must not be negative: %v", name, rl.Burst)
+		}
+	}
+	if rlc.Default != nil {
+		check("default", *rlc.Default)
+	}
+	for k, rl := range rlc.Services {
+		check(k, rl)
+	}
+	for k, rl := range rlc.Operations {
+		check(k, rl)
+	}
+	if rlc.MinRate < 0 {
+		ve.add("min rate must not be negative: %v", rlc.MinRate)
+	}
+	if rlc.ThrottleBackoff < 0 || rlc.ThrottleBackoff >= 1 {
+		ve.add("throttle backoff must be between 0 and 1: %v", rlc.ThrottleBackoff)
+	}
+	if rlc.RecoveryFactor != 0 && rlc.RecoveryFactor <= 1 {
+		ve.add("recovery factor must be greater than 1: %v", rlc.RecoveryFactor)
+	}
+	return ve.errOrNil()
+}
+
+// adaptiveBucket is a token bucket whose rate drops on throttling and recovers up to max
+type adaptiveBucket struct {
+	limiter *rate.Limiter
+	max     rate.Limit
+}
+
+type rateLimiter struct {
+	cfg     RateLimitConfig
+	mu      sync.Mutex
+	buckets map[string]*adaptiveBucket
+}
+
+func newRateLimiter(cfg *RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		cfg:     *cfg,
+		buckets: map[string]*adaptiveBucket{},
+	}
+	if rl.cfg.ThrottleBackoff == 0 {
+		rl.cfg.ThrottleBackoff = defaultThrottleBackoff
+	}
+	if rl.cfg.RecoveryFactor == 0 {
+		rl.cfg.RecoveryFactor = defaultRecoveryFactor
+	}
+	if rl.cfg.MinRate == 0 {
+		rl.cfg.MinRate = defaultMinRate
+	}
+	return rl
+}
+
+// bucket returns the bucket for a request, or nil if it is not limited
+func (rl *rateLimiter) bucket(service string, operation string) *adaptiveBucket {
+	op := fmt.Sprintf("%v.%v", service, operation)
+	key := op
+	lim, ok := rl.cfg.Operations[op]
+	if !ok {
+		key = service
+		lim, ok = rl.cfg.Services[service]
+	}
+	if !ok {
+		if rl.cfg.Default == nil {
+			return nil
+		}
+		key = "default:" + op
+		lim = *rl.cfg.Default
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b, ok := rl.buckets[key]
+	if !ok {
+		burst := lim.Burst
+		if burst == 0 {
+			burst = 1
+		}
+		b = &adaptiveBucket{
+			limiter: rate.NewLimiter(rate.Limit(lim.Rate), burst),
+			max:     rate.Limit(lim.Rate),
+		}
+		rl.buckets[key] = b
+	}
+	return b
+}
+
+// adapt slows the bucket after a throttled attempt and speeds it back up after successful ones
+func (rl *rateLimiter) adapt(b *adaptiveBucket, throttled bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	cur := b.limiter.Limit()
+	next := cur
+	switch {
+	case throttled:
+		// the floor never exceeds the configured rate, so throttling cannot speed a slow bucket up
+		floor := rate.Limit(rl.cfg.MinRate)
+		if floor > b.max {
+			floor = b.max
+		}
+		next = cur * rate.Limit(rl.cfg.ThrottleBackoff)
+		if next < floor {
+			next = floor
+		}
+	case cur < b.max:
+		next = cur * rate.Limit(rl.cfg.RecoveryFactor)
+		if next > b.max {
+			next = b.max
+		}
+	}
+	if next != cur {
+		b.limiter.SetLimit(next)
+	}
+}
+
+// SetRateLimits limits the API requests made by this service (including SDK retries), or removes limits if cfg
+// is nil. The limits are shared by all goroutines using the service. It must not be called concurrently with
+// other methods.
+func (aws *RealAWSService) SetRateLimits(cfg *RateLimitConfig) error {
+	if cfg == nil {
+		aws.limiter = nil
+		return nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	aws.limiter = newRateLimiter(cfg)
+	return nil
+}
+
+// rateLimitSignHandler runs before every attempt of a request is signed, waiting for a token
+func (aws *RealAWSService) rateLimitSignHandler(r *request.Request) {
+	rl := aws.limiter
+	if rl == nil || r.IsPresigned() {
+		return
+	}
+	b := rl.bucket(r.ClientInfo.ServiceName, r.Operation.Name)
+	if b == nil {
+		return
+	}
+	if err := b.limiter.Wait(r.Context()); err != nil {
+		r.Error = awserr.New(request.CanceledErrorCode, "rate limit wait canceled", err)
+	}
+}
+
+// rateLimitAttemptHandler runs after every attempt of a request
+func (aws *RealAWSService) rateLimitAttemptHandler(r *request.Request) {
+	rl := aws.limiter
+	if rl == nil || r.HTTPResponse == nil {
+		return
+	}
+	b := rl.bucket(r.ClientInfo.ServiceName, r.Operation.Name)
+	if b == nil {
+		return
+	}
+	throttled := false
+	if aerr, ok := r.Error.(awserr.Error); ok {
+		throttled = classifyErrorCode(aerr.Code(), r.HTTPResponse.StatusCode) == ErrThrottled
+	}
+	rl.adapt(b, throttled)
+}
+
+func (aws *RealAWSService) addRateLimitHandlers(h *request.Handlers) {
+	h.Sign.PushFrontNamed(request.NamedHandler{Name: "awsservice.RateLimit", Fn: aws.rateLimitSignHandler})
+	h.CompleteAttempt.PushBackNamed(request.NamedHandler{Name: "awsservice.RateLimitAdapt", Fn: aws.rateLimitAttemptHandler})
+}
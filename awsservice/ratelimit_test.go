@@ -0,0 +1,94 @@
+package awsservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	if err := DefaultRateLimitConfig().Validate(); err != nil {
+		t.Fatalf("default config should have been valid: %v", err)
+	}
+	rlc := &RateLimitConfig{
+		Default:         &RateLimit{Rate: 0},
+		Operations:      map[string]RateLimit{"ec2.CreateTags": {Rate: 1, Burst: -1}},
+		MinRate:         -1,
+		ThrottleBackoff: 2,
+		RecoveryFactor:  0.5,
+	}
+	ve, ok := rlc.Validate().(*ValidationError)
+	if !ok || len(ve.Problems) != 5 {
+		t.Fatalf("expected 5 problems: %v", ve)
+	}
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	rl := newRateLimiter(&RateLimitConfig{
+		Default:    &RateLimit{Rate: 10},
+		Services:   map[string]RateLimit{"route53": {Rate: 2}},
+		Operations: map[string]RateLimit{"ec2.CreateTags": {Rate: 5}},
+	})
+	if b := rl.bucket("ec2", "CreateTags"); b.max != 5 {
+		t.Fatalf("operation limit should apply: %v", b.max)
+	}
+	if rl.bucket("route53", "ChangeResourceRecordSets") != rl.bucket("route53", "ListResourceRecordSets") {
+		t.Fatalf("service bucket should be shared by its operations")
+	}
+	if rl.bucket("ec2", "DescribeInstances") == rl.bucket("ec2", "DescribeSubnets") {
+		t.Fatalf("default buckets should be per operation")
+	}
+	if b := rl.bucket("ec2", "DescribeInstances"); b.max != 10 || b.limiter.Burst() != 1 {
+		t.Fatalf("bad default bucket: %v %v", b.max, b.limiter.Burst())
+	}
+	if newRateLimiter(&RateLimitConfig{}).bucket("ec2", "DescribeInstances") != nil {
+		t.Fatalf("requests should be unlimited without a default")
+	}
+}
+
+func TestRateLimiterAdapt(t *testing.T) {
+	rl := newRateLimiter(&RateLimitConfig{Default: &RateLimit{Rate: 8}, MinRate: 1.5})
+	b := rl.bucket("ec2", "CreateTags")
+	for _, expected := range []rate.Limit{4, 2, 1.5, 1.5} {
+		rl.adapt(b, true)
+		if b.limiter.Limit() != expected {
+			t.Fatalf("bad limit after throttling: %v (expected %v)", b.limiter.Limit(), expected)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		rl.adapt(b, false)
+	}
+	if b.limiter.Limit() != 8 {
+		t.Fatalf("limit should have recovered to the configured rate: %v", b.limiter.Limit())
+	}
+	rl = newRateLimiter(&RateLimitConfig{Default: &RateLimit{Rate: 0.05}})
+	b = rl.bucket("ec2", "CreateTags")
+	rl.adapt(b, true)
+	if b.limiter.Limit() != 0.05 {
+		t.Fatalf("throttling should not raise a limit below the floor: %v", b.limiter.Limit())
+	}
+}
+
+func TestRealAWSServiceRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<CreateTagsResponse><requestId>req-1</requestId><return>true</return></CreateTagsResponse>`))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if err := svc.SetRateLimits(&RateLimitConfig{Operations: map[string]RateLimit{"ec2.CreateTags": {Rate: 50}}}); err != nil {
+		t.Fatalf("error setting rate limits: %v", err)
+	}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := svc.TagInstances([]string{"i-1"}, "k", "v"); err != nil {
+			t.Fatalf("error tagging: %v", err)
+		}
+	}
+	// the first request uses the initial token, the other 5 wait 20ms each
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Fatalf("requests were not rate limited: %v", d)
+	}
+}
@@ -0,0 +1,318 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Reaper tags. A resource expires at the time in ReaperExpiresAtTagKey (RFC 3339), or when the duration in
+// ReaperTTLTagKey (eg "36h") has passed since it was launched; if both are set the earlier applies.
+// ReaperDNSTagKey lists DNS records to delete along with the resource, as comma separated "zone-id/name" pairs.
+const (
+	ReaperExpiresAtTagKey = "awsservice:expires-at"
+	ReaperTTLTagKey       = "awsservice:ttl"
+	ReaperDNSTagKey       = "awsservice:dns"
+)
+
+// Reaper resource kinds
+const (
+	ReapInstance     = "instance"
+	ReapLoadBalancer = "load_balancer"
+	ReapDNSRecord    = "dns_record"
+)
+
+// Reap outcomes
+const (
+	ReapReaped   = "reaped"
+	ReapDryRun   = "would reap"
+	ReapDeferred = "deferred" // Over the per-run cap
+	ReapAllowed  = "allowed"  // On the allow list
+	ReapInvalid  = "invalid"  // Expiry tags could not be parsed
+	ReapFailed   = "failed"
+	ReapNotFound = "not found" // No DNS record had the tagged name
+)
+
+const defaultReaperMaxPerRun = 20
+
+// ReaperConfig controls a Reap run
+type ReaperConfig struct {
+	DryRun        bool              // Report what would be reaped without changing anything
+	AllowList     []string          // Instance IDs and load balancer names never to reap
+	AllowTags     map[string]string // Resources carrying any of these tags (with these values) are never reaped
+	MaxPerRun     int               // Most instances plus load balancers reaped per run (default: 20)
+	LoadBalancers bool              // Also reap expired classic load balancers
+	Now           time.Time         // Optional (default: the current time)
+}
+
+// ReapResult is the outcome for one overdue (or badly tagged) resource
+type ReapResult struct {
+	Kind      string
+	ID        string // Instance ID, load balancer name or "zone-id/name"
+	ExpiresAt time.Time
+	Outcome   string
+	Error     string
+}
+
+// ReapReport lists every overdue resource found by Reap, most overdue first, followed by any DNS records
+type ReapReport struct {
+	Now     time.Time
+	DryRun  bool
+	Results []ReapResult
+}
+
+// Count returns the number of results with the outcome
+func (rr *ReapReport) Count(outcome string) int {
+	n := 0
+	for _, r := range rr.Results {
+		if r.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+func (rr *ReapReport) String() string {
+	b := &strings.Builder{}
+	tw := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tID\tOVERDUE\tOUTCOME\tERROR")
+	for _, r := range rr.Results {
+		overdue := ""
+		if !r.ExpiresAt.IsZero() {
+			overdue = rr.Now.Sub(r.ExpiresAt).Round(time.Minute).String()
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", r.Kind, r.ID, overdue, r.Outcome, r.Error)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// reapCandidate is an overdue resource
+type reapCandidate struct {
+	result ReapResult
+	dns    []string
+}
+
+// expiryFromTags returns when a resource launched at launched expires (zero if it has no expiry tags)
+func expiryFromTags(tags map[string]string, launched time.Time) (time.Time, error) {
+	var expires time.Time
+	if v, ok := tags[ReaperExpiresAtTagKey]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return expires, fmt.Errorf("bad %v tag: %v", ReaperExpiresAtTagKey, v)
+		}
+		expires = t
+	}
+	if v, ok := tags[ReaperTTLTagKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return expires, fmt.Errorf("bad %v tag: %v", ReaperTTLTagKey, v)
+		}
+		if launched.IsZero() {
+			return expires, fmt.Errorf("%v tag without a launch time", ReaperTTLTagKey)
+		}
+		if t := launched.Add(d); expires.IsZero() || t.Before(expires) {
+			expires = t
+		}
+	}
+	return expires, nil
+}
+
+func (cfg *ReaperConfig) allowed(id string, tags map[string]string) bool {
+	if stringInSlice(id, cfg.AllowList) {
+		return true
+	}
+	for k, v := range cfg.AllowTags {
+		if tv, ok := tags[k]; ok && tv == v {
+			return true
+		}
+	}
+	return false
+}
+
+// candidate classifies a tagged resource, returning nil if it has not expired
+func (cfg *ReaperConfig) candidate(kind string, id string, tags map[string]string, launched time.Time, now time.Time) *reapCandidate {
+	rc := &reapCandidate{result: ReapResult{Kind: kind, ID: id}}
+	expires, err := expiryFromTags(tags, launched)
+	if err != nil {
+		rc.result.Outcome = ReapInvalid
+		rc.result.Error = err.Error()
+		return rc
+	}
+	if expires.IsZero() || expires.After(now) {
+		return nil
+	}
+	rc.result.ExpiresAt = expires
+	if cfg.allowed(id, tags) {
+		rc.result.Outcome = ReapAllowed
+	}
+	for _, r := range strings.Split(tags[ReaperDNSTagKey], ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			rc.dns = append(rc.dns, r)
+		}
+	}
+	return rc
+}
+
+// findTagged returns the IDs found by find for either expiry tag, without duplicates
+func findTagged(find func(string, string) ([]string, error)) ([]string, error) {
+	ids := []string{}
+	for _, k := range []string{ReaperExpiresAtTagKey, ReaperTTLTagKey} {
+		found, err := find(k, "*")
+		if err != nil {
+			return ids, err
+		}
+		for _, id := range found {
+			if !stringInSlice(id, ids) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func reapCandidates(svc AWSService, cfg *ReaperConfig, now time.Time) ([]*reapCandidate, error) {
+	rcs := []*reapCandidate{}
+	ids, err := findTagged(svc.FindInstancesByTag)
+	if err != nil {
+		return rcs, fmt.Errorf("error finding instances: %w", err)
+	}
+	if len(ids) > 0 {
+		iis, err := svc.GetInstancesInfo(ids)
+		if err != nil {
+			return rcs, fmt.Errorf("error getting instances: %w", err)
+		}
+		for _, ii := range iis {
+			if ii.State == ec2.InstanceStateNameTerminated || ii.State == ec2.InstanceStateNameShuttingDown {
+				continue
+			}
+			if rc := cfg.candidate(ReapInstance, ii.ID, ii.Tags, ii.LaunchTime, now); rc != nil {
+				rcs = append(rcs, rc)
+			}
+		}
+	}
+	if cfg.LoadBalancers {
+		names, err := findTagged(svc.FindLoadBalancersByTag)
+		if err != nil {
+			return rcs, fmt.Errorf("error finding load balancers: %w", err)
+		}
+		for _, n := range names {
+			tags, err := svc.GetLoadBalancerTags(n)
+			if err != nil {
+				return rcs, fmt.Errorf("error getting load balancer tags: %w", err)
+			}
+			lbi, err := svc.GetLoadBalancerInfo(n)
+			if err != nil {
+				return rcs, fmt.Errorf("error getting load balancer: %w", err)
+			}
+			if rc := cfg.candidate(ReapLoadBalancer, n, tags, lbi.CreatedTime, now); rc != nil {
+				rcs = append(rcs, rc)
+			}
+		}
+	}
+	// Most overdue first, so the cap defers the most recent expiries; badly tagged resources last
+	sort.SliceStable(rcs, func(i, j int) bool {
+		ei, ej := rcs[i].result.ExpiresAt, rcs[j].result.ExpiresAt
+		if ei.IsZero() != ej.IsZero() {
+			return ej.IsZero()
+		}
+		return ei.Before(ej)
+	})
+	return rcs, nil
+}
+
+// reapDNS deletes the records listed in a reaped resource's DNS tag, each record set (all values, or an alias)
+// in a single change
+func reapDNS(svc AWSService, dryRun bool, records []string) []ReapResult {
+	results := []ReapResult{}
+	for _, r := range records {
+		res := ReapResult{Kind: ReapDNSRecord, ID: r, Outcome: ReapReaped}
+		if dryRun {
+			res.Outcome = ReapDryRun
+		}
+		parts := strings.SplitN(r, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			res.Outcome = ReapInvalid
+			res.Error = fmt.Sprintf("bad %v tag entry (want zone-id/name): %v", ReaperDNSTagKey, r)
+			results = append(results, res)
+			continue
+		}
+		rds, err := svc.GetDNSRecords(parts[0], parts[1])
+		sets := DNSRecordSets(rds)
+		if err == nil && len(sets) == 0 {
+			res.Outcome = ReapNotFound
+		}
+		if err == nil && !dryRun {
+			for i := range sets {
+				if err = svc.DeleteDNSRecord(&sets[i]); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			res.Outcome = ReapFailed
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Reap finds instances (and optionally classic load balancers) whose expiry tags have passed and terminates or
+// deletes them, along with the DNS records named in their ReaperDNSTagKey tags. At most cfg.MaxPerRun
+// resources are reaped; the rest are reported as deferred until the next run. Allow-listed resources are
+// reported but never touched. An error is returned only if the overdue resources could not be listed;
+// failures to reap individual resources are recorded in the report.
+func Reap(svc AWSService, cfg *ReaperConfig) (*ReapReport, error) {
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	max := cfg.MaxPerRun
+	if max <= 0 {
+		max = defaultReaperMaxPerRun
+	}
+	report := &ReapReport{Now: now, DryRun: cfg.DryRun, Results: []ReapResult{}}
+	rcs, err := reapCandidates(svc, cfg, now)
+	if err != nil {
+		return report, err
+	}
+	dns := []ReapResult{}
+	n := 0
+	for _, rc := range rcs {
+		res := rc.result
+		if res.Outcome != "" {
+			report.Results = append(report.Results, res)
+			continue
+		}
+		if n >= max {
+			res.Outcome = ReapDeferred
+			report.Results = append(report.Results, res)
+			continue
+		}
+		n++
+		res.Outcome = ReapDryRun
+		if !cfg.DryRun {
+			res.Outcome = ReapReaped
+			if res.Kind == ReapInstance {
+				err = svc.TerminateInstances([]string{res.ID})
+			} else {
+				err = svc.DeleteLoadBalancer(res.ID)
+			}
+			if err != nil {
+				res.Outcome = ReapFailed
+				res.Error = err.Error()
+			}
+		}
+		report.Results = append(report.Results, res)
+		if res.Outcome != ReapFailed {
+			dns = append(dns, reapDNS(svc, cfg.DryRun, rc.dns)...)
+		}
+	}
+	report.Results = append(report.Results, dns...)
+	return report, nil
+}
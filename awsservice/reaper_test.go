@@ -0,0 +1,181 @@
+package awsservice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reaperTestService serves fixed instances, load balancers and DNS records; mutations are logged by
+// TestingAWSService
+type reaperTestService struct {
+	*TestingAWSService
+	instances  []InstanceInfo
+	lbs        map[string]map[string]string
+	lbCreated  time.Time
+	dns        []Route53RecordDefinition
+	deletedDNS []Route53RecordDefinition
+}
+
+func (s *reaperTestService) FindInstancesByTag(n string, v string) ([]string, error) {
+	ids := []string{}
+	for _, ii := range s.instances {
+		if _, ok := ii.Tags[n]; ok {
+			ids = append(ids, ii.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *reaperTestService) GetInstancesInfo(ids []string) ([]InstanceInfo, error) {
+	iis := []InstanceInfo{}
+	for _, ii := range s.instances {
+		if stringInSlice(ii.ID, ids) {
+			iis = append(iis, ii)
+		}
+	}
+	return iis, nil
+}
+
+func (s *reaperTestService) FindLoadBalancersByTag(n string, v string) ([]string, error) {
+	names := []string{}
+	for name, tags := range s.lbs {
+		if _, ok := tags[n]; ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (s *reaperTestService) GetLoadBalancerTags(n string) (map[string]string, error) {
+	return s.lbs[n], nil
+}
+
+func (s *reaperTestService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, error) {
+	return &LoadBalancerInfo{Name: n, CreatedTime: s.lbCreated}, nil
+}
+
+func (s *reaperTestService) GetDNSRecords(zoneID string, name string) ([]Route53RecordDefinition, error) {
+	rds := []Route53RecordDefinition{}
+	for _, rd := range s.dns {
+		if rd.ZoneID == zoneID && sameDNSName(rd.Name, name) {
+			rds = append(rds, rd)
+		}
+	}
+	return rds, nil
+}
+
+func (s *reaperTestService) DeleteDNSRecord(rd *Route53RecordDefinition) error {
+	s.deletedDNS = append(s.deletedDNS, *rd)
+	return s.TestingAWSService.DeleteDNSRecord(rd)
+}
+
+func newReaperTestService(now time.Time) *reaperTestService {
+	return &reaperTestService{
+		TestingAWSService: &TestingAWSService{},
+		instances: []InstanceInfo{
+			{ID: "i-old", State: "running", Tags: map[string]string{
+				ReaperExpiresAtTagKey: now.Add(-48 * time.Hour).Format(time.RFC3339),
+				ReaperDNSTagKey:       "Z1/old.example.com",
+			}},
+			{ID: "i-ttl", State: "running", LaunchTime: now.Add(-3 * time.Hour), Tags: map[string]string{ReaperTTLTagKey: "1h"}},
+			{ID: "i-fresh", State: "running", LaunchTime: now.Add(-1 * time.Hour), Tags: map[string]string{ReaperTTLTagKey: "24h"}},
+			{ID: "i-keep", State: "running", Tags: map[string]string{ReaperExpiresAtTagKey: now.Add(-time.Hour).Format(time.RFC3339), "keep": "true"}},
+			{ID: "i-bad", State: "running", Tags: map[string]string{ReaperTTLTagKey: "soon"}},
+			{ID: "i-gone", State: "terminated", Tags: map[string]string{ReaperExpiresAtTagKey: now.Add(-time.Hour).Format(time.RFC3339)}},
+		},
+		lbs: map[string]map[string]string{
+			"lb-old": {ReaperTTLTagKey: "2h"},
+		},
+		lbCreated: now.Add(-12 * time.Hour),
+		dns:       []Route53RecordDefinition{{ZoneID: "Z1", Name: "old.example.com", Type: "A", Value: "10.0.0.1"}},
+	}
+}
+
+func TestReap(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	svc := newReaperTestService(now)
+	cfg := &ReaperConfig{
+		AllowTags:     map[string]string{"keep": "true"},
+		MaxPerRun:     2,
+		LoadBalancers: true,
+		Now:           now,
+	}
+	report, err := Reap(svc, cfg)
+	if err != nil {
+		t.Fatalf("error reaping: %v", err)
+	}
+	got := []string{}
+	for _, r := range report.Results {
+		got = append(got, r.ID+"="+r.Outcome)
+	}
+	expected := "i-old=reaped lb-old=reaped i-ttl=deferred i-keep=allowed i-bad=invalid Z1/old.example.com=reaped"
+	if strings.Join(got, " ") != expected {
+		t.Fatalf("bad results: %v", got)
+	}
+	actions := []string{}
+	for _, l := range svc.Log {
+		actions = append(actions, l.Action+":"+l.NotableParams["ids"]+l.NotableParams["name"])
+	}
+	if strings.Join(actions, " ") != "TerminateInstances:[i-old] DeleteDNSRecord:old.example.com DeleteLoadBalancer:lb-old" {
+		t.Fatalf("bad actions: %v", actions)
+	}
+	if !strings.Contains(report.String(), "48h0m0s") {
+		t.Fatalf("report should show how overdue resources are: %v", report)
+	}
+}
+
+func TestReapDryRun(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	svc := newReaperTestService(now)
+	report, err := Reap(svc, &ReaperConfig{DryRun: true, AllowList: []string{"i-ttl"}, Now: now})
+	if err != nil {
+		t.Fatalf("error reaping: %v", err)
+	}
+	if len(svc.Log) != 0 {
+		t.Fatalf("dry run should not change anything: %v", svc.Log)
+	}
+	if report.Count(ReapDryRun) != 3 || report.Count(ReapAllowed) != 1 || report.Count(ReapInvalid) != 1 {
+		t.Fatalf("bad report: %v", report)
+	}
+}
+
+func TestReapDNS(t *testing.T) {
+	svc := newReaperTestService(time.Now())
+	svc.dns = []Route53RecordDefinition{
+		{ZoneID: "Z1", Name: "multi.example.com.", Type: "A", TTL: 60, Value: "10.0.0.1"},
+		{ZoneID: "Z1", Name: "multi.example.com.", Type: "A", TTL: 60, Value: "10.0.0.2"},
+		{ZoneID: "Z1", Name: "alias.example.com.", Type: "A", Alias: &Route53AliasTarget{ZoneID: "Z2", DNSName: "lb.example."}},
+	}
+	results := reapDNS(svc, false, []string{"Z1/multi.example.com", "Z1/alias.example.com", "Z1/missing.example.com"})
+	got := []string{}
+	for _, r := range results {
+		got = append(got, r.ID+"="+r.Outcome)
+	}
+	if strings.Join(got, " ") != "Z1/multi.example.com=reaped Z1/alias.example.com=reaped Z1/missing.example.com=not found" {
+		t.Fatalf("bad results: %v", got)
+	}
+	if len(svc.deletedDNS) != 2 {
+		t.Fatalf("each record set should be deleted once: %+v", svc.deletedDNS)
+	}
+	if strings.Join(svc.deletedDNS[0].Values(), ",") != "10.0.0.1,10.0.0.2" {
+		t.Fatalf("every value should be deleted together: %+v", svc.deletedDNS[0])
+	}
+	if svc.deletedDNS[1].Alias == nil || svc.deletedDNS[1].Alias.DNSName != "lb.example." {
+		t.Fatalf("alias target should be deleted: %+v", svc.deletedDNS[1])
+	}
+}
+
+func TestReapDNSNotFound(t *testing.T) {
+	svc := newReaperTestService(time.Now())
+	svc.dns = nil
+	for _, dryRun := range []bool{false, true} {
+		results := reapDNS(svc, dryRun, []string{"Z1/old.example.com"})
+		if len(results) != 1 || results[0].Outcome != ReapNotFound {
+			t.Fatalf("record with no matches should not be reported as reaped: %+v", results)
+		}
+	}
+	if len(svc.Log) != 0 {
+		t.Fatalf("nothing should have been deleted: %v", svc.Log)
+	}
+}
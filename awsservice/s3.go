@@ -0,0 +1,330 @@
+package awsservice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Server-side encryption modes
+const (
+	SSEAES256 = s3.ServerSideEncryptionAes256
+	SSEKMS    = s3.ServerSideEncryptionAwsKms
+)
+
+// S3PutOptions are optional settings for objects written to S3
+type S3PutOptions struct {
+	ContentType          string
+	ServerSideEncryption string // SSEAES256 or SSEKMS
+	KMSKeyID             string // Optional with SSEKMS (default: the AWS managed key)
+	Metadata             map[string]string
+}
+
+type S3ObjectInfo struct {
+	Bucket               string
+	Key                  string
+	Size                 int64
+	ETag                 string
+	LastModified         time.Time
+	ContentType          string
+	StorageClass         string
+	ServerSideEncryption string
+	Metadata             map[string]string
+}
+
+func s3Metadata(m map[string]string) map[string]*string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := map[string]*string{}
+	for k, v := range m {
+		cv := v
+		out[k] = &cv
+	}
+	return out
+}
+
+func (opts *S3PutOptions) putObjectInput(bucket string, key string) *s3.PutObjectInput {
+	poi := &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}
+	if opts == nil {
+		return poi
+	}
+	if opts.ContentType != "" {
+		poi.ContentType = &opts.ContentType
+	}
+	if opts.ServerSideEncryption != "" {
+		poi.ServerSideEncryption = &opts.ServerSideEncryption
+	}
+	if opts.KMSKeyID != "" {
+		poi.SSEKMSKeyId = &opts.KMSKeyID
+	}
+	poi.Metadata = s3Metadata(opts.Metadata)
+	return poi
+}
+
+func (aws *RealAWSService) PutObject(bucket string, key string, data []byte, opts *S3PutOptions) error {
+	poi := opts.putObjectInput(bucket, key)
+	poi.Body = bytes.NewReader(data)
+	_, err := aws.s3c.PutObject(poi)
+	return wrapError("PutObject", err)
+}
+
+// UploadStream writes r to S3, switching to a concurrent multipart upload for large bodies. In dry-run mode a
+// single PutObject mutation is recorded and r is not read.
+func (aws *RealAWSService) UploadStream(bucket string, key string, r io.Reader, opts *S3PutOptions) error {
+	poi := opts.putObjectInput(bucket, key)
+	if plan := aws.dryRun; plan != nil {
+		// the uploader can't run against skipped calls: a multipart upload needs the ID CreateMultipartUpload returns
+		plan.add(Mutation{Service: s3.ServiceName, Operation: "PutObject", Params: poi})
+		return nil
+	}
+	_, err := s3manager.NewUploaderWithClient(aws.s3c).Upload(&s3manager.UploadInput{
+		Bucket:               poi.Bucket,
+		Key:                  poi.Key,
+		Body:                 r,
+		ContentType:          poi.ContentType,
+		ServerSideEncryption: poi.ServerSideEncryption,
+		SSEKMSKeyId:          poi.SSEKMSKeyId,
+		Metadata:             poi.Metadata,
+	})
+	return wrapError("UploadStream", err)
+}
+
+// UploadFile writes a local file to S3, using a multipart upload for large files
+func (aws *RealAWSService) UploadFile(bucket string, key string, path string, opts *S3PutOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer f.Close()
+	return aws.UploadStream(bucket, key, f, opts)
+}
+
+func (aws *RealAWSService) GetObject(bucket string, key string) ([]byte, error) {
+	body, _, err := aws.GetObjectStream(bucket, key)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// GetObjectStream returns the object body, which the caller must close, along with its metadata
+func (aws *RealAWSService) GetObjectStream(bucket string, key string) (io.ReadCloser, *S3ObjectInfo, error) {
+	info := &S3ObjectInfo{Bucket: bucket, Key: key}
+	o, err := aws.s3c.GetObject(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, info, wrapError("GetObjectStream", err)
+	}
+	info.Size = drefInt64Ptr(o.ContentLength)
+	info.ETag = drefStringPtr(o.ETag)
+	info.LastModified = drefTimePtr(o.LastModified)
+	info.ContentType = drefStringPtr(o.ContentType)
+	info.StorageClass = drefStringPtr(o.StorageClass)
+	info.ServerSideEncryption = drefStringPtr(o.ServerSideEncryption)
+	md := map[string]string{}
+	for k, v := range o.Metadata {
+		md[k] = drefStringPtr(v)
+	}
+	info.Metadata = md
+	return o.Body, info, nil
+}
+
+func (aws *RealAWSService) DeleteObject(bucket string, key string) error {
+	_, err := aws.s3c.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	return wrapError("DeleteObject", err)
+}
+
+// ListObjects returns every object in bucket whose key begins with prefix
+func (aws *RealAWSService) ListObjects(bucket string, prefix string) ([]S3ObjectInfo, error) {
+	result := []S3ObjectInfo{}
+	loi := &s3.ListObjectsV2Input{
+		Bucket: &bucket,
+		Prefix: &prefix,
+	}
+	err := aws.s3c.ListObjectsV2Pages(loi, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			result = append(result, S3ObjectInfo{
+				Bucket:       bucket,
+				Key:          drefStringPtr(o.Key),
+				Size:         drefInt64Ptr(o.Size),
+				ETag:         drefStringPtr(o.ETag),
+				LastModified: drefTimePtr(o.LastModified),
+				StorageClass: drefStringPtr(o.StorageClass),
+			})
+		}
+		return true
+	})
+	return result, wrapError("ListObjects", err)
+}
+
+// CopyObject copies an object (up to 5GB) within S3. If opts is nil the source metadata is kept.
+func (aws *RealAWSService) CopyObject(srcBucket string, srcKey string, dstBucket string, dstKey string, opts *S3PutOptions) error {
+	src := url.PathEscape(srcBucket + "/" + srcKey)
+	coi := &s3.CopyObjectInput{
+		Bucket:     &dstBucket,
+		Key:        &dstKey,
+		CopySource: &src,
+	}
+	if opts != nil {
+		poi := opts.putObjectInput(dstBucket, dstKey)
+		directive := s3.MetadataDirectiveReplace
+		coi.MetadataDirective = &directive
+		coi.ContentType = poi.ContentType
+		coi.ServerSideEncryption = poi.ServerSideEncryption
+		coi.SSEKMSKeyId = poi.SSEKMSKeyId
+		coi.Metadata = poi.Metadata
+	}
+	_, err := aws.s3c.CopyObject(coi)
+	return wrapError("CopyObject", err)
+}
+
+// PresignGetURL returns a URL allowing anyone holding it to download the object until expiry
+func (aws *RealAWSService) PresignGetURL(bucket string, key string, expiry time.Duration) (string, error) {
+	req, _ := aws.s3c.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	return req.Presign(expiry)
+}
+
+// PresignPutURL returns a URL allowing anyone holding it to upload the object until expiry
+func (aws *RealAWSService) PresignPutURL(bucket string, key string, expiry time.Duration) (string, error) {
+	req, _ := aws.s3c.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	return req.Presign(expiry)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) PutObject(bucket string, key string, data []byte, opts *S3PutOptions) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "PutObject",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+			"size":   fmt.Sprintf("%v", len(data)),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) UploadStream(bucket string, key string, r io.Reader, opts *S3PutOptions) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "UploadStream",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) UploadFile(bucket string, key string, path string, opts *S3PutOptions) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "UploadFile",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+			"path":   path,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetObject(bucket string, key string) ([]byte, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetObject",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+		},
+	})
+	return []byte{}, nil
+}
+
+func (aws *TestingAWSService) GetObjectStream(bucket string, key string) (io.ReadCloser, *S3ObjectInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetObjectStream",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+		},
+	})
+	return ioutil.NopCloser(bytes.NewReader([]byte{})), &S3ObjectInfo{Bucket: bucket, Key: key}, nil
+}
+
+func (aws *TestingAWSService) DeleteObject(bucket string, key string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteObject",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) ListObjects(bucket string, prefix string) ([]S3ObjectInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ListObjects",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"prefix": prefix,
+		},
+	})
+	return []S3ObjectInfo{}, nil
+}
+
+func (aws *TestingAWSService) CopyObject(srcBucket string, srcKey string, dstBucket string, dstKey string, opts *S3PutOptions) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CopyObject",
+		NotableParams: map[string]string{
+			"source":      srcBucket + "/" + srcKey,
+			"destination": dstBucket + "/" + dstKey,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) PresignGetURL(bucket string, key string, expiry time.Duration) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "PresignGetURL",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+			"expiry": expiry.String(),
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) PresignPutURL(bucket string, key string, expiry time.Duration) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "PresignPutURL",
+		NotableParams: map[string]string{
+			"bucket": bucket,
+			"key":    key,
+			"expiry": expiry.String(),
+		},
+	})
+	return "", nil
+}
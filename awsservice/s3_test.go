@@ -0,0 +1,240 @@
+package awsservice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3Request is a request received by s3Fake
+type s3Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Size   int
+}
+
+// s3Fake answers the S3 REST API calls made by RealAWSService, recording each request. Objects are kept
+// only long enough to be read back.
+type s3Fake struct {
+	mu       sync.Mutex
+	requests []s3Request
+	objects  map[string][]byte // path -> body
+}
+
+func (f *s3Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, s3Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header, Size: len(body)})
+	q := r.URL.Query()
+	switch {
+	case r.Method == "POST" && q["uploads"] != nil:
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%v</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`, r.URL.Path)
+	case r.Method == "PUT" && q.Get("partNumber") != "":
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%v"`, q.Get("partNumber")))
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+		w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	case r.Method == "PUT":
+		f.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == "GET" && q.Get("list-type") == "2":
+		w.Write([]byte(`<ListBucketResult><Name>bucket</Name><Prefix>logs/</Prefix><KeyCount>1</KeyCount><IsTruncated>false</IsTruncated>` +
+			`<Contents><Key>logs/a.txt</Key><Size>5</Size><ETag>"etag"</ETag><StorageClass>STANDARD</StorageClass>` +
+			`<LastModified>2026-10-19T12:00:00.000Z</LastModified></Contents></ListBucketResult>`))
+	case r.Method == "GET":
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message><RequestId>req-1</RequestId></Error>`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		w.Header().Set("X-Amz-Meta-Owner", "web")
+		w.Write(data)
+	case r.Method == "DELETE":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newS3FakeService() (*RealAWSService, *s3Fake, func()) {
+	f := &s3Fake{objects: map[string][]byte{}}
+	ts := httptest.NewServer(f)
+	return newEndpointAWSService(ts.URL), f, ts.Close
+}
+
+func TestS3PutAndGetObject(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	opts := &S3PutOptions{ContentType: "text/plain", ServerSideEncryption: SSEKMS, KMSKeyID: "key-1", Metadata: map[string]string{"owner": "web"}}
+	if err := svc.PutObject("bucket", "logs/a.txt", []byte("hello"), opts); err != nil {
+		t.Fatalf("error putting object: %v", err)
+	}
+	h := f.requests[0].Header
+	if f.requests[0].Path != "/bucket/logs/a.txt" || h.Get("Content-Type") != "text/plain" || h.Get("X-Amz-Server-Side-Encryption") != "aws:kms" ||
+		h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "key-1" || h.Get("X-Amz-Meta-Owner") != "web" {
+		t.Fatalf("bad put request: %+v", f.requests[0])
+	}
+	data, err := svc.GetObject("bucket", "logs/a.txt")
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("bad object: %q: %v", data, err)
+	}
+	body, info, err := svc.GetObjectStream("bucket", "logs/a.txt")
+	if err != nil {
+		t.Fatalf("error getting object: %v", err)
+	}
+	body.Close()
+	if info.Size != 5 || info.ContentType != "text/plain" || info.ServerSideEncryption != SSEKMS || info.Metadata["Owner"] != "web" {
+		t.Fatalf("bad object info: %+v", info)
+	}
+	if _, err := svc.GetObject("bucket", "missing"); !IsNotFound(err) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+}
+
+func TestS3PutObjectNoOptions(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	if err := svc.PutObject("bucket", "a.txt", []byte("hello"), nil); err != nil {
+		t.Fatalf("error putting object: %v", err)
+	}
+	if h := f.requests[0].Header; h.Get("X-Amz-Server-Side-Encryption") != "" || h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "" {
+		t.Fatalf("encryption should not be requested without options: %v", h)
+	}
+}
+
+func TestS3UploadStreamMultipart(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	data := bytes.Repeat([]byte("a"), 6*1024*1024)
+	if err := svc.UploadStream("bucket", "big.bin", bytes.NewReader(data), &S3PutOptions{ServerSideEncryption: SSEAES256}); err != nil {
+		t.Fatalf("error uploading: %v", err)
+	}
+	if len(f.requests) != 4 {
+		t.Fatalf("expected create, two parts and complete: %+v", f.requests)
+	}
+	if f.requests[0].Query["uploads"] == nil || f.requests[0].Header.Get("X-Amz-Server-Side-Encryption") != "AES256" {
+		t.Fatalf("bad create request: %+v", f.requests[0])
+	}
+	size := 0
+	for _, r := range f.requests[1:3] {
+		if r.Query.Get("uploadId") != "upload-1" {
+			t.Fatalf("bad part request: %+v", r)
+		}
+		size += r.Size
+	}
+	if size != len(data) || f.requests[3].Method != "POST" || f.requests[3].Query.Get("uploadId") != "upload-1" {
+		t.Fatalf("bad upload: %v bytes: %+v", size, f.requests[3])
+	}
+}
+
+func TestS3UploadStreamDryRun(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	plan := &MutationPlan{}
+	svc.SetDryRun(plan)
+	data := bytes.Repeat([]byte("a"), 6*1024*1024)
+	if err := svc.UploadStream("bucket", "big.bin", bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("dry run upload should have succeeded: %v", err)
+	}
+	if len(f.requests) != 0 {
+		t.Fatalf("nothing should have been sent: %+v", f.requests)
+	}
+	ms := plan.Mutations()
+	if len(ms) != 1 || ms[0].Service != "s3" || ms[0].Operation != "PutObject" {
+		t.Fatalf("expected a single PutObject mutation: %v", plan)
+	}
+}
+
+func TestS3UploadFile(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	if err := svc.UploadFile("bucket", "a.txt", path, nil); err != nil {
+		t.Fatalf("error uploading: %v", err)
+	}
+	if string(f.objects["/bucket/a.txt"]) != "hello" {
+		t.Fatalf("bad upload: %v", f.objects)
+	}
+	if err := svc.UploadFile("bucket", "a.txt", filepath.Join(t.TempDir(), "missing"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file error should be wrapped: %v", err)
+	}
+}
+
+func TestS3CopyObject(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	if err := svc.CopyObject("src", "dir/a b.txt", "dst", "b.txt", nil); err != nil {
+		t.Fatalf("error copying: %v", err)
+	}
+	r := f.requests[0]
+	if r.Path != "/dst/b.txt" || r.Header.Get("X-Amz-Copy-Source") != "src%2Fdir%2Fa%20b.txt" || r.Header.Get("X-Amz-Metadata-Directive") != "" {
+		t.Fatalf("bad copy request: %+v", r)
+	}
+	if err := svc.CopyObject("src", "a.txt", "dst", "a.txt", &S3PutOptions{ContentType: "text/plain", Metadata: map[string]string{"owner": "web"}}); err != nil {
+		t.Fatalf("error copying: %v", err)
+	}
+	if h := f.requests[1].Header; h.Get("X-Amz-Metadata-Directive") != "REPLACE" || h.Get("Content-Type") != "text/plain" || h.Get("X-Amz-Meta-Owner") != "web" {
+		t.Fatalf("options should replace the metadata: %v", h)
+	}
+}
+
+func TestS3ListAndDeleteObjects(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	objs, err := svc.ListObjects("bucket", "logs/")
+	if err != nil {
+		t.Fatalf("error listing: %v", err)
+	}
+	if len(objs) != 1 || objs[0].Bucket != "bucket" || objs[0].Key != "logs/a.txt" || objs[0].Size != 5 || objs[0].LastModified.IsZero() {
+		t.Fatalf("bad objects: %+v", objs)
+	}
+	if f.requests[0].Query.Get("prefix") != "logs/" {
+		t.Fatalf("bad list request: %+v", f.requests[0])
+	}
+	if err := svc.DeleteObject("bucket", "logs/a.txt"); err != nil || f.requests[1].Method != "DELETE" || f.requests[1].Path != "/bucket/logs/a.txt" {
+		t.Fatalf("bad delete: %v: %+v", err, f.requests[1])
+	}
+}
+
+func TestS3Presign(t *testing.T) {
+	svc, f, done := newS3FakeService()
+	defer done()
+	for method, presign := range map[string]func(string, string, time.Duration) (string, error){"GET": svc.PresignGetURL, "PUT": svc.PresignPutURL} {
+		s, err := presign("bucket", "logs/a.txt", 15*time.Minute)
+		if err != nil {
+			t.Fatalf("error presigning %v: %v", method, err)
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatalf("bad URL: %v", err)
+		}
+		if u.Path != "/bucket/logs/a.txt" || u.Query().Get("X-Amz-Expires") != "900" || u.Query().Get("X-Amz-Signature") == "" ||
+			!strings.HasPrefix(u.Query().Get("X-Amz-Credential"), "AKID/") {
+			t.Fatalf("bad presigned %v URL: %v", method, s)
+		}
+	}
+	if len(f.requests) != 0 {
+		t.Fatalf("presigning should not make requests: %+v", f.requests)
+	}
+}
@@ -0,0 +1,172 @@
+package awsservice
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryS3Object struct {
+	data []byte
+	info S3ObjectInfo
+}
+
+// objectInfo returns a copy of the object info that callers can modify without changing the stored object
+func (o memoryS3Object) objectInfo() S3ObjectInfo {
+	info := o.info
+	info.Metadata = make(map[string]string, len(o.info.Metadata))
+	for k, v := range o.info.Metadata {
+		info.Metadata[k] = v
+	}
+	return info
+}
+
+// MemoryS3Service is an in-memory AWSS3Service for tests. Buckets are created implicitly on first write.
+// It is safe for concurrent use.
+type MemoryS3Service struct {
+	mu      sync.Mutex
+	objects map[string]map[string]memoryS3Object
+}
+
+func NewMemoryS3Service() *MemoryS3Service {
+	return &MemoryS3Service{
+		objects: map[string]map[string]memoryS3Object{},
+	}
+}
+
+func (m *MemoryS3Service) put(bucket string, key string, data []byte, opts *S3PutOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info := S3ObjectInfo{
+		Bucket:       bucket,
+		Key:          key,
+		Size:         int64(len(data)),
+		ETag:         fmt.Sprintf("\"%x\"", md5.Sum(data)),
+		LastModified: time.Now().UTC(),
+		StorageClass: "STANDARD",
+		Metadata:     map[string]string{},
+	}
+	if opts != nil {
+		info.ContentType = opts.ContentType
+		info.ServerSideEncryption = opts.ServerSideEncryption
+		for k, v := range opts.Metadata {
+			info.Metadata[k] = v
+		}
+	}
+	if m.objects[bucket] == nil {
+		m.objects[bucket] = map[string]memoryS3Object{}
+	}
+	m.objects[bucket][key] = memoryS3Object{
+		data: append([]byte{}, data...),
+		info: info,
+	}
+}
+
+func (m *MemoryS3Service) get(bucket string, key string) (memoryS3Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.objects[bucket][key]
+	if !ok {
+		return obj, notFoundError("GetObject", "object not found: %v/%v", bucket, key)
+	}
+	return obj, nil
+}
+
+func (m *MemoryS3Service) PutObject(bucket string, key string, data []byte, opts *S3PutOptions) error {
+	m.put(bucket, key, data, opts)
+	return nil
+}
+
+func (m *MemoryS3Service) UploadStream(bucket string, key string, r io.Reader, opts *S3PutOptions) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.put(bucket, key, data, opts)
+	return nil
+}
+
+func (m *MemoryS3Service) UploadFile(bucket string, key string, path string, opts *S3PutOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening file: %v", err)
+	}
+	defer f.Close()
+	return m.UploadStream(bucket, key, f, opts)
+}
+
+func (m *MemoryS3Service) GetObject(bucket string, key string) ([]byte, error) {
+	obj, err := m.get(bucket, key)
+	if err != nil {
+		return []byte{}, err
+	}
+	return append([]byte{}, obj.data...), nil
+}
+
+func (m *MemoryS3Service) GetObjectStream(bucket string, key string) (io.ReadCloser, *S3ObjectInfo, error) {
+	obj, err := m.get(bucket, key)
+	if err != nil {
+		return nil, &S3ObjectInfo{Bucket: bucket, Key: key}, err
+	}
+	info := obj.objectInfo()
+	return ioutil.NopCloser(bytes.NewReader(obj.data)), &info, nil
+}
+
+// DeleteObject succeeds whether or not the object exists, as S3 does
+func (m *MemoryS3Service) DeleteObject(bucket string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects[bucket], key)
+	return nil
+}
+
+func (m *MemoryS3Service) ListObjects(bucket string, prefix string) ([]S3ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []S3ObjectInfo{}
+	for k, obj := range m.objects[bucket] {
+		if strings.HasPrefix(k, prefix) {
+			result = append(result, obj.objectInfo())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+func (m *MemoryS3Service) CopyObject(srcBucket string, srcKey string, dstBucket string, dstKey string, opts *S3PutOptions) error {
+	obj, err := m.get(srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &S3PutOptions{
+			ContentType:          obj.info.ContentType,
+			ServerSideEncryption: obj.info.ServerSideEncryption,
+			Metadata:             obj.info.Metadata,
+		}
+	}
+	m.put(dstBucket, dstKey, obj.data, opts)
+	return nil
+}
+
+func (m *MemoryS3Service) presign(method string, bucket string, key string, expiry time.Duration) string {
+	return fmt.Sprintf("memory://%v/%v?method=%v&expires=%v", bucket, url.PathEscape(key), method, time.Now().Add(expiry).Unix())
+}
+
+// PresignGetURL returns a memory:// URL; it cannot be fetched
+func (m *MemoryS3Service) PresignGetURL(bucket string, key string, expiry time.Duration) (string, error) {
+	return m.presign("GET", bucket, key, expiry), nil
+}
+
+// PresignPutURL returns a memory:// URL; it cannot be fetched
+func (m *MemoryS3Service) PresignPutURL(bucket string, key string, expiry time.Duration) (string, error) {
+	return m.presign("PUT", bucket, key, expiry), nil
+}
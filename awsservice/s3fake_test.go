@@ -0,0 +1,67 @@
+package awsservice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+var _ AWSS3Service = &MemoryS3Service{}
+
+func TestMemoryS3Service(t *testing.T) {
+	s3 := NewMemoryS3Service()
+	opts := &S3PutOptions{ContentType: "text/plain", ServerSideEncryption: SSEAES256}
+	if err := s3.PutObject("bucket", "logs/a.txt", []byte("hello"), opts); err != nil {
+		t.Fatalf("error putting object: %v", err)
+	}
+	if err := s3.UploadStream("bucket", "logs/b.txt", bytes.NewReader([]byte("world")), nil); err != nil {
+		t.Fatalf("error uploading object: %v", err)
+	}
+	if err := s3.PutObject("bucket", "artifacts/c.tgz", []byte{0}, nil); err != nil {
+		t.Fatalf("error putting object: %v", err)
+	}
+	objs, err := s3.ListObjects("bucket", "logs/")
+	if err != nil {
+		t.Fatalf("error listing objects: %v", err)
+	}
+	if len(objs) != 2 || objs[0].Key != "logs/a.txt" || objs[1].Key != "logs/b.txt" {
+		t.Fatalf("incorrect objects listed: %v", objs)
+	}
+	if err := s3.CopyObject("bucket", "logs/a.txt", "archive", "a.txt", nil); err != nil {
+		t.Fatalf("error copying object: %v", err)
+	}
+	body, info, err := s3.GetObjectStream("archive", "a.txt")
+	if err != nil {
+		t.Fatalf("error getting object: %v", err)
+	}
+	defer body.Close()
+	data, _ := ioutil.ReadAll(body)
+	if string(data) != "hello" || info.ContentType != "text/plain" || info.ServerSideEncryption != SSEAES256 {
+		t.Fatalf("incorrect copied object: %q: %+v", data, info)
+	}
+	if err := s3.DeleteObject("bucket", "logs/a.txt"); err != nil {
+		t.Fatalf("error deleting object: %v", err)
+	}
+	if _, err := s3.GetObject("bucket", "logs/a.txt"); err == nil {
+		t.Fatalf("deleted object should not be found")
+	}
+}
+
+func TestMemoryS3ServiceInfoIsCopied(t *testing.T) {
+	s3 := NewMemoryS3Service()
+	if err := s3.PutObject("bucket", "a.txt", []byte("hello"), &S3PutOptions{Metadata: map[string]string{"owner": "web"}}); err != nil {
+		t.Fatalf("error putting object: %v", err)
+	}
+	_, info, err := s3.GetObjectStream("bucket", "a.txt")
+	if err != nil {
+		t.Fatalf("error getting object: %v", err)
+	}
+	info.Size = 0
+	info.Metadata["owner"] = "changed"
+	objs, _ := s3.ListObjects("bucket", "")
+	objs[0].Metadata["owner"] = "changed"
+	_, info, _ = s3.GetObjectStream("bucket", "a.txt")
+	if info.Size != 5 || info.Metadata["owner"] != "web" {
+		t.Fatalf("stored object should not have been modified: %+v", info)
+	}
+}
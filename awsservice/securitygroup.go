@@ -0,0 +1,445 @@
+package awsservice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type SecurityGroupDefinition struct {
+	Name        string
+	Description string
+	VPC         string
+	Tags        map[string]string // Optional
+}
+
+// SecurityGroupRule is a single permission. Exactly one of CIDR, PrefixListID or SourceGroupID must be set.
+type SecurityGroupRule struct {
+	Protocol      string // "tcp", "udp", "icmp" or "-1" (all)
+	FromPort      int64  // Ignored for protocol -1
+	ToPort        int64  // Ignored for protocol -1
+	CIDR          string // IPv4 or IPv6
+	PrefixListID  string
+	SourceGroupID string
+	Description   string // Not considered when comparing rules
+}
+
+type SecurityGroupInfo struct {
+	ID          string
+	Name        string
+	Description string
+	VPC         string
+	Ingress     []SecurityGroupRule
+	Egress      []SecurityGroupRule
+	Tags        map[string]string
+}
+
+// SecurityGroupRuleChanges lists the rules changed by ReconcileSecurityGroup
+type SecurityGroupRuleChanges struct {
+	AuthorizedIngress []SecurityGroupRule
+	RevokedIngress    []SecurityGroupRule
+	AuthorizedEgress  []SecurityGroupRule
+	RevokedEgress     []SecurityGroupRule
+}
+
+var protocolNumbers = map[string]string{
+	"all": "-1",
+	"1":   "icmp",
+	"6":   "tcp",
+	"17":  "udp",
+}
+
+func (r SecurityGroupRule) normalize() SecurityGroupRule {
+	r.Protocol = strings.ToLower(r.Protocol)
+	if p, ok := protocolNumbers[r.Protocol]; ok {
+		r.Protocol = p
+	}
+	if r.Protocol == "-1" {
+		r.FromPort = 0
+		r.ToPort = 0
+	}
+	return r
+}
+
+// key identifies a rule irrespective of its description
+func (r SecurityGroupRule) key() string {
+	n := r.normalize()
+	return fmt.Sprintf("%v|%v|%v|%v|%v|%v", n.Protocol, n.FromPort, n.ToPort, n.CIDR, n.PrefixListID, n.SourceGroupID)
+}
+
+func (r SecurityGroupRule) validate() error {
+	sources := 0
+	for _, s := range []string{r.CIDR, r.PrefixListID, r.SourceGroupID} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return invalidDefinition("rule %v: exactly one of CIDR, prefix list or source group is required", r.key())
+	}
+	if r.Protocol == "" {
+		return invalidDefinition("rule %v: protocol is required", r.key())
+	}
+	return nil
+}
+
+func toIPPermissions(rules []SecurityGroupRule) ([]*ec2.IpPermission, error) {
+	perms := []*ec2.IpPermission{}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return perms, err
+		}
+		r := rule.normalize()
+		perm := &ec2.IpPermission{
+			IpProtocol: &r.Protocol,
+		}
+		if r.Protocol != "-1" {
+			perm.FromPort = &r.FromPort
+			perm.ToPort = &r.ToPort
+		}
+		var desc *string
+		if r.Description != "" {
+			desc = &r.Description
+		}
+		switch {
+		case strings.Contains(r.CIDR, ":"):
+			perm.Ipv6Ranges = []*ec2.Ipv6Range{{CidrIpv6: &r.CIDR, Description: desc}}
+		case r.CIDR != "":
+			perm.IpRanges = []*ec2.IpRange{{CidrIp: &r.CIDR, Description: desc}}
+		case r.PrefixListID != "":
+			perm.PrefixListIds = []*ec2.PrefixListId{{PrefixListId: &r.PrefixListID, Description: desc}}
+		default:
+			perm.UserIdGroupPairs = []*ec2.UserIdGroupPair{{GroupId: &r.SourceGroupID, Description: desc}}
+		}
+		perms = append(perms, perm)
+	}
+	return perms, nil
+}
+
+// fromIPPermissions flattens AWS permissions into one rule per source
+func fromIPPermissions(perms []*ec2.IpPermission) []SecurityGroupRule {
+	rules := []SecurityGroupRule{}
+	for _, p := range perms {
+		base := SecurityGroupRule{
+			Protocol: drefStringPtr(p.IpProtocol),
+			FromPort: drefInt64Ptr(p.FromPort),
+			ToPort:   drefInt64Ptr(p.ToPort),
+		}
+		for _, ipr := range p.IpRanges {
+			r := base
+			r.CIDR = drefStringPtr(ipr.CidrIp)
+			r.Description = drefStringPtr(ipr.Description)
+			rules = append(rules, r.normalize())
+		}
+		for _, ipr := range p.Ipv6Ranges {
+			r := base
+			r.CIDR = drefStringPtr(ipr.CidrIpv6)
+			r.Description = drefStringPtr(ipr.Description)
+			rules = append(rules, r.normalize())
+		}
+		for _, pl := range p.PrefixListIds {
+			r := base
+			r.PrefixListID = drefStringPtr(pl.PrefixListId)
+			r.Description = drefStringPtr(pl.Description)
+			rules = append(rules, r.normalize())
+		}
+		for _, gp := range p.UserIdGroupPairs {
+			r := base
+			r.SourceGroupID = drefStringPtr(gp.GroupId)
+			r.Description = drefStringPtr(gp.Description)
+			rules = append(rules, r.normalize())
+		}
+	}
+	return rules
+}
+
+// diffSecurityGroupRules returns the rules in desired but not current (add) and in current but not desired (remove)
+func diffSecurityGroupRules(current []SecurityGroupRule, desired []SecurityGroupRule) ([]SecurityGroupRule, []SecurityGroupRule) {
+	cur := map[string]bool{}
+	for _, r := range current {
+		cur[r.key()] = true
+	}
+	des := map[string]bool{}
+	add := []SecurityGroupRule{}
+	for _, r := range desired {
+		k := r.key()
+		if !cur[k] && !des[k] {
+			add = append(add, r)
+		}
+		des[k] = true
+	}
+	remove := []SecurityGroupRule{}
+	for _, r := range current {
+		if !des[r.key()] {
+			remove = append(remove, r)
+		}
+	}
+	return add, remove
+}
+
+func (aws *RealAWSService) CreateSecurityGroup(sgd *SecurityGroupDefinition) (string, error) {
+	o, err := aws.ec2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+		GroupName:         &sgd.Name,
+		Description:       &sgd.Description,
+		VpcId:             &sgd.VPC,
+		TagSpecifications: ec2TagSpecification(ec2.ResourceTypeSecurityGroup, sgd.Tags),
+	})
+	if err != nil {
+		return "", wrapError("CreateSecurityGroup", err)
+	}
+	return drefStringPtr(o.GroupId), nil
+}
+
+func (aws *RealAWSService) DeleteSecurityGroup(id string) error {
+	_, err := aws.ec2.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{
+		GroupId: &id,
+	})
+	return wrapError("DeleteSecurityGroup", err)
+}
+
+func (aws *RealAWSService) AuthorizeIngress(id string, rules []SecurityGroupRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	perms, err := toIPPermissions(rules)
+	if err != nil {
+		return err
+	}
+	_, err = aws.ec2.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       &id,
+		IpPermissions: perms,
+	})
+	return wrapError("AuthorizeIngress", err)
+}
+
+func (aws *RealAWSService) RevokeIngress(id string, rules []SecurityGroupRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	perms, err := toIPPermissions(rules)
+	if err != nil {
+		return err
+	}
+	_, err = aws.ec2.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       &id,
+		IpPermissions: perms,
+	})
+	return wrapError("RevokeIngress", err)
+}
+
+func (aws *RealAWSService) AuthorizeEgress(id string, rules []SecurityGroupRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	perms, err := toIPPermissions(rules)
+	if err != nil {
+		return err
+	}
+	_, err = aws.ec2.AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
+		GroupId:       &id,
+		IpPermissions: perms,
+	})
+	return wrapError("AuthorizeEgress", err)
+}
+
+func (aws *RealAWSService) RevokeEgress(id string, rules []SecurityGroupRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	perms, err := toIPPermissions(rules)
+	if err != nil {
+		return err
+	}
+	_, err = aws.ec2.RevokeSecurityGroupEgress(&ec2.RevokeSecurityGroupEgressInput{
+		GroupId:       &id,
+		IpPermissions: perms,
+	})
+	return wrapError("RevokeEgress", err)
+}
+
+func securityGroupInfo(sg *ec2.SecurityGroup) SecurityGroupInfo {
+	return SecurityGroupInfo{
+		ID:          drefStringPtr(sg.GroupId),
+		Name:        drefStringPtr(sg.GroupName),
+		Description: drefStringPtr(sg.Description),
+		VPC:         drefStringPtr(sg.VpcId),
+		Ingress:     fromIPPermissions(sg.IpPermissions),
+		Egress:      fromIPPermissions(sg.IpPermissionsEgress),
+		Tags:        ec2TagMap(sg.Tags),
+	}
+}
+
+func (aws *RealAWSService) describeSecurityGroups(dsgi *ec2.DescribeSecurityGroupsInput) ([]SecurityGroupInfo, error) {
+	result := []SecurityGroupInfo{}
+	err := aws.ec2.DescribeSecurityGroupsPages(dsgi, func(page *ec2.DescribeSecurityGroupsOutput, last bool) bool {
+		for _, sg := range page.SecurityGroups {
+			result = append(result, securityGroupInfo(sg))
+		}
+		return true
+	})
+	return result, err
+}
+
+func (aws *RealAWSService) GetSecurityGroupsInfo(ids []string) ([]SecurityGroupInfo, error) {
+	sgs, err := aws.describeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: stringSlicetoStringPointerSlice(ids),
+	})
+	return sgs, wrapError("GetSecurityGroupsInfo", err)
+}
+
+// FindSecurityGroups returns the groups in a VPC carrying all of the given tags (tags may be empty)
+func (aws *RealAWSService) FindSecurityGroups(vpc string, tags map[string]string) ([]SecurityGroupInfo, error) {
+	sgs, err := aws.describeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: ec2Filters(map[string]string{"vpc-id": vpc}, tags),
+	})
+	return sgs, wrapError("FindSecurityGroups", err)
+}
+
+// ReconcileSecurityGroup authorizes and revokes rules so that the group's rules exactly match ingress and egress.
+// Rules are compared irrespective of description. It is safe to call repeatedly.
+// A nil ingress or egress leaves those rules unchanged (for egress, including AWS's default allow-all rule);
+// an empty, non-nil slice revokes them all.
+func (aws *RealAWSService) ReconcileSecurityGroup(id string, ingress []SecurityGroupRule, egress []SecurityGroupRule) (*SecurityGroupRuleChanges, error) {
+	changes := &SecurityGroupRuleChanges{}
+	for _, rules := range [][]SecurityGroupRule{ingress, egress} {
+		for _, r := range rules {
+			if err := r.validate(); err != nil {
+				return changes, err
+			}
+		}
+	}
+	sgs, err := aws.GetSecurityGroupsInfo([]string{id})
+	if err != nil {
+		return changes, err
+	}
+	if len(sgs) != 1 {
+		return changes, notFoundError("ReconcileSecurityGroup", "security group not found: %v", id)
+	}
+	addi, remi := []SecurityGroupRule{}, []SecurityGroupRule{}
+	if ingress != nil {
+		addi, remi = diffSecurityGroupRules(sgs[0].Ingress, ingress)
+	}
+	adde, reme := []SecurityGroupRule{}, []SecurityGroupRule{}
+	if egress != nil {
+		adde, reme = diffSecurityGroupRules(sgs[0].Egress, egress)
+	}
+	// authorize before revoking so traffic matching both old and new rules is never interrupted
+	if err := aws.AuthorizeIngress(id, addi); err != nil {
+		return changes, err
+	}
+	changes.AuthorizedIngress = addi
+	if err := aws.AuthorizeEgress(id, adde); err != nil {
+		return changes, err
+	}
+	changes.AuthorizedEgress = adde
+	if err := aws.RevokeIngress(id, remi); err != nil {
+		return changes, err
+	}
+	changes.RevokedIngress = remi
+	if err := aws.RevokeEgress(id, reme); err != nil {
+		return changes, err
+	}
+	changes.RevokedEgress = reme
+	return changes, nil
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) CreateSecurityGroup(sgd *SecurityGroupDefinition) (string, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "CreateSecurityGroup",
+		NotableParams: map[string]string{
+			"name": sgd.Name,
+			"vpc":  sgd.VPC,
+		},
+	})
+	return "", nil
+}
+
+func (aws *TestingAWSService) DeleteSecurityGroup(id string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteSecurityGroup",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) AuthorizeIngress(id string, rules []SecurityGroupRule) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AuthorizeIngress",
+		NotableParams: map[string]string{
+			"id":    id,
+			"rules": fmt.Sprintf("%v", rules),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) RevokeIngress(id string, rules []SecurityGroupRule) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "RevokeIngress",
+		NotableParams: map[string]string{
+			"id":    id,
+			"rules": fmt.Sprintf("%v", rules),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) AuthorizeEgress(id string, rules []SecurityGroupRule) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "AuthorizeEgress",
+		NotableParams: map[string]string{
+			"id":    id,
+			"rules": fmt.Sprintf("%v", rules),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) RevokeEgress(id string, rules []SecurityGroupRule) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "RevokeEgress",
+		NotableParams: map[string]string{
+			"id":    id,
+			"rules": fmt.Sprintf("%v", rules),
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetSecurityGroupsInfo(ids []string) ([]SecurityGroupInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetSecurityGroupsInfo",
+		NotableParams: map[string]string{
+			"ids": fmt.Sprintf("%v", ids),
+		},
+	})
+	return []SecurityGroupInfo{}, nil
+}
+
+func (aws *TestingAWSService) FindSecurityGroups(vpc string, tags map[string]string) ([]SecurityGroupInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindSecurityGroups",
+		NotableParams: map[string]string{
+			"vpc":  vpc,
+			"tags": fmt.Sprintf("%v", tags),
+		},
+	})
+	return []SecurityGroupInfo{}, nil
+}
+
+func (aws *TestingAWSService) ReconcileSecurityGroup(id string, ingress []SecurityGroupRule, egress []SecurityGroupRule) (*SecurityGroupRuleChanges, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "ReconcileSecurityGroup",
+		NotableParams: map[string]string{
+			"id":      id,
+			"ingress": fmt.Sprintf("%v", ingress),
+			"egress":  fmt.Sprintf("%v", egress),
+		},
+	})
+	return &SecurityGroupRuleChanges{}, nil
+}
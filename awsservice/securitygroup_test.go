@@ -0,0 +1,96 @@
+package awsservice
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiffSecurityGroupRules(t *testing.T) {
+	current := []SecurityGroupRule{
+		{Protocol: "tcp", FromPort: 22, ToPort: 22, CIDR: "10.0.0.0/8"},
+		{Protocol: "-1", CIDR: "0.0.0.0/0"},
+	}
+	desired := []SecurityGroupRule{
+		{Protocol: "TCP", FromPort: 22, ToPort: 22, CIDR: "10.0.0.0/8", Description: "ssh"},
+		{Protocol: "tcp", FromPort: 443, ToPort: 443, SourceGroupID: "sg-123456"},
+	}
+	add, remove := diffSecurityGroupRules(current, desired)
+	if len(add) != 1 || add[0].SourceGroupID != "sg-123456" {
+		t.Fatalf("incorrect rules to add: %v", add)
+	}
+	if len(remove) != 1 || remove[0].CIDR != "0.0.0.0/0" {
+		t.Fatalf("incorrect rules to remove: %v", remove)
+	}
+	add, remove = diffSecurityGroupRules(current, current)
+	if len(add) != 0 || len(remove) != 0 {
+		t.Fatalf("identical rules should have no diff: %v: %v", add, remove)
+	}
+}
+
+func TestIPPermissionsRoundTrip(t *testing.T) {
+	rules := []SecurityGroupRule{
+		{Protocol: "tcp", FromPort: 80, ToPort: 80, CIDR: "::/0"},
+		{Protocol: "all", PrefixListID: "pl-123456"},
+	}
+	perms, err := toIPPermissions(rules)
+	if err != nil {
+		t.Fatalf("error converting rules: %v", err)
+	}
+	if len(perms[0].Ipv6Ranges) != 1 || len(perms[1].PrefixListIds) != 1 {
+		t.Fatalf("incorrect permissions: %v", perms)
+	}
+	if add, remove := diffSecurityGroupRules(fromIPPermissions(perms), rules); len(add) != 0 || len(remove) != 0 {
+		t.Fatalf("round trip should have no diff: %v: %v", add, remove)
+	}
+	if _, err := toIPPermissions([]SecurityGroupRule{{Protocol: "tcp", CIDR: "10.0.0.0/8", SourceGroupID: "sg-1"}}); err == nil {
+		t.Fatalf("should have failed with multiple sources")
+	}
+}
+
+const ec2DescribeSecurityGroupResponse = `<DescribeSecurityGroupsResponse><securityGroupInfo><item><groupId>sg-1</groupId>` +
+	`<ipPermissions><item><ipProtocol>tcp</ipProtocol><fromPort>22</fromPort><toPort>22</toPort><ipRanges><item><cidrIp>10.0.0.0/8</cidrIp></item></ipRanges></item></ipPermissions>` +
+	`<ipPermissionsEgress><item><ipProtocol>-1</ipProtocol><ipRanges><item><cidrIp>0.0.0.0/0</cidrIp></item></ipRanges></item></ipPermissionsEgress>` +
+	`</item></securityGroupInfo></DescribeSecurityGroupsResponse>`
+
+func TestReconcileSecurityGroupEgress(t *testing.T) {
+	actions := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action := r.Form.Get("Action")
+		if action == "DescribeSecurityGroups" {
+			w.Write([]byte(ec2DescribeSecurityGroupResponse))
+			return
+		}
+		actions = append(actions, action)
+		fmt.Fprintf(w, `<%vResponse><return>true</return></%vResponse>`, action, action)
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	ingress := []SecurityGroupRule{{Protocol: "tcp", FromPort: 443, ToPort: 443, CIDR: "10.0.0.0/8"}}
+	changes, err := svc.ReconcileSecurityGroup("sg-1", ingress, nil)
+	if err != nil {
+		t.Fatalf("error reconciling: %v", err)
+	}
+	if strings.Join(actions, " ") != "AuthorizeSecurityGroupIngress RevokeSecurityGroupIngress" || len(changes.RevokedEgress) != 0 {
+		t.Fatalf("nil egress should leave egress unchanged: %v: %+v", actions, changes)
+	}
+	actions = []string{}
+	changes, err = svc.ReconcileSecurityGroup("sg-1", ingress, []SecurityGroupRule{})
+	if err != nil {
+		t.Fatalf("error reconciling: %v", err)
+	}
+	if !stringInSlice("RevokeSecurityGroupEgress", actions) || len(changes.RevokedEgress) != 1 {
+		t.Fatalf("empty egress should revoke the default rule: %v: %+v", actions, changes)
+	}
+	actions = []string{}
+	changes, err = svc.ReconcileSecurityGroup("sg-1", nil, []SecurityGroupRule{})
+	if err != nil {
+		t.Fatalf("error reconciling: %v", err)
+	}
+	if strings.Join(actions, " ") != "RevokeSecurityGroupEgress" || len(changes.RevokedIngress) != 0 || len(changes.AuthorizedIngress) != 0 {
+		t.Fatalf("nil ingress should leave ingress unchanged: %v: %+v", actions, changes)
+	}
+}
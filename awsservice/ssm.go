@@ -0,0 +1,215 @@
+package awsservice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Parameter types
+const (
+	ParameterString       = ssm.ParameterTypeString
+	ParameterStringList   = ssm.ParameterTypeStringList
+	ParameterSecureString = ssm.ParameterTypeSecureString
+)
+
+type ParameterDefinition struct {
+	Name        string
+	Value       string
+	Type        string // Optional (default: ParameterString)
+	Description string
+	KMSKeyID    string // Optional key for SecureString (default: the AWS managed key)
+	Overwrite   bool   // Replace an existing parameter, creating a new version
+	Tags        map[string]string
+}
+
+type ParameterInfo struct {
+	Name         string
+	Type         string
+	Value        string // Ciphertext for SecureString unless decrypted
+	Version      int64
+	LastModified time.Time
+	ARN          string
+	ModifiedBy   string   // Only set by GetParameterHistory
+	Labels       []string // Only set by GetParameterHistory
+}
+
+func parameterInfo(p *ssm.Parameter) ParameterInfo {
+	return ParameterInfo{
+		Name:         drefStringPtr(p.Name),
+		Type:         drefStringPtr(p.Type),
+		Value:        drefStringPtr(p.Value),
+		Version:      drefInt64Ptr(p.Version),
+		LastModified: drefTimePtr(p.LastModifiedDate),
+		ARN:          drefStringPtr(p.ARN),
+	}
+}
+
+func (aws *RealAWSService) GetParameter(name string, decrypt bool) (*ParameterInfo, error) {
+	result := &ParameterInfo{}
+	res, err := aws.ssmc.GetParameter(&ssm.GetParameterInput{
+		Name:           &name,
+		WithDecryption: &decrypt,
+	})
+	if err != nil {
+		return result, wrapError("GetParameter", err)
+	}
+	if res.Parameter != nil {
+		*result = parameterInfo(res.Parameter)
+	}
+	return result, nil
+}
+
+// GetParametersByPath returns the parameters under a hierarchy path (eg "/myapp/production/"),
+// descending into sub-paths if recursive is true
+func (aws *RealAWSService) GetParametersByPath(path string, recursive bool, decrypt bool) ([]ParameterInfo, error) {
+	result := []ParameterInfo{}
+	gpbpi := &ssm.GetParametersByPathInput{
+		Path:           &path,
+		Recursive:      &recursive,
+		WithDecryption: &decrypt,
+	}
+	err := aws.ssmc.GetParametersByPathPages(gpbpi, func(page *ssm.GetParametersByPathOutput, last bool) bool {
+		for _, p := range page.Parameters {
+			result = append(result, parameterInfo(p))
+		}
+		return true
+	})
+	return result, wrapError("GetParametersByPath", err)
+}
+
+// PutParameter creates or (with Overwrite) updates a parameter, returning the new version
+func (aws *RealAWSService) PutParameter(pd *ParameterDefinition) (int64, error) {
+	pt := pd.Type
+	if pt == "" {
+		pt = ParameterString
+	}
+	ppi := &ssm.PutParameterInput{
+		Name:      &pd.Name,
+		Value:     &pd.Value,
+		Type:      &pt,
+		Overwrite: &pd.Overwrite,
+	}
+	if pd.Description != "" {
+		ppi.Description = &pd.Description
+	}
+	if pd.KMSKeyID != "" {
+		ppi.KeyId = &pd.KMSKeyID
+	}
+	tags := []*ssm.Tag{}
+	for k, v := range pd.Tags {
+		ck := k
+		cv := v
+		tags = append(tags, &ssm.Tag{Key: &ck, Value: &cv})
+	}
+	// tags cannot be given alongside Overwrite, so are applied separately in that case
+	if len(tags) > 0 && !pd.Overwrite {
+		ppi.Tags = tags
+	}
+	res, err := aws.ssmc.PutParameter(ppi)
+	if err != nil {
+		return 0, wrapError("PutParameter", err)
+	}
+	if len(tags) > 0 && pd.Overwrite {
+		rt := ssm.ResourceTypeForTaggingParameter
+		_, err := aws.ssmc.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceId:   &pd.Name,
+			ResourceType: &rt,
+			Tags:         tags,
+		})
+		if err != nil {
+			return drefInt64Ptr(res.Version), fmt.Errorf("error tagging parameter %v: %w", pd.Name, wrapError("PutParameter", err))
+		}
+	}
+	return drefInt64Ptr(res.Version), nil
+}
+
+func (aws *RealAWSService) DeleteParameter(name string) error {
+	_, err := aws.ssmc.DeleteParameter(&ssm.DeleteParameterInput{
+		Name: &name,
+	})
+	return wrapError("DeleteParameter", err)
+}
+
+// GetParameterHistory returns every version of a parameter, oldest first
+func (aws *RealAWSService) GetParameterHistory(name string, decrypt bool) ([]ParameterInfo, error) {
+	result := []ParameterInfo{}
+	gphi := &ssm.GetParameterHistoryInput{
+		Name:           &name,
+		WithDecryption: &decrypt,
+	}
+	err := aws.ssmc.GetParameterHistoryPages(gphi, func(page *ssm.GetParameterHistoryOutput, last bool) bool {
+		for _, p := range page.Parameters {
+			result = append(result, ParameterInfo{
+				Name:         drefStringPtr(p.Name),
+				Type:         drefStringPtr(p.Type),
+				Value:        drefStringPtr(p.Value),
+				Version:      drefInt64Ptr(p.Version),
+				LastModified: drefTimePtr(p.LastModifiedDate),
+				ModifiedBy:   drefStringPtr(p.LastModifiedUser),
+				Labels:       stringPointerSlicetoStringSlice(p.Labels),
+			})
+		}
+		return true
+	})
+	return result, wrapError("GetParameterHistory", err)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) GetParameter(name string, decrypt bool) (*ParameterInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetParameter",
+		NotableParams: map[string]string{
+			"name":    name,
+			"decrypt": fmt.Sprintf("%v", decrypt),
+		},
+	})
+	return &ParameterInfo{Name: name}, nil
+}
+
+func (aws *TestingAWSService) GetParametersByPath(path string, recursive bool, decrypt bool) ([]ParameterInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetParametersByPath",
+		NotableParams: map[string]string{
+			"path":      path,
+			"recursive": fmt.Sprintf("%v", recursive),
+			"decrypt":   fmt.Sprintf("%v", decrypt),
+		},
+	})
+	return []ParameterInfo{}, nil
+}
+
+func (aws *TestingAWSService) PutParameter(pd *ParameterDefinition) (int64, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "PutParameter",
+		NotableParams: map[string]string{
+			"name":      pd.Name,
+			"type":      pd.Type,
+			"overwrite": fmt.Sprintf("%v", pd.Overwrite),
+		},
+	})
+	return 1, nil
+}
+
+func (aws *TestingAWSService) DeleteParameter(name string) error {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "DeleteParameter",
+		NotableParams: map[string]string{
+			"name": name,
+		},
+	})
+	return nil
+}
+
+func (aws *TestingAWSService) GetParameterHistory(name string, decrypt bool) ([]ParameterInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetParameterHistory",
+		NotableParams: map[string]string{
+			"name":    name,
+			"decrypt": fmt.Sprintf("%v", decrypt),
+		},
+	})
+	return []ParameterInfo{}, nil
+}
@@ -0,0 +1,112 @@
+package awsservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ssmFake answers SSM JSON API operations (selected by the X-Amz-Target header) with canned response bodies,
+// recording the decoded request bodies
+type ssmFake struct {
+	mu        sync.Mutex
+	responses map[string][]string // operation -> response bodies, returned in turn
+	requests  []map[string]interface{}
+}
+
+func (f *ssmFake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&req)
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	bodies := f.responses[op]
+	if len(bodies) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"InvalidAction","message":"unexpected operation"}`))
+		return
+	}
+	w.Write([]byte(bodies[0]))
+	if len(bodies) > 1 {
+		f.responses[op] = bodies[1:]
+	}
+}
+
+func newSSMFakeService(responses map[string][]string) (*RealAWSService, *ssmFake, func()) {
+	f := &ssmFake{responses: responses}
+	ts := httptest.NewServer(f)
+	return newEndpointAWSService(ts.URL), f, ts.Close
+}
+
+func TestGetParametersByPath(t *testing.T) {
+	svc, f, done := newSSMFakeService(map[string][]string{
+		"GetParametersByPath": {
+			`{"Parameters":[{"Name":"/app/prod/db","Type":"SecureString","Value":"secret","Version":2,"ARN":"arn:aws:ssm:us-west-2:123456789012:parameter/app/prod/db"}],"NextToken":"t1"}`,
+			`{"Parameters":[{"Name":"/app/prod/host","Type":"String","Value":"db.internal","Version":1,"LastModifiedDate":1.7924256E9}]}`,
+		},
+	})
+	defer done()
+	pis, err := svc.GetParametersByPath("/app/prod/", true, true)
+	if err != nil {
+		t.Fatalf("error getting parameters: %v", err)
+	}
+	if len(pis) != 2 || pis[0].Name != "/app/prod/db" || pis[0].Value != "secret" || pis[0].Version != 2 || pis[0].ARN == "" ||
+		pis[1].Name != "/app/prod/host" || pis[1].LastModified.IsZero() {
+		t.Fatalf("bad parameters: %+v", pis)
+	}
+	if len(f.requests) != 2 {
+		t.Fatalf("bad requests: %v", f.requests)
+	}
+	for _, req := range f.requests {
+		if req["Path"] != "/app/prod/" || req["Recursive"] != true || req["WithDecryption"] != true {
+			t.Fatalf("bad request: %v", req)
+		}
+	}
+	if f.requests[0]["NextToken"] != nil || f.requests[1]["NextToken"] != "t1" {
+		t.Fatalf("bad paging: %v", f.requests)
+	}
+}
+
+func TestGetParameterHistory(t *testing.T) {
+	svc, f, done := newSSMFakeService(map[string][]string{
+		"GetParameterHistory": {
+			`{"Parameters":[` +
+				`{"Name":"/app/prod/db","Type":"String","Value":"a","Version":1,"LastModifiedUser":"arn:aws:iam::123456789012:user/alice"},` +
+				`{"Name":"/app/prod/db","Type":"String","Value":"b","Version":2,"LastModifiedUser":"arn:aws:iam::123456789012:user/bob","Labels":["live"]}]}`,
+		},
+	})
+	defer done()
+	pis, err := svc.GetParameterHistory("/app/prod/db", false)
+	if err != nil {
+		t.Fatalf("error getting history: %v", err)
+	}
+	if len(pis) != 2 || pis[0].Value != "a" || pis[1].Version != 2 || !strings.HasSuffix(pis[1].ModifiedBy, "user/bob") ||
+		strings.Join(pis[1].Labels, ",") != "live" || len(pis[0].Labels) != 0 {
+		t.Fatalf("bad history: %+v", pis)
+	}
+	if req := f.requests[0]; req["Name"] != "/app/prod/db" || req["WithDecryption"] != false {
+		t.Fatalf("bad request: %v", req)
+	}
+}
+
+func TestGetParameterNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"ParameterNotFound","message":"parameter not found"}`))
+	}))
+	defer ts.Close()
+	svc := newEndpointAWSService(ts.URL)
+	if _, err := svc.GetParameter("/app/prod/missing", false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+	if _, err := svc.GetParameterHistory("/app/prod/missing", false); !IsNotFound(err) {
+		t.Fatalf("should have been not found: %v", err)
+	}
+}
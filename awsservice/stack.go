@@ -0,0 +1,493 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StackTagKey is the instance and load balancer tag identifying the stack a resource belongs to
+const StackTagKey = "awsservice:stack"
+
+const defaultStackDNSTTL = 300
+
+// Stack change actions
+const (
+	StackCreate     = "create"
+	StackUpdate     = "update"
+	StackDelete     = "delete"
+	StackRegister   = "register"
+	StackDeregister = "deregister"
+)
+
+// Stack resources
+const (
+	StackLoadBalancer = "load_balancer"
+	StackHealthCheck  = "health_check"
+	StackInstances    = "instances"
+	StackDNSRecord    = "dns_record"
+)
+
+// StackDefinition describes a service as a group of instances behind a classic load balancer with a DNS record
+// pointing at it. Every component is optional; components left nil are not managed.
+//
+// Instances are found via StackTagKey, so Instances.Count is the number of live instances the stack should have.
+// The load balancer is found by name and its registered instances are made to match the stack's instances. It is
+// tagged with StackTagKey when the stack creates it; a load balancer of the same name without that tag is reported
+// as a conflict (ErrAlreadyExists) rather than adopted or destroyed.
+// If DNS.Value is empty the record points at the load balancer DNS name (DNS.Type defaults to CNAME and
+// DNS.TTL to 300).
+//
+// Only the instance count, the health check, registration and the DNS record are converged. The load balancer's
+// listeners, subnets and security groups and the instances' AMI, type and other launch settings are used when
+// creating them and not compared afterwards; changing them requires destroying and re-creating the load balancer,
+// or scaling the instances down to zero and back up.
+type StackDefinition struct {
+	Name         string
+	Instances    *InstancesDefinition
+	LoadBalancer *LoadBalancerDefinition
+	HealthCheck  *LBHealthCheck
+	DNS          *Route53RecordDefinition
+}
+
+// StackChange is a single step of a stack plan
+type StackChange struct {
+	Action   string
+	Resource string
+	Name     string   // Load balancer or record name
+	IDs      []string // Existing instances affected
+	Count    int      // Instances to launch (StackCreate), or new instances to register (StackRegister)
+	Value    string   // DNS record value, empty if it will be the DNS name of a load balancer not yet created
+	record   *Route53RecordDefinition
+}
+
+func (sc StackChange) String() string {
+	desc := fmt.Sprintf("%v %v", sc.Action, sc.Resource)
+	if sc.Name != "" {
+		desc += " " + sc.Name
+	}
+	if len(sc.IDs) > 0 {
+		desc += fmt.Sprintf(" %v", sc.IDs)
+	}
+	if sc.Count > 0 {
+		desc += fmt.Sprintf(" (%v new)", sc.Count)
+	}
+	if sc.Value != "" {
+		desc += " -> " + sc.Value
+	}
+	return desc
+}
+
+// StackPlan is the ordered list of changes needed to bring a stack to its definition (or to destroy it)
+type StackPlan struct {
+	Stack   string
+	Changes []StackChange
+}
+
+// Empty reports whether the stack already matches its definition
+func (sp *StackPlan) Empty() bool {
+	return len(sp.Changes) == 0
+}
+
+func (sp *StackPlan) String() string {
+	if sp.Empty() {
+		return fmt.Sprintf("stack %v: no changes", sp.Stack)
+	}
+	lines := []string{fmt.Sprintf("stack %v:", sp.Stack)}
+	for _, c := range sp.Changes {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the definition without calling AWS
+func (sd *StackDefinition) Validate() error {
+	ve := &ValidationError{}
+	if sd.Name == "" {
+		ve.add("stack name is required")
+	}
+	if sd.Instances != nil && sd.Instances.Count > 0 {
+		if err := sd.Instances.Validate(); err != nil {
+			ve.add("instances: %v", err)
+		}
+	}
+	if sd.LoadBalancer != nil && sd.LoadBalancer.Name == "" {
+		ve.add("load balancer name is required")
+	}
+	if sd.HealthCheck != nil && sd.LoadBalancer == nil {
+		ve.add("health check requires a load balancer")
+	}
+	if sd.DNS != nil {
+		if sd.DNS.ZoneID == "" || sd.DNS.Name == "" {
+			ve.add("DNS record zone ID and name are required")
+		}
+		if sd.DNS.Value == "" && sd.LoadBalancer == nil {
+			ve.add("DNS record value is required without a load balancer")
+		}
+	}
+	return ve.errOrNil()
+}
+
+func (sd *StackDefinition) dnsRecord() *Route53RecordDefinition {
+	rd := *sd.DNS
+	if rd.Type == "" {
+		rd.Type = "CNAME"
+	}
+	if rd.TTL == 0 {
+		rd.TTL = defaultStackDNSTTL
+	}
+	return &rd
+}
+
+// stackState is what currently exists for a stack
+type stackState struct {
+	instances []InstanceInfo // live (not terminated or terminating) instances carrying the stack tag
+	lb        *LoadBalancerInfo
+	dns       []Route53RecordDefinition // record sets with the stack DNS name, one per type
+}
+
+func liveInstance(state string) bool {
+	return state != "terminated" && state != "shutting-down"
+}
+
+func observeStack(svc AWSService, sd *StackDefinition) (*stackState, error) {
+	st := &stackState{}
+	ids, err := svc.FindInstancesByTag(StackTagKey, sd.Name)
+	if err != nil {
+		return st, fmt.Errorf("error finding stack instances: %w", err)
+	}
+	if len(ids) > 0 {
+		insts, err := svc.GetInstancesInfo(ids)
+		if err != nil {
+			return st, fmt.Errorf("error getting stack instances: %w", err)
+		}
+		for _, inst := range insts {
+			if liveInstance(inst.State) {
+				st.instances = append(st.instances, inst)
+			}
+		}
+	}
+	if sd.LoadBalancer != nil {
+		lb, err := svc.GetLoadBalancerInfo(sd.LoadBalancer.Name)
+		switch {
+		case IsNotFound(err):
+		case err != nil:
+			return st, fmt.Errorf("error getting load balancer: %w", err)
+		default:
+			tags, err := svc.GetLoadBalancerTags(lb.Name)
+			if err != nil {
+				return st, fmt.Errorf("error getting load balancer tags: %w", err)
+			}
+			if tags[StackTagKey] != sd.Name {
+				return st, fmt.Errorf("load balancer %v does not belong to stack %v (missing tag %v=%v): %w", lb.Name, sd.Name, StackTagKey, sd.Name, ErrAlreadyExists)
+			}
+			st.lb = lb
+		}
+	}
+	if sd.DNS != nil {
+		recs, err := svc.GetDNSRecords(sd.DNS.ZoneID, sd.DNS.Name)
+		if err != nil {
+			return st, fmt.Errorf("error getting DNS records: %w", err)
+		}
+		st.dns = DNSRecordSets(recs)
+	}
+	return st, nil
+}
+
+// instancesToTerminate picks n instances to remove, preferring those that are not running
+func instancesToTerminate(insts []InstanceInfo, n int) []string {
+	sorted := append([]InstanceInfo{}, insts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := sorted[i].State == "running", sorted[j].State == "running"
+		if ri != rj {
+			return !ri
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	ids := []string{}
+	for _, inst := range sorted[:n] {
+		ids = append(ids, inst.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// stackTarget normalizes a record value or alias target for comparison with the stack's load balancer and
+// instances (alias targets of load balancers carry a "dualstack." prefix and a trailing dot)
+func stackTarget(s string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(s, ".")), "dualstack.")
+}
+
+// ownsRecord reports whether every value of rd (or its alias target) is the DNS name of the stack's load balancer
+// or an IP address of one of its instances
+func (st *stackState) ownsRecord(rd *Route53RecordDefinition) bool {
+	targets := map[string]bool{}
+	if st.lb != nil && st.lb.DNSName != "" {
+		targets[stackTarget(st.lb.DNSName)] = true
+	}
+	for _, inst := range st.instances {
+		for _, ip := range []string{inst.PrivateIP, inst.PublicIP} {
+			if ip != "" {
+				targets[ip] = true
+			}
+		}
+	}
+	if rd.Alias != nil {
+		return targets[stackTarget(rd.Alias.DNSName)]
+	}
+	for _, v := range rd.Values() {
+		if !targets[stackTarget(v)] {
+			return false
+		}
+	}
+	return true
+}
+
+func healthCheckEqual(a *LBHealthCheck, b *LBHealthCheck) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+func sameDNSName(a string, b string) bool {
+	return strings.TrimSuffix(a, ".") == strings.TrimSuffix(b, ".")
+}
+
+// planStack computes the changes bringing st to sd, in dependency order: load balancer, health check,
+// instances, registration, DNS
+func planStack(sd *StackDefinition, st *stackState) []StackChange {
+	changes := []StackChange{}
+	if sd.LoadBalancer != nil {
+		if st.lb == nil {
+			changes = append(changes, StackChange{Action: StackCreate, Resource: StackLoadBalancer, Name: sd.LoadBalancer.Name})
+		}
+		if sd.HealthCheck != nil && (st.lb == nil || !healthCheckEqual(st.lb.HealthCheck, sd.HealthCheck)) {
+			changes = append(changes, StackChange{Action: StackUpdate, Resource: StackHealthCheck, Name: sd.LoadBalancer.Name})
+		}
+	}
+	keep := map[string]bool{}
+	for _, inst := range st.instances {
+		keep[inst.ID] = true
+	}
+	launch := 0
+	if sd.Instances != nil {
+		switch n := sd.Instances.Count - len(st.instances); {
+		case n > 0:
+			launch = n
+			changes = append(changes, StackChange{Action: StackCreate, Resource: StackInstances, Count: n})
+		case n < 0:
+			excess := instancesToTerminate(st.instances, -n)
+			for _, id := range excess {
+				delete(keep, id)
+			}
+			if st.lb != nil {
+				registered := []string{}
+				for _, id := range excess {
+					if stringInSlice(id, st.lb.Instances) {
+						registered = append(registered, id)
+					}
+				}
+				if len(registered) > 0 {
+					changes = append(changes, StackChange{Action: StackDeregister, Resource: StackInstances, Name: sd.LoadBalancer.Name, IDs: registered})
+				}
+			}
+			changes = append(changes, StackChange{Action: StackDelete, Resource: StackInstances, IDs: excess})
+		}
+	}
+	if sd.LoadBalancer != nil {
+		registered := []string{}
+		if st.lb != nil {
+			registered = st.lb.Instances
+		}
+		stackIDs := st.instancesIDs()
+		unregister := []string{}
+		for _, id := range registered {
+			// stack instances being terminated were deregistered above
+			if !stringInSlice(id, stackIDs) {
+				unregister = append(unregister, id)
+			}
+		}
+		if len(unregister) > 0 {
+			changes = append(changes, StackChange{Action: StackDeregister, Resource: StackInstances, Name: sd.LoadBalancer.Name, IDs: unregister})
+		}
+		register := []string{}
+		for _, inst := range st.instances {
+			if keep[inst.ID] && !stringInSlice(inst.ID, registered) {
+				register = append(register, inst.ID)
+			}
+		}
+		if len(register) > 0 || launch > 0 {
+			changes = append(changes, StackChange{Action: StackRegister, Resource: StackInstances, Name: sd.LoadBalancer.Name, IDs: register, Count: launch})
+		}
+	}
+	if sd.DNS != nil {
+		rd := sd.dnsRecord()
+		if rd.Value == "" && st.lb != nil {
+			rd.Value = st.lb.DNSName
+		}
+		var current *Route53RecordDefinition
+		for i := range st.dns {
+			if st.dns[i].Type == rd.Type {
+				current = &st.dns[i]
+			}
+		}
+		switch {
+		case current == nil:
+			changes = append(changes, StackChange{Action: StackCreate, Resource: StackDNSRecord, Name: rd.Name, Value: rd.Value, record: rd})
+		case rd.Value == "" || !sameDNSName(current.Value, rd.Value) || len(current.ExtraValues) > 0 || current.TTL != rd.TTL:
+			changes = append(changes, StackChange{Action: StackUpdate, Resource: StackDNSRecord, Name: rd.Name, Value: rd.Value, record: rd})
+		}
+	}
+	return changes
+}
+
+// planStackDestroy computes the changes removing everything in st, in reverse dependency order. DNS record sets
+// not pointing at the stack's load balancer or instances are left alone.
+func planStackDestroy(sd *StackDefinition, st *stackState) []StackChange {
+	changes := []StackChange{}
+	if sd.DNS != nil {
+		rd := sd.dnsRecord()
+		for i := range st.dns {
+			if st.dns[i].Type == rd.Type && st.ownsRecord(&st.dns[i]) {
+				changes = append(changes, StackChange{Action: StackDelete, Resource: StackDNSRecord, Name: st.dns[i].Name, Value: strings.Join(st.dns[i].Values(), ","), record: &st.dns[i]})
+			}
+		}
+	}
+	if st.lb != nil {
+		changes = append(changes, StackChange{Action: StackDelete, Resource: StackLoadBalancer, Name: st.lb.Name})
+	}
+	if ids := st.instancesIDs(); len(ids) > 0 {
+		changes = append(changes, StackChange{Action: StackDelete, Resource: StackInstances, IDs: ids})
+	}
+	return changes
+}
+
+func (st *stackState) instancesIDs() []string {
+	ids := []string{}
+	for _, inst := range st.instances {
+		ids = append(ids, inst.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func stringInSlice(s string, sl []string) bool {
+	for _, v := range sl {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// applyStackChanges executes changes in order. On error the changes completed so far are returned.
+func applyStackChanges(svc AWSService, sd *StackDefinition, changes []StackChange) ([]StackChange, error) {
+	done := []StackChange{}
+	lbDNS := ""
+	newIDs := []string{}
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.Resource == StackLoadBalancer && c.Action == StackCreate:
+			lbd := *sd.LoadBalancer
+			lbd.Tags = map[string]string{}
+			for k, v := range sd.LoadBalancer.Tags {
+				lbd.Tags[k] = v
+			}
+			lbd.Tags[StackTagKey] = sd.Name
+			lbDNS, err = svc.CreateLoadBalancer(&lbd)
+		case c.Resource == StackLoadBalancer && c.Action == StackDelete:
+			err = svc.DeleteLoadBalancer(c.Name)
+		case c.Resource == StackHealthCheck:
+			err = svc.SetHealthCheck(c.Name, sd.HealthCheck)
+		case c.Resource == StackInstances && c.Action == StackCreate:
+			idef := *sd.Instances
+			idef.Count = c.Count
+			idef.Tags = map[string]string{}
+			for k, v := range sd.Instances.Tags {
+				idef.Tags[k] = v
+			}
+			idef.Tags[StackTagKey] = sd.Name
+			newIDs, err = svc.RunInstances(&idef)
+			c.IDs = newIDs
+		case c.Resource == StackInstances && c.Action == StackDelete:
+			err = svc.TerminateInstances(c.IDs)
+		case c.Resource == StackInstances && c.Action == StackDeregister:
+			err = svc.DeregisterInstances(c.Name, c.IDs)
+		case c.Resource == StackInstances && c.Action == StackRegister:
+			c.IDs = append(append([]string{}, c.IDs...), newIDs...)
+			c.Count = 0
+			err = svc.RegisterInstances(c.Name, c.IDs)
+		case c.Resource == StackDNSRecord && c.Action == StackDelete:
+			err = svc.DeleteDNSRecord(c.record)
+		case c.Resource == StackDNSRecord:
+			rd := *c.record
+			if rd.Value == "" {
+				rd.Value = lbDNS
+			}
+			if rd.Value == "" {
+				err = fmt.Errorf("load balancer DNS name is unknown")
+			}
+			if err == nil {
+				err = svc.UpsertDNSRecord(&rd)
+			}
+			c.Value = rd.Value
+		default:
+			err = fmt.Errorf("unknown change")
+		}
+		if err != nil {
+			return done, fmt.Errorf("error applying change (%v): %w", c, err)
+		}
+		done = append(done, c)
+	}
+	return done, nil
+}
+
+// PlanStack returns the changes ApplyStack would make, without making them
+func PlanStack(svc AWSService, sd *StackDefinition) (*StackPlan, error) {
+	plan := &StackPlan{Stack: sd.Name}
+	if err := sd.Validate(); err != nil {
+		return plan, err
+	}
+	st, err := observeStack(svc, sd)
+	if err != nil {
+		return plan, err
+	}
+	plan.Changes = planStack(sd, st)
+	return plan, nil
+}
+
+// ApplyStack creates, updates or removes resources so that the stack matches sd. It is safe to call repeatedly.
+// The returned plan holds the changes actually made (including those made before any error), with the IDs of
+// launched instances filled in.
+func ApplyStack(svc AWSService, sd *StackDefinition) (*StackPlan, error) {
+	plan, err := PlanStack(svc, sd)
+	if err != nil {
+		return plan, err
+	}
+	plan.Changes, err = applyStackChanges(svc, sd, plan.Changes)
+	return plan, err
+}
+
+// PlanStackDestroy returns the changes DestroyStack would make, without making them
+func PlanStackDestroy(svc AWSService, sd *StackDefinition) (*StackPlan, error) {
+	plan := &StackPlan{Stack: sd.Name}
+	if sd.Name == "" {
+		return plan, invalidDefinition("stack name is required")
+	}
+	st, err := observeStack(svc, sd)
+	if err != nil {
+		return plan, err
+	}
+	plan.Changes = planStackDestroy(sd, st)
+	return plan, nil
+}
+
+// DestroyStack removes the stack's DNS record, load balancer and instances, in that order. The DNS record is only
+// removed if it points at the stack's load balancer or instances.
+func DestroyStack(svc AWSService, sd *StackDefinition) (*StackPlan, error) {
+	plan, err := PlanStackDestroy(svc, sd)
+	if err != nil {
+		return plan, err
+	}
+	plan.Changes, err = applyStackChanges(svc, sd, plan.Changes)
+	return plan, err
+}
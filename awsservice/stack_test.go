@@ -0,0 +1,263 @@
+package awsservice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testStackDefinition() *StackDefinition {
+	idef := testInstancesDefinition()
+	idef.Count = 2
+	return &StackDefinition{
+		Name:         "web",
+		Instances:    idef,
+		LoadBalancer: &LoadBalancerDefinition{Name: "web-lb"},
+		HealthCheck:  &LBHealthCheck{Target: "HTTP:80/health", Interval: 10, Timeout: 5, HealthyThreshold: 2, UnhealthyThreshold: 2},
+		DNS:          &Route53RecordDefinition{ZoneID: "Z123", Name: "web.example.com"},
+	}
+}
+
+func stackChangeStrings(changes []StackChange) []string {
+	out := []string{}
+	for _, c := range changes {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+func checkStackChanges(t *testing.T, changes []StackChange, expected []string) {
+	got := stackChangeStrings(changes)
+	if len(got) != len(expected) {
+		t.Fatalf("bad changes: %q (expected %q)", got, expected)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("bad change %v: %q (expected %q)", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestPlanStackCreate(t *testing.T) {
+	sd := testStackDefinition()
+	if err := sd.Validate(); err != nil {
+		t.Fatalf("should have been valid: %v", err)
+	}
+	checkStackChanges(t, planStack(sd, &stackState{}), []string{
+		"create load_balancer web-lb",
+		"update health_check web-lb",
+		"create instances (2 new)",
+		"register instances web-lb (2 new)",
+		"create dns_record web.example.com",
+	})
+}
+
+func TestPlanStackConverged(t *testing.T) {
+	sd := testStackDefinition()
+	st := &stackState{
+		instances: []InstanceInfo{{ID: "i-1", State: "running"}, {ID: "i-2", State: "running"}},
+		lb: &LoadBalancerInfo{
+			Name:        "web-lb",
+			DNSName:     "web-lb-123.us-west-2.elb.amazonaws.com",
+			Instances:   []string{"i-1", "i-2"},
+			HealthCheck: &LBHealthCheck{Target: "HTTP:80/health", Interval: 10, Timeout: 5, HealthyThreshold: 2, UnhealthyThreshold: 2},
+		},
+		dns: []Route53RecordDefinition{{ZoneID: "Z123", Name: "web.example.com.", Type: "CNAME", TTL: 300, Value: "web-lb-123.us-west-2.elb.amazonaws.com"}},
+	}
+	if changes := planStack(sd, st); len(changes) != 0 {
+		t.Fatalf("should have been converged: %q", stackChangeStrings(changes))
+	}
+}
+
+func TestPlanStackIgnoresCreationSettings(t *testing.T) {
+	sd := testStackDefinition()
+	sd.Instances.AMI = "ami-new"
+	sd.Instances.Type = "m5.large"
+	sd.LoadBalancer.Listeners = []ELBListener{{InstancePort: 8080, LoadBalancerPort: 443, LoadBalancerProtocol: "HTTPS", InstanceProtocol: "HTTP"}}
+	sd.LoadBalancer.Subnets = []string{"subnet-new"}
+	sd.LoadBalancer.SecurityGroups = []string{"sg-new"}
+	st := &stackState{
+		instances: []InstanceInfo{{ID: "i-1", State: "running", AMI: "ami-old", Type: "t3.micro"}, {ID: "i-2", State: "running", AMI: "ami-old", Type: "t3.micro"}},
+		lb: &LoadBalancerInfo{
+			Name:           "web-lb",
+			DNSName:        "lb.example",
+			Subnets:        []string{"subnet-old"},
+			SecurityGroups: []string{"sg-old"},
+			Instances:      []string{"i-1", "i-2"},
+			HealthCheck:    &LBHealthCheck{Target: "HTTP:80/health", Interval: 10, Timeout: 5, HealthyThreshold: 2, UnhealthyThreshold: 2},
+		},
+		dns: []Route53RecordDefinition{{Name: "web.example.com.", Type: "CNAME", TTL: 300, Value: "lb.example"}},
+	}
+	// creation settings are not converged (see StackDefinition)
+	if changes := planStack(sd, st); len(changes) != 0 {
+		t.Fatalf("creation settings should have been ignored: %q", stackChangeStrings(changes))
+	}
+}
+
+func TestPlanStackScaleDown(t *testing.T) {
+	sd := testStackDefinition()
+	sd.Instances.Count = 1
+	sd.HealthCheck = nil
+	st := &stackState{
+		instances: []InstanceInfo{{ID: "i-1", State: "running"}, {ID: "i-2", State: "stopped"}},
+		lb:        &LoadBalancerInfo{Name: "web-lb", DNSName: "lb.example", Instances: []string{"i-1", "i-2", "i-9"}},
+		dns:       []Route53RecordDefinition{{Name: "web.example.com.", Type: "CNAME", TTL: 60, Value: "lb.example"}},
+	}
+	checkStackChanges(t, planStack(sd, st), []string{
+		"deregister instances web-lb [i-2]",
+		"delete instances [i-2]",
+		"deregister instances web-lb [i-9]",
+		"update dns_record web.example.com -> lb.example",
+	})
+}
+
+func TestPlanStackDestroy(t *testing.T) {
+	sd := testStackDefinition()
+	st := &stackState{
+		instances: []InstanceInfo{{ID: "i-2", State: "running"}, {ID: "i-1", State: "running"}},
+		lb:        &LoadBalancerInfo{Name: "web-lb", DNSName: "lb.example"},
+		dns:       []Route53RecordDefinition{{Name: "web.example.com.", Type: "CNAME", Value: "lb.example."}},
+	}
+	checkStackChanges(t, planStackDestroy(sd, st), []string{
+		"delete dns_record web.example.com. -> lb.example.",
+		"delete load_balancer web-lb",
+		"delete instances [i-1 i-2]",
+	})
+	st.dns[0].Value = "other.example"
+	checkStackChanges(t, planStackDestroy(sd, st), []string{
+		"delete load_balancer web-lb",
+		"delete instances [i-1 i-2]",
+	})
+	st.dns[0] = Route53RecordDefinition{Name: "web.example.com.", Type: "CNAME", Alias: &Route53AliasTarget{ZoneID: "Z35SXDOTRQ7X7K", DNSName: "dualstack.LB.example."}}
+	checkStackChanges(t, planStackDestroy(sd, st), []string{
+		"delete dns_record web.example.com.",
+		"delete load_balancer web-lb",
+		"delete instances [i-1 i-2]",
+	})
+}
+
+func TestPlanStackDestroyMultiValue(t *testing.T) {
+	sd := testStackDefinition()
+	sd.DNS.Type = "A"
+	st := &stackState{
+		instances: []InstanceInfo{{ID: "i-1", State: "running", PrivateIP: "10.0.0.1"}, {ID: "i-2", State: "running", PrivateIP: "10.0.0.2"}},
+		dns: DNSRecordSets([]Route53RecordDefinition{
+			{Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.0.1"},
+			{Name: "web.example.com.", Type: "A", TTL: 60, Value: "10.0.0.2"},
+		}),
+	}
+	checkStackChanges(t, planStackDestroy(sd, st), []string{
+		"delete dns_record web.example.com. -> 10.0.0.1,10.0.0.2",
+		"delete instances [i-1 i-2]",
+	})
+	sd.DNS.Value = "10.0.0.1"
+	sd.DNS.TTL = 60
+	checkStackChanges(t, planStack(sd, st), []string{
+		"create load_balancer web-lb",
+		"update health_check web-lb",
+		"register instances web-lb [i-1 i-2]",
+		"update dns_record web.example.com -> 10.0.0.1",
+	})
+	// a record set with any value outside the stack is not the stack's to delete
+	st.instances = st.instances[:1]
+	checkStackChanges(t, planStackDestroy(sd, st), []string{
+		"delete instances [i-1]",
+	})
+}
+
+// stackTestService serves a fixed load balancer (with tags) and DNS records; mutations are logged by
+// TestingAWSService
+type stackTestService struct {
+	*TestingAWSService
+	lb     *LoadBalancerInfo
+	lbTags map[string]string
+	dns    []Route53RecordDefinition
+}
+
+func (s *stackTestService) FindInstancesByTag(n string, v string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (s *stackTestService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, error) {
+	if s.lb == nil || s.lb.Name != n {
+		return nil, notFoundError("GetLoadBalancerInfo", "load balancer not found: %v", n)
+	}
+	return s.lb, nil
+}
+
+func (s *stackTestService) GetLoadBalancerTags(n string) (map[string]string, error) {
+	return s.lbTags, nil
+}
+
+func (s *stackTestService) GetDNSRecords(zoneID string, name string) ([]Route53RecordDefinition, error) {
+	return s.dns, nil
+}
+
+func stackTestActions(svc *stackTestService) []string {
+	actions := []string{}
+	for _, l := range svc.Log {
+		actions = append(actions, l.Action)
+	}
+	return actions
+}
+
+func TestDestroyStackForeignLoadBalancer(t *testing.T) {
+	sd := testStackDefinition()
+	svc := &stackTestService{
+		TestingAWSService: &TestingAWSService{},
+		lb:                &LoadBalancerInfo{Name: "web-lb", DNSName: "web-lb-123.us-west-2.elb.amazonaws.com"},
+		lbTags:            map[string]string{"team": "other"},
+		dns:               []Route53RecordDefinition{{ZoneID: "Z123", Name: "web.example.com.", Type: "CNAME", TTL: 300, Value: "web-lb-123.us-west-2.elb.amazonaws.com"}},
+	}
+	if _, err := DestroyStack(svc, sd); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("should have been a conflict: %v", err)
+	}
+	if _, err := ApplyStack(svc, sd); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("should have been a conflict: %v", err)
+	}
+	if len(svc.Log) != 0 {
+		t.Fatalf("nothing should have been changed: %v", stackTestActions(svc))
+	}
+	svc.lbTags[StackTagKey] = sd.Name
+	plan, err := DestroyStack(svc, sd)
+	if err != nil {
+		t.Fatalf("error destroying stack: %v", err)
+	}
+	checkStackChanges(t, plan.Changes, []string{
+		"delete dns_record web.example.com. -> web-lb-123.us-west-2.elb.amazonaws.com",
+		"delete load_balancer web-lb",
+	})
+	if strings.Join(stackTestActions(svc), " ") != "DeleteDNSRecord DeleteLoadBalancer" {
+		t.Fatalf("bad actions: %v", stackTestActions(svc))
+	}
+}
+
+func TestApplyStackTagsLoadBalancer(t *testing.T) {
+	sd := testStackDefinition()
+	sd.Instances = nil
+	sd.LoadBalancer.Tags = map[string]string{"team": "web"}
+	svc := &stackTestService{TestingAWSService: &TestingAWSService{}}
+	if _, err := ApplyStack(svc, sd); err == nil {
+		t.Fatalf("DNS record should have failed without a load balancer DNS name")
+	}
+	if len(svc.Log) == 0 || svc.Log[0].Action != "CreateLoadBalancer" || svc.Log[0].NotableParams["tags"] != "map["+StackTagKey+":web team:web]" {
+		t.Fatalf("load balancer should have been tagged: %+v", svc.Log)
+	}
+	if len(sd.LoadBalancer.Tags) != 1 {
+		t.Fatalf("definition should not have been modified: %v", sd.LoadBalancer.Tags)
+	}
+}
+
+func TestStackDefinitionValidate(t *testing.T) {
+	sd := &StackDefinition{
+		HealthCheck: &LBHealthCheck{},
+		DNS:         &Route53RecordDefinition{},
+	}
+	ve, ok := sd.Validate().(*ValidationError)
+	if !ok {
+		t.Fatalf("expected validation error")
+	}
+	if len(ve.Problems) != 4 {
+		t.Fatalf("expected 4 problems: %v", ve.Problems)
+	}
+}
@@ -0,0 +1,161 @@
+package awsservice
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// SubnetPlacement records the instances launched into one subnet by RunInstancesAcrossSubnets
+type SubnetPlacement struct {
+	Subnet           string
+	AvailabilityZone string
+	InstanceIDs      []string
+}
+
+type subnetAllocation struct {
+	subnet SubnetInfo
+	count  int
+}
+
+// FindSubnets returns the available subnets in a VPC carrying all of the given tags (either may be empty)
+func (aws *RealAWSService) FindSubnets(vpc string, tags map[string]string) ([]SubnetInfo, error) {
+	result := []SubnetInfo{}
+	dsi := &ec2.DescribeSubnetsInput{
+		Filters: ec2Filters(map[string]string{"vpc-id": vpc, "state": ec2.SubnetStateAvailable}, tags),
+	}
+	err := aws.ec2.DescribeSubnetsPages(dsi, func(page *ec2.DescribeSubnetsOutput, last bool) bool {
+		for _, s := range page.Subnets {
+			result = append(result, subnetInfo(s))
+		}
+		return true
+	})
+	return result, wrapError("FindSubnets", err)
+}
+
+// planSubnetSpread assigns count instances to subnets one at a time, cycling through availability zones
+// so the number per AZ differs by at most one wherever capacity allows. Within an AZ the subnet with
+// the most free addresses is used first. Unavailable subnets are skipped.
+func planSubnetSpread(subnets []SubnetInfo, count int) ([]subnetAllocation, error) {
+	allocs := []*subnetAllocation{}
+	byaz := map[string][]*subnetAllocation{}
+	free := int64(0)
+	for _, s := range subnets {
+		if s.State != ec2.SubnetStateAvailable || s.AvailableIPAddresses <= 0 {
+			continue
+		}
+		a := &subnetAllocation{subnet: s}
+		allocs = append(allocs, a)
+		byaz[s.AvailabilityZone] = append(byaz[s.AvailabilityZone], a)
+		free += s.AvailableIPAddresses
+	}
+	if free < int64(count) {
+		return []subnetAllocation{}, fmt.Errorf("insufficient subnet capacity: %v free addresses for %v instances", free, count)
+	}
+	azs := []string{}
+	for az := range byaz {
+		azs = append(azs, az)
+	}
+	sort.Strings(azs)
+	remaining := func(a *subnetAllocation) int64 {
+		return a.subnet.AvailableIPAddresses - int64(a.count)
+	}
+	next := 0
+	for i := 0; i < count; i++ {
+		// free >= count guarantees some AZ has room
+		for {
+			az := azs[next%len(azs)]
+			next++
+			var best *subnetAllocation
+			for _, a := range byaz[az] {
+				if remaining(a) > 0 && (best == nil || remaining(a) > remaining(best)) {
+					best = a
+				}
+			}
+			if best != nil {
+				best.count++
+				break
+			}
+		}
+	}
+	result := []subnetAllocation{}
+	for _, a := range allocs {
+		if a.count > 0 {
+			result = append(result, *a)
+		}
+	}
+	return result, nil
+}
+
+// RunInstancesAcrossSubnets launches idef.Count instances spread evenly across the availability zones of the
+// candidate subnets, respecting each subnet's free addresses. idef.Subnet is ignored and PrivateIPs are not supported.
+// If a launch fails, the placements made so far are returned along with the error.
+func (aws *RealAWSService) RunInstancesAcrossSubnets(idef *InstancesDefinition, subnets []string) ([]SubnetPlacement, error) {
+	placements := []SubnetPlacement{}
+	if len(subnets) == 0 {
+		return placements, invalidDefinition("at least one candidate subnet is required")
+	}
+	if len(idef.PrivateIPs) > 0 {
+		return placements, invalidDefinition("private IPs are not supported when spreading across subnets")
+	}
+	vdef := *idef
+	vdef.Subnet = subnets[0]
+	if err := vdef.Validate(); err != nil {
+		return placements, err
+	}
+	res, err := aws.ec2.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: stringSlicetoStringPointerSlice(subnets),
+	})
+	if err != nil {
+		return placements, wrapError("RunInstancesAcrossSubnets", err)
+	}
+	infos := []SubnetInfo{}
+	for _, s := range res.Subnets {
+		infos = append(infos, subnetInfo(s))
+	}
+	allocs, err := planSubnetSpread(infos, idef.Count)
+	if err != nil {
+		return placements, err
+	}
+	for _, a := range allocs {
+		sdef := *idef
+		sdef.Subnet = a.subnet.ID
+		sdef.Count = a.count
+		ids, err := aws.RunInstances(&sdef)
+		if err != nil {
+			return placements, fmt.Errorf("error launching %v instances in %v: %w", a.count, a.subnet.ID, err)
+		}
+		placements = append(placements, SubnetPlacement{
+			Subnet:           a.subnet.ID,
+			AvailabilityZone: a.subnet.AvailabilityZone,
+			InstanceIDs:      ids,
+		})
+	}
+	return placements, nil
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) FindSubnets(vpc string, tags map[string]string) ([]SubnetInfo, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "FindSubnets",
+		NotableParams: map[string]string{
+			"vpc":  vpc,
+			"tags": fmt.Sprintf("%v", tags),
+		},
+	})
+	return []SubnetInfo{}, nil
+}
+
+func (aws *TestingAWSService) RunInstancesAcrossSubnets(idef *InstancesDefinition, subnets []string) ([]SubnetPlacement, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "RunInstancesAcrossSubnets",
+		NotableParams: map[string]string{
+			"ami":     idef.AMI,
+			"count":   fmt.Sprintf("%v", idef.Count),
+			"subnets": fmt.Sprintf("%v", subnets),
+		},
+	})
+	return []SubnetPlacement{}, nil
+}
@@ -0,0 +1,41 @@
+package awsservice
+
+import (
+	"testing"
+)
+
+func TestPlanSubnetSpread(t *testing.T) {
+	subnets := []SubnetInfo{
+		{ID: "subnet-a1", AvailabilityZone: "us-west-2a", AvailableIPAddresses: 100, State: "available"},
+		{ID: "subnet-a2", AvailabilityZone: "us-west-2a", AvailableIPAddresses: 50, State: "available"},
+		{ID: "subnet-b1", AvailabilityZone: "us-west-2b", AvailableIPAddresses: 2, State: "available"},
+		{ID: "subnet-c1", AvailabilityZone: "us-west-2c", AvailableIPAddresses: 100, State: "pending"},
+	}
+	allocs, err := planSubnetSpread(subnets, 6)
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	counts := map[string]int{}
+	for _, a := range allocs {
+		counts[a.subnet.ID] = a.count
+	}
+	if counts["subnet-b1"] != 2 {
+		t.Fatalf("subnet-b1 should have been filled: %v", counts)
+	}
+	if counts["subnet-a1"]+counts["subnet-a2"] != 4 {
+		t.Fatalf("remaining instances should have gone to us-west-2a: %v", counts)
+	}
+	if counts["subnet-c1"] != 0 {
+		t.Fatalf("pending subnet should not be used: %v", counts)
+	}
+	allocs, err = planSubnetSpread(subnets, 4)
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	if len(allocs) != 2 || allocs[0].count != 2 || allocs[1].count != 2 {
+		t.Fatalf("instances should have been spread evenly: %v", allocs)
+	}
+	if _, err := planSubnetSpread(subnets, 200); err == nil {
+		t.Fatalf("should have failed with insufficient capacity")
+	}
+}
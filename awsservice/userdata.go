@@ -0,0 +1,254 @@
+package awsservice
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Cloud-init user data part content types
+const (
+	CloudConfig = "text/cloud-config"
+	ShellScript = "text/x-shellscript"
+	Boothook    = "text/cloud-boothook"
+	IncludeURL  = "text/x-include-url"
+	PlainText   = "text/plain"
+)
+
+// MaxUserDataSize is the EC2 limit on user data size (after compression)
+const MaxUserDataSize = 16384
+
+const userDataBoundary = "==AWSSERVICE-USERDATA-BOUNDARY=="
+
+type UserDataPart struct {
+	ContentType string
+	Filename    string
+	Content     []byte
+}
+
+// UserDataBuilder composes cloud-init parts into a multipart MIME document
+type UserDataBuilder struct {
+	parts []UserDataPart
+}
+
+func NewUserDataBuilder() *UserDataBuilder {
+	return &UserDataBuilder{}
+}
+
+// AddPart appends an arbitrary part
+func (b *UserDataBuilder) AddPart(p UserDataPart) *UserDataBuilder {
+	b.parts = append(b.parts, p)
+	return b
+}
+
+// AddCloudConfig appends a cloud-config YAML document
+func (b *UserDataBuilder) AddCloudConfig(yaml string) *UserDataBuilder {
+	if !strings.HasPrefix(yaml, "#cloud-config") {
+		yaml = "#cloud-config\n" + yaml
+	}
+	return b.AddPart(UserDataPart{ContentType: CloudConfig, Filename: "cloud-config.yaml", Content: []byte(yaml)})
+}
+
+// AddShellScript appends a script executed once on first boot
+func (b *UserDataBuilder) AddShellScript(filename string, script string) *UserDataBuilder {
+	return b.AddPart(UserDataPart{ContentType: ShellScript, Filename: filename, Content: []byte(script)})
+}
+
+// AddBoothook appends a script executed early on every boot
+func (b *UserDataBuilder) AddBoothook(filename string, script string) *UserDataBuilder {
+	return b.AddPart(UserDataPart{ContentType: Boothook, Filename: filename, Content: []byte(script)})
+}
+
+// AddIncludeURLs appends a part instructing cloud-init to fetch and process each URL
+func (b *UserDataBuilder) AddIncludeURLs(urls ...string) *UserDataBuilder {
+	return b.AddPart(UserDataPart{ContentType: IncludeURL, Filename: "include.txt", Content: []byte(strings.Join(urls, "\n") + "\n")})
+}
+
+// Build returns the multipart document, suitable for InstancesDefinition.UserData.
+// It returns an error if the document exceeds MaxUserDataSize after compression.
+func (b *UserDataBuilder) Build() ([]byte, error) {
+	if len(b.parts) == 0 {
+		return []byte{}, fmt.Errorf("user data has no parts")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=\"%v\"\r\nMIME-Version: 1.0\r\n\r\n", userDataBoundary)
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(userDataBoundary); err != nil {
+		return []byte{}, err
+	}
+	for i, p := range b.parts {
+		if bytes.Contains(p.Content, []byte(userDataBoundary)) {
+			return []byte{}, fmt.Errorf("part %v (%v) contains the MIME boundary", i, p.Filename)
+		}
+		fn := p.Filename
+		if fn == "" {
+			fn = fmt.Sprintf("part-%03d", i)
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", fmt.Sprintf("%v; charset=\"us-ascii\"", p.ContentType))
+		h.Set("MIME-Version", "1.0")
+		h.Set("Content-Transfer-Encoding", "7bit")
+		h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", fn))
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return []byte{}, err
+		}
+		if _, err := pw.Write(p.Content); err != nil {
+			return []byte{}, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return []byte{}, err
+	}
+	ud := buf.Bytes()
+	if err := checkUserDataSize(ud); err != nil {
+		return []byte{}, err
+	}
+	return ud, nil
+}
+
+func gzipUserData(ud []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return []byte{}, err
+	}
+	if _, err := w.Write(ud); err != nil {
+		return []byte{}, err
+	}
+	if err := w.Close(); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+func checkUserDataSize(ud []byte) error {
+	gz, err := gzipUserData(ud)
+	if err != nil {
+		return err
+	}
+	if len(gz) > MaxUserDataSize {
+		return fmt.Errorf("user data too large: %v bytes compressed (max: %v)", len(gz), MaxUserDataSize)
+	}
+	return nil
+}
+
+func encodeUserData(ud []byte) (string, error) {
+	gz, err := gzipUserData(ud)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gz), nil
+}
+
+// DecodeUserData reverses the base64 (and gzip, if present) encoding of user data as returned by EC2
+func DecodeUserData(encoded string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return []byte{}, fmt.Errorf("error decoding base64 user data: %v", err)
+	}
+	if len(raw) < 2 || raw[0] != 0x1f || raw[1] != 0x8b {
+		return raw, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return []byte{}, fmt.Errorf("error decompressing user data: %v", err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// ParseUserData splits decoded user data into its parts. Non-multipart user data
+// is returned as a single part with the content type cloud-init would infer.
+func ParseUserData(ud []byte) ([]UserDataPart, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(ud), []byte("Content-Type: multipart/")) {
+		return []UserDataPart{{ContentType: guessUserDataType(ud), Content: ud}}, nil
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(ud)))
+	if err != nil {
+		return []UserDataPart{}, fmt.Errorf("error reading MIME headers: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return []UserDataPart{}, fmt.Errorf("error parsing content type: %v", err)
+	}
+	parts := []UserDataPart{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []UserDataPart{}, fmt.Errorf("error reading MIME part: %v", err)
+		}
+		content, err := ioutil.ReadAll(p)
+		if err != nil {
+			return []UserDataPart{}, fmt.Errorf("error reading MIME part: %v", err)
+		}
+		ct, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			ct = guessUserDataType(content)
+		}
+		parts = append(parts, UserDataPart{
+			ContentType: ct,
+			Filename:    p.FileName(),
+			Content:     content,
+		})
+	}
+	return parts, nil
+}
+
+func guessUserDataType(ud []byte) string {
+	switch {
+	case bytes.HasPrefix(ud, []byte("#cloud-config")):
+		return CloudConfig
+	case bytes.HasPrefix(ud, []byte("#cloud-boothook")):
+		return Boothook
+	case bytes.HasPrefix(ud, []byte("#include")):
+		return IncludeURL
+	case bytes.HasPrefix(ud, []byte("#!")):
+		return ShellScript
+	default:
+		return PlainText
+	}
+}
+
+// GetInstanceUserData returns the decoded user data of a running instance
+func (aws *RealAWSService) GetInstanceUserData(id string) ([]byte, error) {
+	attr := ec2.InstanceAttributeNameUserData
+	res, err := aws.ec2.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		InstanceId: &id,
+		Attribute:  &attr,
+	})
+	if err != nil {
+		return []byte{}, wrapError("GetInstanceUserData", err)
+	}
+	if res.UserData == nil || res.UserData.Value == nil {
+		return []byte{}, nil
+	}
+	return DecodeUserData(*res.UserData.Value)
+}
+
+// Testing mocks
+
+func (aws *TestingAWSService) GetInstanceUserData(id string) ([]byte, error) {
+	aws.Log = append(aws.Log, AWSActionLog{
+		Action: "GetInstanceUserData",
+		NotableParams: map[string]string{
+			"id": id,
+		},
+	})
+	return []byte{}, nil
+}
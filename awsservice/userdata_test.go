@@ -0,0 +1,67 @@
+package awsservice
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestUserDataBuildParse(t *testing.T) {
+	ud, err := NewUserDataBuilder().
+		AddCloudConfig("packages:\n  - nginx\n").
+		AddShellScript("setup.sh", "#!/bin/bash\necho hello\n").
+		AddBoothook("hook.sh", "#cloud-boothook\necho early\n").
+		AddIncludeURLs("https://example.com/a", "https://example.com/b").
+		Build()
+	if err != nil {
+		t.Fatalf("error building user data: %v", err)
+	}
+	enc, err := encodeUserData(ud)
+	if err != nil {
+		t.Fatalf("error encoding user data: %v", err)
+	}
+	dec, err := DecodeUserData(enc)
+	if err != nil {
+		t.Fatalf("error decoding user data: %v", err)
+	}
+	if !bytes.Equal(dec, ud) {
+		t.Fatalf("decoded user data does not match")
+	}
+	parts, err := ParseUserData(dec)
+	if err != nil {
+		t.Fatalf("error parsing user data: %v", err)
+	}
+	if len(parts) != 4 {
+		t.Fatalf("expected 4 parts: %v", len(parts))
+	}
+	if parts[0].ContentType != CloudConfig || !bytes.HasPrefix(parts[0].Content, []byte("#cloud-config\n")) {
+		t.Fatalf("incorrect cloud-config part: %v: %q", parts[0].ContentType, parts[0].Content)
+	}
+	if parts[1].ContentType != ShellScript || parts[1].Filename != "setup.sh" {
+		t.Fatalf("incorrect shell script part: %v: %v", parts[1].ContentType, parts[1].Filename)
+	}
+	if parts[3].ContentType != IncludeURL || string(parts[3].Content) != "https://example.com/a\nhttps://example.com/b\n" {
+		t.Fatalf("incorrect include part: %v: %q", parts[3].ContentType, parts[3].Content)
+	}
+}
+
+func TestParseUserDataSinglePart(t *testing.T) {
+	parts, err := ParseUserData([]byte("#!/bin/sh\nexit 0\n"))
+	if err != nil {
+		t.Fatalf("error parsing user data: %v", err)
+	}
+	if len(parts) != 1 || parts[0].ContentType != ShellScript {
+		t.Fatalf("incorrect parts: %v", parts)
+	}
+}
+
+func TestUserDataSizeLimit(t *testing.T) {
+	random := make([]byte, MaxUserDataSize)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("error generating data: %v", err)
+	}
+	_, err := NewUserDataBuilder().AddPart(UserDataPart{ContentType: PlainText, Content: random}).Build()
+	if err == nil {
+		t.Fatalf("should have exceeded size limit")
+	}
+}
@@ -0,0 +1,152 @@
+package awsservice
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ValidationError collects every problem found in a definition
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid definition: %v", strings.Join(e.Problems, "; "))
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// invalidDefinition returns a ValidationError holding a single problem
+func invalidDefinition(format string, args ...interface{}) error {
+	ve := &ValidationError{}
+	ve.add(format, args...)
+	return ve
+}
+
+// errOrNil returns e if any problems were found, nil otherwise
+func (e *ValidationError) errOrNil() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ebsVolumeLimits are the EC2-documented size/performance limits of a volume type
+type ebsVolumeLimits struct {
+	minSize, maxSize             int64 // GiB
+	minIops, maxIops             int64 // maxIops == 0 means Iops may not be specified
+	iopsRequired                 bool
+	minThroughput, maxThroughput int64 // maxThroughput == 0 means Throughput may not be specified
+}
+
+var ebsLimits = map[EBSVolumeType]ebsVolumeLimits{
+	Gp2:      {minSize: 1, maxSize: 16384},
+	Gp3:      {minSize: 1, maxSize: 16384, minIops: 3000, maxIops: 16000, minThroughput: 125, maxThroughput: 1000},
+	Io1:      {minSize: 4, maxSize: 16384, minIops: 100, maxIops: 64000, iopsRequired: true},
+	Io2:      {minSize: 4, maxSize: 65536, minIops: 100, maxIops: 256000, iopsRequired: true},
+	Sc1:      {minSize: 125, maxSize: 16384},
+	St1:      {minSize: 125, maxSize: 16384},
+	Standard: {minSize: 1, maxSize: 1024},
+}
+
+func (bd *BlockDeviceDefinition) validate(ve *ValidationError, prefix string) {
+	lim, ok := ebsLimits[bd.Type]
+	if !ok {
+		ve.add("%vunknown volume type: %v", prefix, bd.Type)
+		return
+	}
+	vt := bd.Type.apiName()
+	switch {
+	case bd.Size == 0 && bd.SnapshotID == "":
+		ve.add("%vsize is required unless a snapshot ID is given", prefix)
+	case bd.Size < 0:
+		ve.add("%vinvalid size: %v", prefix, bd.Size)
+	case bd.Size != 0 && (bd.Size < lim.minSize || bd.Size > lim.maxSize):
+		ve.add("%vsize %v GiB out of range for %v (%v-%v)", prefix, bd.Size, vt, lim.minSize, lim.maxSize)
+	}
+	switch {
+	case lim.maxIops == 0 && bd.Iops != 0:
+		ve.add("%viops may not be specified for %v volumes", prefix, vt)
+	case lim.iopsRequired && bd.Iops == 0:
+		ve.add("%viops is required for %v volumes", prefix, vt)
+	case bd.Iops != 0 && (bd.Iops < lim.minIops || bd.Iops > lim.maxIops):
+		ve.add("%viops %v out of range for %v (%v-%v)", prefix, bd.Iops, vt, lim.minIops, lim.maxIops)
+	}
+	switch {
+	case lim.maxThroughput == 0 && bd.Throughput != 0:
+		ve.add("%vthroughput may not be specified for %v volumes", prefix, vt)
+	case bd.Throughput != 0 && (bd.Throughput < lim.minThroughput || bd.Throughput > lim.maxThroughput):
+		ve.add("%vthroughput %v out of range for %v (%v-%v)", prefix, bd.Throughput, vt, lim.minThroughput, lim.maxThroughput)
+	}
+}
+
+// Validate checks the block device against EBS limits, returning a *ValidationError listing every problem found
+func (bd *BlockDeviceDefinition) Validate() error {
+	ve := &ValidationError{}
+	if bd.Name == "" {
+		ve.add("device name is required")
+	}
+	bd.validate(ve, "")
+	return ve.errOrNil()
+}
+
+// Validate checks the definition before any API call is made, returning a *ValidationError listing every problem found
+func (idef *InstancesDefinition) Validate() error {
+	ve := &ValidationError{}
+	if idef.AMI == "" {
+		ve.add("AMI is required")
+	}
+	if idef.Subnet == "" {
+		ve.add("subnet is required")
+	}
+	if idef.SecurityGroup == "" {
+		ve.add("security group is required")
+	}
+	if idef.Type == "" {
+		ve.add("instance type is required")
+	}
+	if idef.Count < 1 {
+		ve.add("invalid count: %v (must be at least 1)", idef.Count)
+	}
+	if len(idef.PrivateIPs) > 0 {
+		if len(idef.PrivateIPs) != idef.Count {
+			ve.add("invalid private ip count: %v (expected: %v)", len(idef.PrivateIPs), idef.Count)
+		}
+		seen := map[string]bool{}
+		for _, pip := range idef.PrivateIPs {
+			if ip := net.ParseIP(pip); ip == nil || ip.To4() == nil {
+				ve.add("invalid private ip: %v", pip)
+			}
+			if seen[pip] {
+				ve.add("duplicate private ip: %v", pip)
+			}
+			seen[pip] = true
+		}
+	}
+	if len(idef.UserData) > 0 {
+		if err := checkUserDataSize(idef.UserData); err != nil {
+			ve.add("%v", err)
+		}
+	}
+	if idef.RootSizeGB < 0 {
+		ve.add("invalid root size: %v", idef.RootSizeGB)
+	} else {
+		root := idef.rootBlockDevice()
+		root.validate(ve, "root volume: ")
+	}
+	names := map[string]bool{rootDeviceName: true}
+	for i, bd := range idef.BlockDevices {
+		prefix := fmt.Sprintf("block device %v (%v): ", i, bd.Name)
+		if bd.Name == "" {
+			ve.add("%vdevice name is required", prefix)
+		} else if names[bd.Name] {
+			ve.add("%vduplicate device name", prefix)
+		}
+		names[bd.Name] = true
+		bd.validate(ve, prefix)
+	}
+	return ve.errOrNil()
+}
@@ -0,0 +1,85 @@
+package awsservice
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func testInstancesDefinition() *InstancesDefinition {
+	return &InstancesDefinition{
+		AMI:           "ami-123456",
+		Subnet:        "subnet-123456",
+		SecurityGroup: "sg-123456",
+		Type:          "t2.micro",
+		Count:         1,
+	}
+}
+
+func TestInstancesDefinitionValidate(t *testing.T) {
+	if err := testInstancesDefinition().Validate(); err != nil {
+		t.Fatalf("should have been valid: %v", err)
+	}
+	idef := testInstancesDefinition()
+	idef.Count = 2
+	idef.PrivateIPs = []string{"10.0.0.1"}
+	idef.RootVolumeType = Gp3
+	idef.RootThroughput = 2000
+	idef.BlockDevices = []BlockDeviceDefinition{
+		{Name: "/dev/xvdb", Size: 100, Type: Io1},
+		{Name: "/dev/xvdb", Size: 100, Type: Gp2, Iops: 300},
+	}
+	err := idef.Validate()
+	if err == nil {
+		t.Fatalf("should have failed")
+	}
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if len(ve.Problems) != 5 {
+		t.Fatalf("expected 5 problems: %v", ve.Problems)
+	}
+}
+
+func TestBlockDeviceDefinitionValidate(t *testing.T) {
+	bd := BlockDeviceDefinition{Name: "/dev/xvdb", Size: 500, Type: Io2, Iops: 10000}
+	if err := bd.Validate(); err != nil {
+		t.Fatalf("should have been valid: %v", err)
+	}
+	bd = BlockDeviceDefinition{Name: "/dev/xvdb", SnapshotID: "snap-123456", Type: St1}
+	if err := bd.Validate(); err != nil {
+		t.Fatalf("should have been valid: %v", err)
+	}
+	bd = BlockDeviceDefinition{Name: "/dev/xvdb", Size: 10, Type: Sc1, Throughput: 250}
+	if err := bd.Validate(); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestParseEBSVolumeType(t *testing.T) {
+	vt, err := ParseEBSVolumeType("GP3")
+	if err != nil || vt != Gp3 {
+		t.Fatalf("incorrect volume type: %v: %v", vt, err)
+	}
+	if _, err := ParseEBSVolumeType("gp9"); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestEBSVolumeTypeJSON(t *testing.T) {
+	bd := BlockDeviceDefinition{Type: Io2}
+	b, err := json.Marshal(bd)
+	if err != nil {
+		t.Fatalf("error marshaling: %v", err)
+	}
+	bd2 := BlockDeviceDefinition{}
+	if err := json.Unmarshal(b, &bd2); err != nil {
+		t.Fatalf("error unmarshaling: %v", err)
+	}
+	if bd2.Type != Io2 {
+		t.Fatalf("incorrect volume type: %v", bd2.Type)
+	}
+	if err := json.Unmarshal([]byte(`{"Type": 1}`), &bd2); err != nil || bd2.Type != Io1 {
+		t.Fatalf("legacy integer type should have parsed: %v: %v", bd2.Type, err)
+	}
+}
@@ -0,0 +1,937 @@
+package awsservice
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// AWSCall describes a single AWSService method call made through a WrappedAWSService
+type AWSCall struct {
+	Method   string
+	Params   map[string]interface{} // Arguments by name
+	CallSite string                 // file:line of the code that made the call
+	Start    time.Time
+	Duration time.Duration // Set after the call
+	Result   interface{}   // Set after the call: the non-error return value, if any
+	Err      error         // Set after the call
+	// RequestIDs is set after the call to the IDs of the AWS API requests it made, in order. It is only known
+	// when the wrapped service is a RealAWSService.
+	RequestIDs []string
+
+	svc        AWSService          // service the call is made on
+	requestIDs *requestIDCollector // see RequestIDs
+}
+
+// AWSCallHook observes calls made through a WrappedAWSService. Hooks must be safe for concurrent use.
+type AWSCallHook interface {
+	BeforeCall(*AWSCall)
+	AfterCall(*AWSCall)
+}
+
+// WrappedAWSService is an AWSService that passes every call on to another AWSService,
+// running hooks (eg an AWSAuditor) before and after each one
+type WrappedAWSService struct {
+	svc   AWSService
+	hooks []AWSCallHook
+}
+
+// WrapAWSService decorates svc with hooks. BeforeCall hooks run in order, AfterCall hooks in reverse order.
+func WrapAWSService(svc AWSService, hooks ...AWSCallHook) *WrappedAWSService {
+	return &WrappedAWSService{
+		svc:   svc,
+		hooks: hooks,
+	}
+}
+
+// Unwrap returns the decorated service
+func (w *WrappedAWSService) Unwrap() AWSService {
+	return w.svc
+}
+
+func (w *WrappedAWSService) before(method string, params map[string]interface{}) *AWSCall {
+	c := &AWSCall{
+		Method: method,
+		Params: params,
+		Start:  time.Now().UTC(),
+		svc:    w.svc,
+	}
+	if rs, ok := w.svc.(*RealAWSService); ok {
+		c.requestIDs = &requestIDCollector{}
+		c.svc = rs.withRequestIDCollector(c.requestIDs)
+	}
+	// skip before() and the wrapper method
+	if _, file, line, ok := runtime.Caller(2); ok {
+		c.CallSite = fmt.Sprintf("%v:%v", file, line)
+	}
+	for _, h := range w.hooks {
+		h.BeforeCall(c)
+	}
+	return c
+}
+
+func (w *WrappedAWSService) after(c *AWSCall, result interface{}, err error) {
+	c.Duration = time.Since(c.Start)
+	c.Result = result
+	c.Err = err
+	if c.requestIDs != nil {
+		c.RequestIDs = c.requestIDs.get()
+	}
+	for i := len(w.hooks) - 1; i >= 0; i-- {
+		w.hooks[i].AfterCall(c)
+	}
+}
+
+// requestIDCollector gathers the IDs of the AWS API requests made during one call. Requests may complete
+// concurrently (eg the parts of an S3 upload).
+type requestIDCollector struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (rc *requestIDCollector) completeHandler(r *request.Request) {
+	if r.RequestID == "" {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ids = append(rc.ids, r.RequestID)
+}
+
+func (rc *requestIDCollector) get() []string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]string{}, rc.ids...)
+}
+
+// AWSLoadBalancerService
+
+func (w *WrappedAWSService) CreateLoadBalancer(lbd *LoadBalancerDefinition) (string, error) {
+	c := w.before("CreateLoadBalancer", map[string]interface{}{"definition": lbd})
+	res, err := c.svc.CreateLoadBalancer(lbd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteLoadBalancer(n string) error {
+	c := w.before("DeleteLoadBalancer", map[string]interface{}{"name": n})
+	err := c.svc.DeleteLoadBalancer(n)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) RegisterInstances(n string, ids []string) error {
+	c := w.before("RegisterInstances", map[string]interface{}{"name": n, "ids": ids})
+	err := c.svc.RegisterInstances(n, ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DeregisterInstances(n string, ids []string) error {
+	c := w.before("DeregisterInstances", map[string]interface{}{"name": n, "ids": ids})
+	err := c.svc.DeregisterInstances(n, ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetLoadBalancerInfo(n string) (*LoadBalancerInfo, error) {
+	c := w.before("GetLoadBalancerInfo", map[string]interface{}{"name": n})
+	res, err := c.svc.GetLoadBalancerInfo(n)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetInstanceHealth(n string) (*LBInstanceHealthInfo, error) {
+	c := w.before("GetInstanceHealth", map[string]interface{}{"name": n})
+	res, err := c.svc.GetInstanceHealth(n)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) SetHealthCheck(n string, hc *LBHealthCheck) error {
+	c := w.before("SetHealthCheck", map[string]interface{}{"name": n, "health_check": hc})
+	err := c.svc.SetHealthCheck(n, hc)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetLoadBalancerTags(n string) (map[string]string, error) {
+	c := w.before("GetLoadBalancerTags", map[string]interface{}{"name": n})
+	res, err := c.svc.GetLoadBalancerTags(n)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindLoadBalancersByTag(n string, v string) ([]string, error) {
+	c := w.before("FindLoadBalancersByTag", map[string]interface{}{"tag": n, "value": v})
+	res, err := c.svc.FindLoadBalancersByTag(n, v)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSRoute53Service
+
+func (w *WrappedAWSService) CreateDNSRecord(rd *Route53RecordDefinition) error {
+	c := w.before("CreateDNSRecord", map[string]interface{}{"record": rd})
+	err := c.svc.CreateDNSRecord(rd)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DeleteDNSRecord(rd *Route53RecordDefinition) error {
+	c := w.before("DeleteDNSRecord", map[string]interface{}{"record": rd})
+	err := c.svc.DeleteDNSRecord(rd)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) UpsertDNSRecord(rd *Route53RecordDefinition) error {
+	c := w.before("UpsertDNSRecord", map[string]interface{}{"record": rd})
+	err := c.svc.UpsertDNSRecord(rd)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetDNSRecords(zoneID string, name string) ([]Route53RecordDefinition, error) {
+	c := w.before("GetDNSRecords", map[string]interface{}{"zone_id": zoneID, "name": name})
+	res, err := c.svc.GetDNSRecords(zoneID, name)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSHostedZoneService
+
+func (w *WrappedAWSService) CreateHostedZone(hzd *HostedZoneDefinition) (*HostedZoneInfo, error) {
+	c := w.before("CreateHostedZone", map[string]interface{}{"definition": hzd})
+	res, err := c.svc.CreateHostedZone(hzd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteHostedZone(id string) error {
+	c := w.before("DeleteHostedZone", map[string]interface{}{"zone_id": id})
+	err := c.svc.DeleteHostedZone(id)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetHostedZoneInfo(id string) (*HostedZoneInfo, error) {
+	c := w.before("GetHostedZoneInfo", map[string]interface{}{"zone_id": id})
+	res, err := c.svc.GetHostedZoneInfo(id)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindHostedZonesByName(name string) ([]HostedZoneInfo, error) {
+	c := w.before("FindHostedZonesByName", map[string]interface{}{"name": name})
+	res, err := c.svc.FindHostedZonesByName(name)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) AssociateVPCWithHostedZone(id string, vpc HostedZoneVPC) error {
+	c := w.before("AssociateVPCWithHostedZone", map[string]interface{}{"zone_id": id, "vpc": vpc})
+	err := c.svc.AssociateVPCWithHostedZone(id, vpc)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DisassociateVPCFromHostedZone(id string, vpc HostedZoneVPC) error {
+	c := w.before("DisassociateVPCFromHostedZone", map[string]interface{}{"zone_id": id, "vpc": vpc})
+	err := c.svc.DisassociateVPCFromHostedZone(id, vpc)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) CreateDelegationSet(zoneID string) (*DelegationSet, error) {
+	c := w.before("CreateDelegationSet", map[string]interface{}{"zone_id": zoneID})
+	res, err := c.svc.CreateDelegationSet(zoneID)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetDelegationSets() ([]DelegationSet, error) {
+	c := w.before("GetDelegationSets", map[string]interface{}{})
+	res, err := c.svc.GetDelegationSets()
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteDelegationSet(id string) error {
+	c := w.before("DeleteDelegationSet", map[string]interface{}{"id": id})
+	err := c.svc.DeleteDelegationSet(id)
+	w.after(c, nil, err)
+	return err
+}
+
+// AWSEC2Service
+
+func (w *WrappedAWSService) RunInstances(idef *InstancesDefinition) ([]string, error) {
+	c := w.before("RunInstances", map[string]interface{}{"definition": idef})
+	res, err := c.svc.RunInstances(idef)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) StartInstances(ids []string) error {
+	c := w.before("StartInstances", map[string]interface{}{"ids": ids})
+	err := c.svc.StartInstances(ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) StopInstances(ids []string) error {
+	c := w.before("StopInstances", map[string]interface{}{"ids": ids})
+	err := c.svc.StopInstances(ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) FindInstancesByTag(n string, v string) ([]string, error) {
+	c := w.before("FindInstancesByTag", map[string]interface{}{"tag": n, "value": v})
+	res, err := c.svc.FindInstancesByTag(n, v)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) TagInstances(ids []string, n string, v string) error {
+	c := w.before("TagInstances", map[string]interface{}{"ids": ids, "tag": n, "value": v})
+	err := c.svc.TagInstances(ids, n, v)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DeleteTag(ids []string, n string) error {
+	c := w.before("DeleteTag", map[string]interface{}{"ids": ids, "tag": n})
+	err := c.svc.DeleteTag(ids, n)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetSubnetInfo(id string) (*SubnetInfo, error) {
+	c := w.before("GetSubnetInfo", map[string]interface{}{"id": id})
+	res, err := c.svc.GetSubnetInfo(id)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindSubnets(vpc string, tags map[string]string) ([]SubnetInfo, error) {
+	c := w.before("FindSubnets", map[string]interface{}{"vpc": vpc, "tags": tags})
+	res, err := c.svc.FindSubnets(vpc, tags)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) RunInstancesAcrossSubnets(idef *InstancesDefinition, subnets []string) ([]SubnetPlacement, error) {
+	c := w.before("RunInstancesAcrossSubnets", map[string]interface{}{"definition": idef, "subnets": subnets})
+	res, err := c.svc.RunInstancesAcrossSubnets(idef, subnets)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetInstancesInfo(ids []string) ([]InstanceInfo, error) {
+	c := w.before("GetInstancesInfo", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetInstancesInfo(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetInstanceUserData(id string) ([]byte, error) {
+	c := w.before("GetInstanceUserData", map[string]interface{}{"id": id})
+	res, err := c.svc.GetInstanceUserData(id)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) TerminateInstances(ids []string) error {
+	c := w.before("TerminateInstances", map[string]interface{}{"ids": ids})
+	err := c.svc.TerminateInstances(ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) RebootInstances(ids []string) error {
+	c := w.before("RebootInstances", map[string]interface{}{"ids": ids})
+	err := c.svc.RebootInstances(ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) HibernateInstances(ids []string) error {
+	c := w.before("HibernateInstances", map[string]interface{}{"ids": ids})
+	err := c.svc.HibernateInstances(ids)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) ModifyInstanceType(id string, t string) error {
+	c := w.before("ModifyInstanceType", map[string]interface{}{"id": id, "type": t})
+	err := c.svc.ModifyInstanceType(id, t)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) ResizeInstance(id string, t string, interval time.Duration, timeout time.Duration) error {
+	c := w.before("ResizeInstance", map[string]interface{}{"id": id, "type": t, "interval": interval, "timeout": timeout})
+	err := c.svc.ResizeInstance(id, t, interval, timeout)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) SetTerminationProtection(id string, enabled bool) error {
+	c := w.before("SetTerminationProtection", map[string]interface{}{"id": id, "enabled": enabled})
+	err := c.svc.SetTerminationProtection(id, enabled)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) SetStopProtection(id string, enabled bool) error {
+	c := w.before("SetStopProtection", map[string]interface{}{"id": id, "enabled": enabled})
+	err := c.svc.SetStopProtection(id, enabled)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) SetSourceDestCheck(id string, enabled bool) error {
+	c := w.before("SetSourceDestCheck", map[string]interface{}{"id": id, "enabled": enabled})
+	err := c.svc.SetSourceDestCheck(id, enabled)
+	w.after(c, nil, err)
+	return err
+}
+
+// AWSInstanceDiagnosticsService
+
+func (w *WrappedAWSService) GetConsoleOutput(id string) (*ConsoleOutput, error) {
+	c := w.before("GetConsoleOutput", map[string]interface{}{"id": id})
+	res, err := c.svc.GetConsoleOutput(id)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetConsoleScreenshot(id string) ([]byte, error) {
+	c := w.before("GetConsoleScreenshot", map[string]interface{}{"id": id})
+	res, err := c.svc.GetConsoleScreenshot(id)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetInstanceStatus(ids []string) ([]InstanceStatusInfo, error) {
+	c := w.before("GetInstanceStatus", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetInstanceStatus(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSKeyPairService
+
+func (w *WrappedAWSService) CreateKeyPair(kpd *KeyPairDefinition) (*KeyPair, error) {
+	c := w.before("CreateKeyPair", map[string]interface{}{"definition": kpd})
+	res, err := c.svc.CreateKeyPair(kpd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) ImportKeyPair(kpd *KeyPairDefinition) (*KeyPairInfo, error) {
+	c := w.before("ImportKeyPair", map[string]interface{}{"definition": kpd})
+	res, err := c.svc.ImportKeyPair(kpd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetKeyPairsInfo(names []string) ([]KeyPairInfo, error) {
+	c := w.before("GetKeyPairsInfo", map[string]interface{}{"names": names})
+	res, err := c.svc.GetKeyPairsInfo(names)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteKeyPair(name string) error {
+	c := w.before("DeleteKeyPair", map[string]interface{}{"name": name})
+	err := c.svc.DeleteKeyPair(name)
+	w.after(c, nil, err)
+	return err
+}
+
+// AWSSecurityGroupService
+
+func (w *WrappedAWSService) CreateSecurityGroup(sgd *SecurityGroupDefinition) (string, error) {
+	c := w.before("CreateSecurityGroup", map[string]interface{}{"definition": sgd})
+	res, err := c.svc.CreateSecurityGroup(sgd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteSecurityGroup(id string) error {
+	c := w.before("DeleteSecurityGroup", map[string]interface{}{"id": id})
+	err := c.svc.DeleteSecurityGroup(id)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) AuthorizeIngress(id string, rules []SecurityGroupRule) error {
+	c := w.before("AuthorizeIngress", map[string]interface{}{"id": id, "rules": rules})
+	err := c.svc.AuthorizeIngress(id, rules)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) RevokeIngress(id string, rules []SecurityGroupRule) error {
+	c := w.before("RevokeIngress", map[string]interface{}{"id": id, "rules": rules})
+	err := c.svc.RevokeIngress(id, rules)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) AuthorizeEgress(id string, rules []SecurityGroupRule) error {
+	c := w.before("AuthorizeEgress", map[string]interface{}{"id": id, "rules": rules})
+	err := c.svc.AuthorizeEgress(id, rules)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) RevokeEgress(id string, rules []SecurityGroupRule) error {
+	c := w.before("RevokeEgress", map[string]interface{}{"id": id, "rules": rules})
+	err := c.svc.RevokeEgress(id, rules)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetSecurityGroupsInfo(ids []string) ([]SecurityGroupInfo, error) {
+	c := w.before("GetSecurityGroupsInfo", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetSecurityGroupsInfo(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindSecurityGroups(vpc string, tags map[string]string) ([]SecurityGroupInfo, error) {
+	c := w.before("FindSecurityGroups", map[string]interface{}{"vpc": vpc, "tags": tags})
+	res, err := c.svc.FindSecurityGroups(vpc, tags)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) ReconcileSecurityGroup(id string, ingress []SecurityGroupRule, egress []SecurityGroupRule) (*SecurityGroupRuleChanges, error) {
+	c := w.before("ReconcileSecurityGroup", map[string]interface{}{"id": id, "ingress": ingress, "egress": egress})
+	res, err := c.svc.ReconcileSecurityGroup(id, ingress, egress)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSNetworkService
+
+func (w *WrappedAWSService) GetVPCsInfo(ids []string) ([]VPCInfo, error) {
+	c := w.before("GetVPCsInfo", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetVPCsInfo(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindVPCs(tags map[string]string) ([]VPCInfo, error) {
+	c := w.before("FindVPCs", map[string]interface{}{"tags": tags})
+	res, err := c.svc.FindVPCs(tags)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetRouteTables(vpc string) ([]RouteTableInfo, error) {
+	c := w.before("GetRouteTables", map[string]interface{}{"vpc": vpc})
+	res, err := c.svc.GetRouteTables(vpc)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetSubnetRouteTable(subnet string) (*RouteTableInfo, error) {
+	c := w.before("GetSubnetRouteTable", map[string]interface{}{"subnet": subnet})
+	res, err := c.svc.GetSubnetRouteTable(subnet)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetInternetGateways(vpc string) ([]InternetGatewayInfo, error) {
+	c := w.before("GetInternetGateways", map[string]interface{}{"vpc": vpc})
+	res, err := c.svc.GetInternetGateways(vpc)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetNATGateways(vpc string) ([]NATGatewayInfo, error) {
+	c := w.before("GetNATGateways", map[string]interface{}{"vpc": vpc})
+	res, err := c.svc.GetNATGateways(vpc)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetVPCEndpoints(vpc string) ([]VPCEndpointInfo, error) {
+	c := w.before("GetVPCEndpoints", map[string]interface{}{"vpc": vpc})
+	res, err := c.svc.GetVPCEndpoints(vpc)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSImageService
+
+func (w *WrappedAWSService) FindAMIs(q *AMIQuery) ([]AMIInfo, error) {
+	c := w.before("FindAMIs", map[string]interface{}{"query": q})
+	res, err := c.svc.FindAMIs(q)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) FindLatestAMI(q *AMIQuery) (*AMIInfo, error) {
+	c := w.before("FindLatestAMI", map[string]interface{}{"query": q})
+	res, err := c.svc.FindLatestAMI(q)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) ResolveAMI(ref string) (string, error) {
+	c := w.before("ResolveAMI", map[string]interface{}{"ref": ref})
+	res, err := c.svc.ResolveAMI(ref)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSElasticIPService
+
+func (w *WrappedAWSService) AllocateElasticIP(tags map[string]string) (*ElasticIPInfo, error) {
+	c := w.before("AllocateElasticIP", map[string]interface{}{"tags": tags})
+	res, err := c.svc.AllocateElasticIP(tags)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) AssociateElasticIP(allocID string, instanceID string) (string, error) {
+	c := w.before("AssociateElasticIP", map[string]interface{}{"allocation_id": allocID, "instance_id": instanceID})
+	res, err := c.svc.AssociateElasticIP(allocID, instanceID)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) AssociateElasticIPWithInterface(allocID string, eni string, privateIP string) (string, error) {
+	c := w.before("AssociateElasticIPWithInterface", map[string]interface{}{"allocation_id": allocID, "network_interface_id": eni, "private_ip": privateIP})
+	res, err := c.svc.AssociateElasticIPWithInterface(allocID, eni, privateIP)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DisassociateElasticIP(assocID string) error {
+	c := w.before("DisassociateElasticIP", map[string]interface{}{"association_id": assocID})
+	err := c.svc.DisassociateElasticIP(assocID)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) ReleaseElasticIP(allocID string) error {
+	c := w.before("ReleaseElasticIP", map[string]interface{}{"allocation_id": allocID})
+	err := c.svc.ReleaseElasticIP(allocID)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetElasticIPsInfo(ids []string) ([]ElasticIPInfo, error) {
+	c := w.before("GetElasticIPsInfo", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetElasticIPsInfo(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSNetworkInterfaceService
+
+func (w *WrappedAWSService) CreateNetworkInterface(nid *NetworkInterfaceDefinition) (string, error) {
+	c := w.before("CreateNetworkInterface", map[string]interface{}{"definition": nid})
+	res, err := c.svc.CreateNetworkInterface(nid)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteNetworkInterface(id string) error {
+	c := w.before("DeleteNetworkInterface", map[string]interface{}{"id": id})
+	err := c.svc.DeleteNetworkInterface(id)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) AttachNetworkInterface(id string, instanceID string, deviceIndex int64) (string, error) {
+	c := w.before("AttachNetworkInterface", map[string]interface{}{"id": id, "instance_id": instanceID, "device_index": deviceIndex})
+	res, err := c.svc.AttachNetworkInterface(id, instanceID, deviceIndex)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DetachNetworkInterface(attachmentID string, force bool) error {
+	c := w.before("DetachNetworkInterface", map[string]interface{}{"attachment_id": attachmentID, "force": force})
+	err := c.svc.DetachNetworkInterface(attachmentID, force)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) AssignPrivateIPs(id string, ips []string, count int64) ([]string, error) {
+	c := w.before("AssignPrivateIPs", map[string]interface{}{"id": id, "ips": ips, "count": count})
+	res, err := c.svc.AssignPrivateIPs(id, ips, count)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) UnassignPrivateIPs(id string, ips []string) error {
+	c := w.before("UnassignPrivateIPs", map[string]interface{}{"id": id, "ips": ips})
+	err := c.svc.UnassignPrivateIPs(id, ips)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetNetworkInterfacesInfo(ids []string) ([]NetworkInterfaceInfo, error) {
+	c := w.before("GetNetworkInterfacesInfo", map[string]interface{}{"ids": ids})
+	res, err := c.svc.GetNetworkInterfacesInfo(ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSAutoScalingService
+
+func (w *WrappedAWSService) CreateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	c := w.before("CreateAutoScalingGroup", map[string]interface{}{"definition": asgd})
+	err := c.svc.CreateAutoScalingGroup(asgd)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) UpdateAutoScalingGroup(asgd *AutoScalingGroupDefinition) error {
+	c := w.before("UpdateAutoScalingGroup", map[string]interface{}{"definition": asgd})
+	err := c.svc.UpdateAutoScalingGroup(asgd)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DeleteAutoScalingGroup(name string, force bool) error {
+	c := w.before("DeleteAutoScalingGroup", map[string]interface{}{"name": name, "force": force})
+	err := c.svc.DeleteAutoScalingGroup(name, force)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetAutoScalingGroupInfo(name string) (*AutoScalingGroupInfo, error) {
+	c := w.before("GetAutoScalingGroupInfo", map[string]interface{}{"name": name})
+	res, err := c.svc.GetAutoScalingGroupInfo(name)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetAutoScalingGroupInstances(name string) ([]ASGInstanceInfo, error) {
+	c := w.before("GetAutoScalingGroupInstances", map[string]interface{}{"name": name})
+	res, err := c.svc.GetAutoScalingGroupInstances(name)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) SetDesiredCapacity(name string, capacity int64, honorCooldown bool) error {
+	c := w.before("SetDesiredCapacity", map[string]interface{}{"name": name, "capacity": capacity, "honor_cooldown": honorCooldown})
+	err := c.svc.SetDesiredCapacity(name, capacity, honorCooldown)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) SuspendProcesses(name string, processes []string) error {
+	c := w.before("SuspendProcesses", map[string]interface{}{"name": name, "processes": processes})
+	err := c.svc.SuspendProcesses(name, processes)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) ResumeProcesses(name string, processes []string) error {
+	c := w.before("ResumeProcesses", map[string]interface{}{"name": name, "processes": processes})
+	err := c.svc.ResumeProcesses(name, processes)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) AttachLoadBalancers(name string, lbs []string) error {
+	c := w.before("AttachLoadBalancers", map[string]interface{}{"name": name, "load_balancers": lbs})
+	err := c.svc.AttachLoadBalancers(name, lbs)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DetachLoadBalancers(name string, lbs []string) error {
+	c := w.before("DetachLoadBalancers", map[string]interface{}{"name": name, "load_balancers": lbs})
+	err := c.svc.DetachLoadBalancers(name, lbs)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) StartInstanceRefresh(name string, prefs *InstanceRefreshPreferences) (string, error) {
+	c := w.before("StartInstanceRefresh", map[string]interface{}{"name": name, "preferences": prefs})
+	res, err := c.svc.StartInstanceRefresh(name, prefs)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) CancelInstanceRefresh(name string) error {
+	c := w.before("CancelInstanceRefresh", map[string]interface{}{"name": name})
+	err := c.svc.CancelInstanceRefresh(name)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetInstanceRefreshes(name string, ids []string) ([]InstanceRefreshInfo, error) {
+	c := w.before("GetInstanceRefreshes", map[string]interface{}{"name": name, "ids": ids})
+	res, err := c.svc.GetInstanceRefreshes(name, ids)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) WaitForInstanceRefresh(name string, id string, interval time.Duration, timeout time.Duration) (*InstanceRefreshInfo, error) {
+	c := w.before("WaitForInstanceRefresh", map[string]interface{}{"name": name, "id": id, "interval": interval, "timeout": timeout})
+	res, err := c.svc.WaitForInstanceRefresh(name, id, interval, timeout)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSS3Service
+
+func (w *WrappedAWSService) PutObject(bucket string, key string, data []byte, opts *S3PutOptions) error {
+	c := w.before("PutObject", map[string]interface{}{"bucket": bucket, "key": key, "data": data, "options": opts})
+	err := c.svc.PutObject(bucket, key, data, opts)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) UploadStream(bucket string, key string, r io.Reader, opts *S3PutOptions) error {
+	c := w.before("UploadStream", map[string]interface{}{"bucket": bucket, "key": key, "body": r, "options": opts})
+	err := c.svc.UploadStream(bucket, key, r, opts)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) UploadFile(bucket string, key string, path string, opts *S3PutOptions) error {
+	c := w.before("UploadFile", map[string]interface{}{"bucket": bucket, "key": key, "path": path, "options": opts})
+	err := c.svc.UploadFile(bucket, key, path, opts)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetObject(bucket string, key string) ([]byte, error) {
+	c := w.before("GetObject", map[string]interface{}{"bucket": bucket, "key": key})
+	res, err := c.svc.GetObject(bucket, key)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetObjectStream(bucket string, key string) (io.ReadCloser, *S3ObjectInfo, error) {
+	c := w.before("GetObjectStream", map[string]interface{}{"bucket": bucket, "key": key})
+	body, info, err := c.svc.GetObjectStream(bucket, key)
+	w.after(c, info, err)
+	return body, info, err
+}
+
+func (w *WrappedAWSService) DeleteObject(bucket string, key string) error {
+	c := w.before("DeleteObject", map[string]interface{}{"bucket": bucket, "key": key})
+	err := c.svc.DeleteObject(bucket, key)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) ListObjects(bucket string, prefix string) ([]S3ObjectInfo, error) {
+	c := w.before("ListObjects", map[string]interface{}{"bucket": bucket, "prefix": prefix})
+	res, err := c.svc.ListObjects(bucket, prefix)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) CopyObject(srcBucket string, srcKey string, dstBucket string, dstKey string, opts *S3PutOptions) error {
+	c := w.before("CopyObject", map[string]interface{}{"src_bucket": srcBucket, "src_key": srcKey, "dst_bucket": dstBucket, "dst_key": dstKey, "options": opts})
+	err := c.svc.CopyObject(srcBucket, srcKey, dstBucket, dstKey, opts)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) PresignGetURL(bucket string, key string, expiry time.Duration) (string, error) {
+	c := w.before("PresignGetURL", map[string]interface{}{"bucket": bucket, "key": key, "expiry": expiry})
+	res, err := c.svc.PresignGetURL(bucket, key, expiry)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) PresignPutURL(bucket string, key string, expiry time.Duration) (string, error) {
+	c := w.before("PresignPutURL", map[string]interface{}{"bucket": bucket, "key": key, "expiry": expiry})
+	res, err := c.svc.PresignPutURL(bucket, key, expiry)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSCloudWatchService
+
+func (w *WrappedAWSService) PutMetricData(namespace string, data []MetricDatum) error {
+	c := w.before("PutMetricData", map[string]interface{}{"namespace": namespace, "data": data})
+	err := c.svc.PutMetricData(namespace, data)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetMetricStatistics(q *MetricStatisticsQuery) ([]MetricDatapoint, error) {
+	c := w.before("GetMetricStatistics", map[string]interface{}{"query": q})
+	res, err := c.svc.GetMetricStatistics(q)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) CreateAlarm(ad *AlarmDefinition) error {
+	c := w.before("CreateAlarm", map[string]interface{}{"definition": ad})
+	err := c.svc.CreateAlarm(ad)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) DeleteAlarms(names []string) error {
+	c := w.before("DeleteAlarms", map[string]interface{}{"names": names})
+	err := c.svc.DeleteAlarms(names)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetAlarmsInfo(names []string) ([]AlarmInfo, error) {
+	c := w.before("GetAlarmsInfo", map[string]interface{}{"names": names})
+	res, err := c.svc.GetAlarmsInfo(names)
+	w.after(c, res, err)
+	return res, err
+}
+
+// AWSSSMService
+
+func (w *WrappedAWSService) GetParameter(name string, decrypt bool) (*ParameterInfo, error) {
+	c := w.before("GetParameter", map[string]interface{}{"name": name, "decrypt": decrypt})
+	res, err := c.svc.GetParameter(name, decrypt)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) GetParametersByPath(path string, recursive bool, decrypt bool) ([]ParameterInfo, error) {
+	c := w.before("GetParametersByPath", map[string]interface{}{"path": path, "recursive": recursive, "decrypt": decrypt})
+	res, err := c.svc.GetParametersByPath(path, recursive, decrypt)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) PutParameter(pd *ParameterDefinition) (int64, error) {
+	c := w.before("PutParameter", map[string]interface{}{"definition": pd})
+	res, err := c.svc.PutParameter(pd)
+	w.after(c, res, err)
+	return res, err
+}
+
+func (w *WrappedAWSService) DeleteParameter(name string) error {
+	c := w.before("DeleteParameter", map[string]interface{}{"name": name})
+	err := c.svc.DeleteParameter(name)
+	w.after(c, nil, err)
+	return err
+}
+
+func (w *WrappedAWSService) GetParameterHistory(name string, decrypt bool) ([]ParameterInfo, error) {
+	c := w.before("GetParameterHistory", map[string]interface{}{"name": name, "decrypt": decrypt})
+	res, err := c.svc.GetParameterHistory(name, decrypt)
+	w.after(c, res, err)
+	return res, err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/dollarshaveclub/go-lib/awsservice"
+	"gopkg.in/yaml.v2"
+)
+
+// instancesFile is an InstancesDefinition with user data as plain text instead of base64
+type instancesFile struct {
+	awsservice.InstancesDefinition
+	UserData string
+}
+
+// yamlToJSON converts decoded YAML into values encoding/json can marshal (YAML maps have interface{} keys)
+func yamlToJSON(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range t {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string key: %v", k)
+			}
+			jv, err := yamlToJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			m[ks] = jv
+		}
+		return m, nil
+	case []interface{}:
+		for i := range t {
+			jv, err := yamlToJSON(t[i])
+			if err != nil {
+				return nil, err
+			}
+			t[i] = jv
+		}
+	}
+	return v, nil
+}
+
+// decodeDefinition decodes a JSON or YAML (anything not named *.json) definition into v. Field names are
+// those of the awsservice struct, matched case-insensitively; unknown fields are rejected.
+func decodeDefinition(name string, data []byte, v interface{}) error {
+	if !strings.EqualFold(filepath.Ext(name), ".json") {
+		var y interface{}
+		if err := yaml.Unmarshal(data, &y); err != nil {
+			return fmt.Errorf("error parsing YAML: %v", err)
+		}
+		j, err := yamlToJSON(y)
+		if err != nil {
+			return fmt.Errorf("error parsing YAML: %v", err)
+		}
+		data, err = json.Marshal(j)
+		if err != nil {
+			return fmt.Errorf("error converting YAML: %v", err)
+		}
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return fmt.Errorf("error decoding definition: %v", err)
+	}
+	return nil
+}
+
+func readDefinition(path string, v interface{}) error {
+	if path == "" {
+		return fmt.Errorf("definition file is required (-f)")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading definition: %v", err)
+	}
+	return decodeDefinition(path, data, v)
+}
+
+func readInstancesDefinition(path string) (*awsservice.InstancesDefinition, error) {
+	f := &instancesFile{}
+	if err := readDefinition(path, f); err != nil {
+		return nil, err
+	}
+	idef := f.InstancesDefinition
+	idef.UserData = []byte(f.UserData)
+	return &idef, nil
+}
+
+func readLoadBalancerDefinition(path string) (*awsservice.LoadBalancerDefinition, error) {
+	lbd := &awsservice.LoadBalancerDefinition{}
+	if err := readDefinition(path, lbd); err != nil {
+		return nil, err
+	}
+	return lbd, nil
+}
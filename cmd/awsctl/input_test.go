@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dollarshaveclub/go-lib/awsservice"
+)
+
+const testInstancesYAML = `
+ami: ami-123
+subnet: subnet-1
+type: t3.small
+count: 2
+rootVolumeType: gp3
+userData: |
+  #!/bin/sh
+  echo hello
+tags:
+  Name: web
+blockDevices:
+  - name: /dev/sdf
+    size: 100
+    type: st1
+`
+
+func TestDecodeInstancesDefinitionYAML(t *testing.T) {
+	f := &instancesFile{}
+	if err := decodeDefinition("web.yaml", []byte(testInstancesYAML), f); err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if f.AMI != "ami-123" || f.Count != 2 || f.Tags["Name"] != "web" {
+		t.Fatalf("bad definition: %+v", f)
+	}
+	if f.RootVolumeType != awsservice.Gp3 {
+		t.Fatalf("bad root volume type: %v", f.RootVolumeType)
+	}
+	if f.UserData != "#!/bin/sh\necho hello\n" {
+		t.Fatalf("user data should be plain text: %q", f.UserData)
+	}
+	if len(f.BlockDevices) != 1 || f.BlockDevices[0].Size != 100 || f.BlockDevices[0].Type != awsservice.St1 {
+		t.Fatalf("bad block devices: %+v", f.BlockDevices)
+	}
+}
+
+func TestDecodeLoadBalancerDefinitionJSON(t *testing.T) {
+	lbd := &awsservice.LoadBalancerDefinition{}
+	data := `{"Name": "web", "Subnets": ["subnet-1"], "Listeners": [{"InstancePort": 80, "LoadBalancerPort": 443}]}`
+	if err := decodeDefinition("lb.json", []byte(data), lbd); err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if lbd.Name != "web" || len(lbd.Listeners) != 1 || lbd.Listeners[0].LoadBalancerPort != 443 {
+		t.Fatalf("bad definition: %+v", lbd)
+	}
+}
+
+func TestDecodeDefinitionUnknownField(t *testing.T) {
+	err := decodeDefinition("web.yml", []byte("ami: ami-123\nimage: ami-456\n"), &instancesFile{})
+	if err == nil || !strings.Contains(err.Error(), "image") {
+		t.Fatalf("unknown field should be rejected: %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+// Command awsctl runs awsservice operations from the command line.
+//
+// Usage:
+//
+//	awsctl [global flags] <command> [flags] [args]
+//
+// Run "awsctl -h" for the list of commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/dollarshaveclub/go-lib/awsservice"
+)
+
+// cli holds what every command needs
+type cli struct {
+	name   string // Command being run
+	usage  string
+	svc    awsservice.AWSService
+	stdout io.Writer
+	stderr io.Writer
+	format string
+}
+
+type command struct {
+	usage string // arguments, eg "[-tag key] ID..."
+	help  string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"run":        {"-f FILE [-count N]", "launch instances from an InstancesDefinition file", runInstances},
+	"start":      {"ID...", "start instances", idsCommand(awsservice.AWSService.StartInstances)},
+	"stop":       {"ID...", "stop instances", idsCommand(awsservice.AWSService.StopInstances)},
+	"reboot":     {"ID...", "reboot instances", idsCommand(awsservice.AWSService.RebootInstances)},
+	"terminate":  {"ID...", "terminate instances", idsCommand(awsservice.AWSService.TerminateInstances)},
+	"info":       {"ID...", "show instances", instancesInfo},
+	"find":       {"-tag KEY [-value VALUE]", "show instances by tag (any value by default)", findInstances},
+	"subnets":    {"ID...", "show subnets", subnetsInfo},
+	"create-elb": {"-f FILE", "create a classic load balancer from a LoadBalancerDefinition file", createLoadBalancer},
+	"delete-elb": {"NAME", "delete a classic load balancer", deleteLoadBalancer},
+	"register":   {"NAME ID...", "register instances with a load balancer", lbInstancesCommand(awsservice.AWSService.RegisterInstances)},
+	"deregister": {"NAME ID...", "deregister instances from a load balancer", lbInstancesCommand(awsservice.AWSService.DeregisterInstances)},
+	"dns":        {"-zone ZONE NAME", "show the DNS records with a name", dnsRecords},
+	"dns-create": {"-zone ZONE -name NAME -value VALUE [-type A] [-ttl 300]", "create a DNS record", dnsCommand(awsservice.AWSService.CreateDNSRecord)},
+	"dns-delete": {"-zone ZONE -name NAME -value VALUE [-type A] [-ttl 300]", "delete a DNS record (all fields must match)", dnsCommand(awsservice.AWSService.DeleteDNSRecord)},
+}
+
+// errUsage means the command line was wrong; usage has already been printed
+var errUsage = errors.New("usage")
+
+func newFlagSet(c *cli) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: awsctl %v %v\n", c.name, c.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses a command's flags, requiring at least min positional arguments
+func parse(fs *flag.FlagSet, args []string, min int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < min {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func idsCommand(f func(awsservice.AWSService, []string) error) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		fs := newFlagSet(c)
+		if err := parse(fs, args, 1); err != nil {
+			return err
+		}
+		return f(c.svc, fs.Args())
+	}
+}
+
+func lbInstancesCommand(f func(awsservice.AWSService, string, []string) error) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		fs := newFlagSet(c)
+		if err := parse(fs, args, 2); err != nil {
+			return err
+		}
+		return f(c.svc, fs.Arg(0), fs.Args()[1:])
+	}
+}
+
+func runInstances(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	path := fs.String("f", "", "InstancesDefinition file (YAML or JSON)")
+	count := fs.Int("count", 0, "number of instances, overriding the definition")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	idef, err := readInstancesDefinition(*path)
+	if err != nil {
+		return err
+	}
+	if *count > 0 {
+		idef.Count = *count
+	}
+	ids, err := c.svc.RunInstances(idef)
+	if err != nil {
+		return err
+	}
+	return writeResult(c.stdout, c.format, ids, idsTable(ids))
+}
+
+func instancesInfo(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	iis, err := c.svc.GetInstancesInfo(fs.Args())
+	if err != nil {
+		return err
+	}
+	return writeResult(c.stdout, c.format, iis, instancesTable(iis))
+}
+
+func findInstances(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	tag := fs.String("tag", "", "tag key")
+	value := fs.String("value", "*", "tag value (wildcards * and ? are allowed)")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *tag == "" {
+		fs.Usage()
+		return errUsage
+	}
+	ids, err := c.svc.FindInstancesByTag(*tag, *value)
+	if err != nil {
+		return err
+	}
+	iis := []awsservice.InstanceInfo{}
+	if len(ids) > 0 {
+		if iis, err = c.svc.GetInstancesInfo(ids); err != nil {
+			return err
+		}
+	}
+	sort.Slice(iis, func(i, j int) bool { return iis[i].ID < iis[j].ID })
+	return writeResult(c.stdout, c.format, iis, instancesTable(iis))
+}
+
+func subnetsInfo(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	sis := []awsservice.SubnetInfo{}
+	for _, id := range fs.Args() {
+		si, err := c.svc.GetSubnetInfo(id)
+		if err != nil {
+			return err
+		}
+		sis = append(sis, *si)
+	}
+	return writeResult(c.stdout, c.format, sis, subnetsTable(sis))
+}
+
+func createLoadBalancer(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	path := fs.String("f", "", "LoadBalancerDefinition file (YAML or JSON)")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	lbd, err := readLoadBalancerDefinition(*path)
+	if err != nil {
+		return err
+	}
+	dns, err := c.svc.CreateLoadBalancer(lbd)
+	if err != nil {
+		return err
+	}
+	t := &table{headers: []string{"NAME", "DNS NAME"}}
+	t.add(lbd.Name, dns)
+	return writeResult(c.stdout, c.format, map[string]string{"Name": lbd.Name, "DNSName": dns}, t)
+}
+
+func deleteLoadBalancer(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	return c.svc.DeleteLoadBalancer(fs.Arg(0))
+}
+
+func dnsRecords(c *cli, args []string) error {
+	fs := newFlagSet(c)
+	zone := fs.String("zone", "", "hosted zone ID")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	if *zone == "" {
+		fs.Usage()
+		return errUsage
+	}
+	rds, err := c.svc.GetDNSRecords(*zone, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeResult(c.stdout, c.format, rds, dnsRecordsTable(rds))
+}
+
+func dnsCommand(f func(awsservice.AWSService, *awsservice.Route53RecordDefinition) error) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		fs := newFlagSet(c)
+		rd := &awsservice.Route53RecordDefinition{}
+		fs.StringVar(&rd.ZoneID, "zone", "", "hosted zone ID")
+		fs.StringVar(&rd.Name, "name", "", "record name")
+		fs.StringVar(&rd.Value, "value", "", "record value")
+		fs.StringVar(&rd.Type, "type", "A", "record type")
+		fs.Int64Var(&rd.TTL, "ttl", 300, "TTL in seconds")
+		if err := parse(fs, args, 0); err != nil {
+			return err
+		}
+		if rd.ZoneID == "" || rd.Name == "" || rd.Value == "" {
+			fs.Usage()
+			return errUsage
+		}
+		return f(c.svc, rd)
+	}
+}
+
+func usage(w io.Writer, global *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: awsctl [global flags] <command> [flags] [args]\n\nglobal flags:\n")
+	global.SetOutput(w)
+	global.PrintDefaults()
+	fmt.Fprintf(w, "\ncommands:\n")
+	names := []string{}
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-11v %v\n", n, commands[n].help)
+	}
+}
+
+// newService builds the service described by the global flags
+func newService(region string, role string, externalID string) (awsservice.AWSService, error) {
+	if role != "" {
+		return awsservice.NewAssumeRoleAWSService(&awsservice.AssumeRoleConfig{
+			RoleARN:    role,
+			ExternalID: externalID,
+			Region:     region,
+		})
+	}
+	return awsservice.NewRegionAWSService(region), nil
+}
+
+// run executes a command line, returning the exit status. If svc is nil one is built from the global flags.
+func run(args []string, stdout io.Writer, stderr io.Writer, svc awsservice.AWSService) int {
+	global := flag.NewFlagSet("awsctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	format := global.String("o", outputTable, "output format: table or json")
+	region := global.String("region", "us-west-2", "AWS region")
+	role := global.String("role", "", "IAM role ARN to assume")
+	externalID := global.String("external-id", "", "external ID for -role")
+	dryRun := global.Bool("dry-run", false, "print the mutating calls that would be made instead of making them")
+	global.Usage = func() { usage(stderr, global) }
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return 2
+	}
+	cmd, ok := commands[global.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %v\n", global.Arg(0))
+		global.Usage()
+		return 2
+	}
+	if *format != outputTable && *format != outputJSON {
+		fmt.Fprintf(stderr, "unknown output format: %v\n", *format)
+		return 2
+	}
+	if svc == nil {
+		var err error
+		if svc, err = newService(*region, *role, *externalID); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	plan := &awsservice.MutationPlan{}
+	if *dryRun {
+		rs, ok := svc.(*awsservice.RealAWSService)
+		if !ok {
+			fmt.Fprintf(stderr, "error: dry run is not supported by %T\n", svc)
+			return 1
+		}
+		rs.SetDryRun(plan)
+	}
+	c := &cli{name: global.Arg(0), usage: cmd.usage, svc: svc, stdout: stdout, stderr: stderr, format: *format}
+	err := cmd.run(c, global.Args()[1:])
+	if *dryRun && len(plan.Mutations()) > 0 {
+		fmt.Fprintf(stderr, "dry run, not executed:\n%v\n", plan)
+	}
+	switch {
+	case err == errUsage:
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, nil))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dollarshaveclub/go-lib/awsservice"
+)
+
+func TestRunCommand(t *testing.T) {
+	svc := &awsservice.TestingAWSService{}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"stop", "i-1", "i-2"}, stdout, stderr, svc); code != 0 {
+		t.Fatalf("bad exit status %v: %v", code, stderr)
+	}
+	if len(svc.Log) != 1 || svc.Log[0].Action != "StopInstances" || svc.Log[0].NotableParams["ids"] != "[i-1 i-2]" {
+		t.Fatalf("bad actions: %v", svc.Log)
+	}
+}
+
+func TestRunCommandJSON(t *testing.T) {
+	svc := &awsservice.TestingAWSService{}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"-o", "json", "info", "i-1"}, stdout, stderr, svc); code != 0 {
+		t.Fatalf("bad exit status %v: %v", code, stderr)
+	}
+	if strings.TrimSpace(stdout.String()) != "[]" {
+		t.Fatalf("bad output: %v", stdout)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"bogus"}, {"stop"}, {"dns-create", "-zone", "Z1"}} {
+		svc := &awsservice.TestingAWSService{}
+		stderr := &bytes.Buffer{}
+		if code := run(args, &bytes.Buffer{}, stderr, svc); code != 2 {
+			t.Fatalf("%v: bad exit status %v", args, code)
+		}
+		if !strings.Contains(stderr.String(), "usage: awsctl") {
+			t.Fatalf("%v: usage should be printed: %v", args, stderr)
+		}
+		if len(svc.Log) != 0 {
+			t.Fatalf("%v: nothing should be called: %v", args, svc.Log)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dollarshaveclub/go-lib/awsservice"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// table is the tabular rendering of a result
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func (t *table) add(cols ...interface{}) {
+	row := []string{}
+	for _, c := range cols {
+		row = append(row, fmt.Sprintf("%v", c))
+	}
+	t.rows = append(t.rows, row)
+}
+
+func (t *table) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+	for _, r := range t.rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
+
+func idsTable(ids []string) *table {
+	t := &table{headers: []string{"ID"}}
+	for _, id := range ids {
+		t.add(id)
+	}
+	return t
+}
+
+func instancesTable(iis []awsservice.InstanceInfo) *table {
+	t := &table{headers: []string{"ID", "NAME", "STATE", "TYPE", "PRIVATE IP", "PUBLIC IP", "SUBNET", "LAUNCHED"}}
+	for _, ii := range iis {
+		launched := ""
+		if !ii.LaunchTime.IsZero() {
+			launched = ii.LaunchTime.Format("2006-01-02 15:04")
+		}
+		t.add(ii.ID, ii.Tags["Name"], ii.State, ii.Type, ii.PrivateIP, ii.PublicIP, ii.Subnet, launched)
+	}
+	return t
+}
+
+func subnetsTable(sis []awsservice.SubnetInfo) *table {
+	t := &table{headers: []string{"ID", "NAME", "VPC", "AZ", "CIDR", "AVAILABLE IPS", "STATE"}}
+	for _, si := range sis {
+		t.add(si.ID, si.Tags["Name"], si.VPC, si.AvailabilityZone, si.CIDR, si.AvailableIPAddresses, si.State)
+	}
+	return t
+}
+
+func dnsRecordsTable(rds []awsservice.Route53RecordDefinition) *table {
+	t := &table{headers: []string{"NAME", "TYPE", "TTL", "VALUE"}}
+	for _, rd := range rds {
+		value := rd.Value
+		if rd.Alias != nil {
+			value = "ALIAS " + rd.Alias.DNSName
+		}
+		t.add(rd.Name, rd.Type, rd.TTL, value)
+	}
+	return t
+}
+
+// writeResult writes v as indented JSON, or as t for table output (t may be nil if there is nothing to show)
+func writeResult(w io.Writer, format string, v interface{}, t *table) error {
+	switch format {
+	case outputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	case outputTable:
+		if t == nil {
+			return nil
+		}
+		return t.write(w)
+	}
+	return fmt.Errorf("unknown output format: %v", format)
+}